@@ -1,10 +1,13 @@
 package gemini
 
 import (
+	"archon/internal/adapters/retry"
+	"archon/internal/core"
 	"context"
 	"fmt"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -35,15 +38,13 @@ func (c *Client) Client() *genai.Client {
 	return c.client
 }
 
-type Response struct {
-	Text         string
-	PromptTokens int
-	AnswerTokens int
-	TotalTokens  int
-}
-
-func (c *Client) Ask(ctx context.Context, prompt string) (*Response, error) {
-	resp, err := c.model.GenerateContent(ctx, genai.Text(prompt))
+func (c *Client) Generate(ctx context.Context, prompt string) (*core.Response, error) {
+	var resp *genai.GenerateContentResponse
+	err := retry.Default.Do(ctx, func() error {
+		var err error
+		resp, err = c.model.GenerateContent(ctx, genai.Text(prompt))
+		return classify(err)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
@@ -52,22 +53,137 @@ func (c *Client) Ask(ctx context.Context, prompt string) (*Response, error) {
 		return nil, fmt.Errorf("no candidates in response")
 	}
 
-	// Simple extraction of text from the first candidate
-	var result string
+	result := &core.Response{Text: candidateText(resp)}
+	setUsage(result, resp.UsageMetadata)
+	return result, nil
+}
+
+// candidateText extracts the text of the first candidate.
+func candidateText(resp *genai.GenerateContentResponse) string {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
+	}
+	var text string
 	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			result += string(text)
+		if t, ok := part.(genai.Text); ok {
+			text += string(t)
 		}
 	}
+	return text
+}
 
-	return &Response{
-		Text:         result,
-		PromptTokens: int(resp.UsageMetadata.PromptTokenCount),
-		AnswerTokens: int(resp.UsageMetadata.CandidatesTokenCount),
-		TotalTokens:  int(resp.UsageMetadata.TotalTokenCount),
-	}, nil
+// setUsage copies the token counts, which the API does not always report.
+func setUsage(result *core.Response, usage *genai.UsageMetadata) {
+	if usage == nil {
+		return
+	}
+	result.PromptTokens = int(usage.PromptTokenCount)
+	result.AnswerTokens = int(usage.CandidatesTokenCount)
+	result.TotalTokens = int(usage.TotalTokenCount)
+}
+
+// GenerateStream streams the answer chunk by chunk as Gemini produces it.
+func (c *Client) GenerateStream(ctx context.Context, prompt string) <-chan core.StreamEvent {
+	return streamResponses(ctx, func() *genai.GenerateContentResponseIterator {
+		return c.model.GenerateContentStream(ctx, genai.Text(prompt))
+	})
+}
+
+// ChatStream sends the last message through a chat session holding the
+// earlier messages as its history.
+func (c *Client) ChatStream(ctx context.Context, messages []core.Message) <-chan core.StreamEvent {
+	if len(messages) == 0 {
+		events := make(chan core.StreamEvent, 1)
+		events <- core.StreamEvent{Err: fmt.Errorf("no message to send")}
+		close(events)
+		return events
+	}
+
+	var history []*genai.Content
+	for _, msg := range messages[:len(messages)-1] {
+		role := "user"
+		if msg.Role == core.RoleAssistant {
+			role = "model"
+		}
+		history = append(history, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(msg.Content)}})
+	}
+	last := genai.Text(messages[len(messages)-1].Content)
+	return streamResponses(ctx, func() *genai.GenerateContentResponseIterator {
+		// A fresh session per attempt: a failed send leaves its message in the history
+		cs := c.model.StartChat()
+		cs.History = append([]*genai.Content(nil), history...)
+		return cs.SendMessageStream(ctx, last)
+	})
+}
+
+// streamResponses forwards the chunks of the stream opened by start. The stream
+// is reopened on temporary failures, as long as no text has been delivered yet.
+func streamResponses(ctx context.Context, start func() *genai.GenerateContentResponseIterator) <-chan core.StreamEvent {
+	events := make(chan core.StreamEvent)
+	go func() {
+		defer close(events)
+		send := func(ev core.StreamEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		result := &core.Response{}
+		var streamErr error
+		err := retry.Default.Do(ctx, func() error {
+			iter := start()
+			for {
+				resp, err := iter.Next()
+				if err == iterator.Done {
+					return nil
+				}
+				if err != nil {
+					if result.Text != "" {
+						// Part of the answer is out, a retry would repeat it
+						streamErr = classify(err)
+						return nil
+					}
+					return classify(err)
+				}
+
+				if delta := candidateText(resp); delta != "" {
+					result.Text += delta
+					if !send(core.StreamEvent{Delta: delta}) {
+						return ctx.Err()
+					}
+				}
+				// Every chunk carries the usage so far, the last one the total
+				setUsage(result, resp.UsageMetadata)
+			}
+		})
+		if err == nil {
+			err = streamErr
+		}
+		if err != nil {
+			send(core.StreamEvent{Err: fmt.Errorf("failed to generate content: %w", err)})
+			return
+		}
+		send(core.StreamEvent{Response: result})
+	}()
+	return events
+}
+
+func (c *Client) CountTokens(ctx context.Context, text string) (int, error) {
+	var resp *genai.CountTokensResponse
+	err := retry.Default.Do(ctx, func() error {
+		var err error
+		resp, err = c.model.CountTokens(ctx, genai.Text(text))
+		return classify(err)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
 }
 
-func (c *Client) Close() {
-	c.client.Close()
+func (c *Client) Close() error {
+	return c.client.Close()
 }
@@ -0,0 +1,101 @@
+package gemini
+
+import (
+	"archon/internal/adapters/retry"
+	"context"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/option"
+)
+
+const DefaultEmbeddingModel = "text-embedding-004"
+
+// Embedder vectorizes text with a Gemini embedding model.
+type Embedder struct {
+	client  *genai.Client
+	model   *genai.EmbeddingModel
+	modelID string
+	limiter *rate.Limiter
+}
+
+func NewEmbedder(ctx context.Context, apiKey string, modelID string) (*Embedder, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	if modelID == "" {
+		modelID = DefaultEmbeddingModel
+	}
+
+	return &Embedder{
+		client:  client,
+		model:   client.EmbeddingModel(modelID),
+		modelID: modelID,
+		// Rate limiter: 1500 RPM (Requests Per Minute) -> 25 RPS
+		limiter: rate.NewLimiter(rate.Limit(25), 1),
+	}, nil
+}
+
+func (e *Embedder) Name() string {
+	return "gemini:" + e.modelID
+}
+
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var res *genai.EmbedContentResponse
+	err := retry.Default.Do(ctx, func() error {
+		if err := e.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		var err error
+		res, err = e.model.EmbedContent(ctx, genai.Text(text))
+		return classify(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Embedding == nil {
+		return nil, fmt.Errorf("empty embedding in response")
+	}
+	return res.Embedding.Values, nil
+}
+
+// batchLimit is the maximum number of contents per BatchEmbedContents request.
+const batchLimit = 100
+
+func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchLimit {
+		end := min(start+batchLimit, len(texts))
+		batch := e.model.NewBatch()
+		for _, text := range texts[start:end] {
+			batch.AddContent(genai.Text(text))
+		}
+
+		var res *genai.BatchEmbedContentsResponse
+		err := retry.Default.Do(ctx, func() error {
+			if err := e.limiter.Wait(ctx); err != nil {
+				return err
+			}
+			var err error
+			res, err = e.model.BatchEmbedContents(ctx, batch)
+			return classify(err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Embeddings) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(res.Embeddings))
+		}
+		for _, emb := range res.Embeddings {
+			vectors = append(vectors, emb.Values)
+		}
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) Close() error {
+	return e.client.Close()
+}
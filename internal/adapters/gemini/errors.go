@@ -0,0 +1,55 @@
+package gemini
+
+import (
+	"archon/internal/adapters/retry"
+	"archon/internal/core"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+)
+
+// classify wraps an error of the Gemini API in a core.ProviderError, so it can be
+// retried and reported by kind. Cancellation and the caller's deadline are
+// returned unchanged.
+func classify(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return &core.ProviderError{Kind: core.ErrSafetyBlocked, Err: err}
+	}
+
+	ae, ok := apierror.FromError(err)
+	if !ok {
+		if retry.Transient(err) {
+			return &core.ProviderError{Temporary: true, Err: err}
+		}
+		return err
+	}
+
+	pe := &core.ProviderError{Err: err}
+	if info := ae.Details().RetryInfo; info != nil && info.GetRetryDelay() != nil {
+		pe.RetryAfter = info.GetRetryDelay().AsDuration()
+	}
+
+	message := strings.ToLower(ae.GRPCStatus().Message())
+	switch code := ae.GRPCStatus().Code(); {
+	case code == codes.ResourceExhausted:
+		pe.Kind, pe.Temporary = core.ErrQuotaExceeded, true
+	case code == codes.Unauthenticated || code == codes.PermissionDenied || ae.Reason() == "API_KEY_INVALID":
+		pe.Kind = core.ErrInvalidKey
+	case code == codes.InvalidArgument && strings.Contains(message, "token") &&
+		(strings.Contains(message, "exceed") || strings.Contains(message, "too long") || strings.Contains(message, "maximum")):
+		pe.Kind = core.ErrContextTooLong
+	case code == codes.Unavailable || code == codes.Internal || code == codes.DeadlineExceeded ||
+		code == codes.Aborted || ae.HTTPCode() >= 500:
+		pe.Temporary = true
+	}
+	return pe
+}
@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		kind      error
+		temporary bool
+	}{
+		{"quota", status.Error(codes.ResourceExhausted, "quota exceeded"), core.ErrQuotaExceeded, true},
+		{"invalid key", status.Error(codes.PermissionDenied, "API key not valid"), core.ErrInvalidKey, false},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "missing key"), core.ErrInvalidKey, false},
+		{"context too long", status.Error(codes.InvalidArgument, "The input token count exceeds the maximum"), core.ErrContextTooLong, false},
+		{"other invalid argument", status.Error(codes.InvalidArgument, "bad schema"), nil, false},
+		{"unavailable", status.Error(codes.Unavailable, "overloaded"), nil, true},
+		{"internal", status.Error(codes.Internal, "oops"), nil, true},
+		{"blocked", &genai.BlockedError{}, core.ErrSafetyBlocked, false},
+		{"truncated transport", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), nil, true},
+	}
+	for _, tt := range tests {
+		var pe *core.ProviderError
+		if !errors.As(classify(tt.err), &pe) {
+			t.Errorf("%s: classify(%v) is not a *core.ProviderError", tt.name, tt.err)
+			continue
+		}
+		if pe.Kind != tt.kind || pe.Temporary != tt.temporary {
+			t.Errorf("%s: kind %v, temporary %v; want %v, %v", tt.name, pe.Kind, pe.Temporary, tt.kind, tt.temporary)
+		}
+	}
+
+	// Cancellation, the caller's deadline and unknown failures pass through unchanged
+	for _, err := range []error{nil, context.Canceled, fmt.Errorf("send: %w", context.DeadlineExceeded), errors.New("invalid json")} {
+		if got := classify(err); got != err {
+			t.Errorf("classify(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
@@ -1,62 +1,142 @@
 package gemini
 
 import (
+	"archon/internal/core"
 	"context"
+	"os"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
 )
 
+// BatchOptions configures a BatchProcessor. Zero limits mean unlimited.
+type BatchOptions struct {
+	// Concurrency is the number of items processed at the same time (default 4).
+	Concurrency int
+	// RPM limits the requests started per minute.
+	RPM int
+	// TPM limits the tokens used per minute.
+	TPM int
+	// Estimate guesses the tokens an item will use before it is processed. By
+	// default it is derived from the file size.
+	Estimate func(item string) int
+}
+
+const defaultConcurrency = 4
+
+// BatchFunc processes one item, typically by sending a prompt built from the
+// file to the model, and returns the model's response for token accounting.
+type BatchFunc func(ctx context.Context, item string) (*core.Response, error)
+
+// BatchResult is the outcome of one item.
+type BatchResult struct {
+	Response *core.Response
+	Err      error
+	Duration time.Duration
+}
+
 type BatchProcessor struct {
-	client      *Client
-	rateLimiter *rate.Limiter
-	workerCount int
+	concurrency int
+	requests    *rate.Limiter
+	tokens      *rate.Limiter
+	estimate    func(item string) int
 }
 
-func NewBatchProcessor(client *Client, rpm int) *BatchProcessor {
-	// rpm: Requests Per Minute
-	limit := rate.Limit(float64(rpm) / 60.0)
+func NewBatchProcessor(opts BatchOptions) *BatchProcessor {
+	bp := &BatchProcessor{
+		concurrency: opts.Concurrency,
+		estimate:    opts.Estimate,
+	}
+	if bp.concurrency <= 0 {
+		bp.concurrency = defaultConcurrency
+	}
+	if bp.estimate == nil {
+		bp.estimate = estimateFileTokens
+	}
+	if opts.RPM > 0 {
+		bp.requests = rate.NewLimiter(rate.Limit(float64(opts.RPM)/60.0), 1)
+	}
+	if opts.TPM > 0 {
+		// The burst is a whole minute's budget, so one large item can still run
+		bp.tokens = rate.NewLimiter(rate.Limit(float64(opts.TPM)/60.0), opts.TPM)
+	}
+	return bp
+}
 
-	return &BatchProcessor{
-		client:      client,
-		rateLimiter: rate.NewLimiter(limit, 1),
-		workerCount: 5,
+// estimateFileTokens assumes the prompt holds the file and the answer is about
+// as long, at roughly 4 characters per token.
+func estimateFileTokens(item string) int {
+	info, err := os.Stat(item)
+	if err != nil {
+		return 0
 	}
+	return int(info.Size()) / 2
 }
 
-func (bp *BatchProcessor) ProcessFiles(files []string, processFunc func(string) error) []error {
-	jobs := make(chan string, len(files))
-	results := make(chan error, len(files))
+// ProcessFiles runs process for every file within the configured limits and
+// returns the result of each, keyed by file. Once ctx is cancelled no new
+// files are started; those not processed get ctx's error.
+func (bp *BatchProcessor) ProcessFiles(ctx context.Context, files []string, process BatchFunc) map[string]BatchResult {
+	jobs := make(chan string)
+	results := make(map[string]BatchResult, len(files))
+	var mu sync.Mutex
 
 	var wg sync.WaitGroup
-	for i := 0; i < bp.workerCount; i++ {
+	for i := 0; i < bp.concurrency; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for file := range jobs {
-				err := bp.rateLimiter.Wait(context.Background())
-				if err == nil {
-					results <- processFunc(file)
-				} else {
-					results <- err
-				}
+				res := bp.processOne(ctx, file, process)
+				mu.Lock()
+				results[file] = res
+				mu.Unlock()
 			}
 		}()
 	}
 
 	for _, f := range files {
-		jobs <- f
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- f:
+		case <-ctx.Done():
+		}
 	}
 	close(jobs)
 	wg.Wait()
 
-	var errors []error
-	close(results)
-	for err := range results {
-		if err != nil {
-			errors = append(errors, err)
+	for _, f := range files {
+		if _, ok := results[f]; !ok {
+			results[f] = BatchResult{Err: ctx.Err()}
 		}
 	}
+	return results
+}
 
-	return errors
+func (bp *BatchProcessor) processOne(ctx context.Context, file string, process BatchFunc) BatchResult {
+	estimate := 0
+	if bp.tokens != nil {
+		estimate = min(bp.estimate(file), bp.tokens.Burst())
+		if err := bp.tokens.WaitN(ctx, estimate); err != nil {
+			return BatchResult{Err: err}
+		}
+	}
+	if bp.requests != nil {
+		if err := bp.requests.Wait(ctx); err != nil {
+			return BatchResult{Err: err}
+		}
+	}
+
+	start := time.Now()
+	resp, err := process(ctx, file)
+	res := BatchResult{Response: resp, Err: err, Duration: time.Since(start)}
+
+	// Charge what the item really used beyond the estimate, delaying later items
+	if bp.tokens != nil && resp != nil && resp.TotalTokens > estimate {
+		bp.tokens.ReserveN(time.Now(), min(resp.TotalTokens-estimate, bp.tokens.Burst()))
+	}
+	return res
 }
@@ -0,0 +1,282 @@
+package openai
+
+import (
+	"archon/internal/adapters/retry"
+	"archon/internal/core"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to any server exposing the OpenAI chat completions API
+// (OpenAI itself, vLLM, llama.cpp, Ollama, LM Studio, ...).
+type Client struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string, apiKey string, modelID string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      modelID,
+		httpClient: &http.Client{Transport: newTransport()},
+	}
+}
+
+// newTransport bounds how long a server may take to start answering. There is
+// no overall timeout, which would cut long streams short; callers cancel the
+// context instead.
+func newTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A non-streamed answer only starts once it is fully generated
+	transport.ResponseHeaderTimeout = 10 * time.Minute
+	return transport
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+}
+
+// chatChunk is one server-sent event of a streamed chat completion.
+type chatChunk struct {
+	Choices []struct {
+		Delta chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+	// Error is set when the server fails after the stream started
+	Error *apiErrorBody `json:"error"`
+}
+
+func (c *Client) Generate(ctx context.Context, prompt string) (*core.Response, error) {
+	req := chatRequest{
+		Model:    c.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+
+	var resp chatResponse
+	if err := c.post(ctx, "/chat/completions", req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	result := &core.Response{Text: resp.Choices[0].Message.Content}
+	if resp.Usage != nil {
+		result.PromptTokens = resp.Usage.PromptTokens
+		result.AnswerTokens = resp.Usage.CompletionTokens
+		result.TotalTokens = resp.Usage.TotalTokens
+	}
+	return result, nil
+}
+
+// GenerateStream streams the answer as server-sent events.
+func (c *Client) GenerateStream(ctx context.Context, prompt string) <-chan core.StreamEvent {
+	return c.ChatStream(ctx, []core.Message{{Role: core.RoleUser, Content: prompt}})
+}
+
+// ChatStream streams the answer to a conversation as server-sent events. Usage
+// is requested with stream_options; servers that ignore it report no token counts.
+func (c *Client) ChatStream(ctx context.Context, messages []core.Message) <-chan core.StreamEvent {
+	chat := make([]chatMessage, 0, len(messages))
+	for _, msg := range messages {
+		chat = append(chat, chatMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	events := make(chan core.StreamEvent)
+	go func() {
+		defer close(events)
+		send := func(ev core.StreamEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		result, err := c.stream(ctx, chatRequest{
+			Model:         c.model,
+			Messages:      chat,
+			Stream:        true,
+			StreamOptions: &streamOptions{IncludeUsage: true},
+		}, func(delta string) bool {
+			return send(core.StreamEvent{Delta: delta})
+		})
+		if err != nil {
+			send(core.StreamEvent{Err: fmt.Errorf("failed to generate content: %w", err)})
+			return
+		}
+		if result != nil {
+			send(core.StreamEvent{Response: result})
+		}
+	}()
+	return events
+}
+
+// stream posts a streaming chat request and calls onDelta for every piece of
+// text. It returns nil without an error when onDelta asks to stop.
+func (c *Client) stream(ctx context.Context, body chatRequest, onDelta func(string) bool) (*core.Response, error) {
+	// Only opening the stream is retried, a broken stream would repeat text
+	var res *http.Response
+	err := retry.Default.Do(ctx, func() error {
+		req, err := c.newRequest(ctx, "/chat/completions", body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+
+		res, err = c.httpClient.Do(req)
+		if err != nil {
+			return classify(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			raw, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			return statusError("/chat/completions", res, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result := &core.Response{}
+	done := false
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, streamError(*chunk.Error)
+		}
+		if chunk.Usage != nil {
+			result.PromptTokens = chunk.Usage.PromptTokens
+			result.AnswerTokens = chunk.Usage.CompletionTokens
+			result.TotalTokens = chunk.Usage.TotalTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		result.Text += delta
+		if !onDelta(delta) {
+			return nil, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !done {
+		// The connection closed before the server finished the answer
+		return nil, fmt.Errorf("stream ended before [DONE]: %w", io.ErrUnexpectedEOF)
+	}
+	return result, nil
+}
+
+// CountTokens estimates the token count, since the OpenAI API has no tokenizer endpoint.
+// Roughly 4 characters per token holds well enough for English prose and source code.
+func (c *Client) CountTokens(ctx context.Context, text string) (int, error) {
+	return (len(text) + 3) / 4, nil
+}
+
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, path string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	var raw []byte
+	err := retry.Default.Do(ctx, func() error {
+		req, err := c.newRequest(ctx, path, body)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			return classify(err)
+		}
+		defer res.Body.Close()
+
+		raw, err = io.ReadAll(res.Body)
+		if err != nil {
+			return classify(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			return statusError(path, res, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,70 @@
+package openai
+
+import (
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sse serves events as a chat completion stream.
+func sse(events ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			fmt.Fprintf(w, "data: %s\n\n", e)
+		}
+	}))
+}
+
+func TestStream(t *testing.T) {
+	const hello = `{"choices":[{"delta":{"content":"Hello"}}]}`
+	tests := []struct {
+		name    string
+		events  []string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "complete",
+			events: []string{hello, `{"choices":[{"delta":{"content":" world"}}]}`, "[DONE]"},
+			want:   "Hello world",
+		},
+		{
+			name:    "cut off before [DONE]",
+			events:  []string{hello},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "error event",
+			events:  []string{hello, `{"error":{"message":"too long","code":"context_length_exceeded"}}`, "[DONE]"},
+			wantErr: core.ErrContextTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := sse(tt.events...)
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "", "test")
+			defer c.Close()
+			res, err := c.stream(context.Background(), chatRequest{Model: "test", Stream: true}, func(string) bool { return true })
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Text != tt.want {
+				t.Errorf("Text = %q, want %q", res.Text, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+)
+
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// Embedder vectorizes text through the /embeddings endpoint of an OpenAI-compatible server.
+type Embedder struct {
+	client  *Client
+	modelID string
+}
+
+func NewEmbedder(baseURL string, apiKey string, modelID string) *Embedder {
+	if modelID == "" {
+		modelID = DefaultEmbeddingModel
+	}
+	return &Embedder{
+		client:  NewClient(baseURL, apiKey, modelID),
+		modelID: modelID,
+	}
+}
+
+type embeddingRequest struct {
+	Model string      `json:"model"`
+	Input interface{} `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *Embedder) Name() string {
+	return "openai:" + e.modelID
+}
+
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var resp embeddingResponse
+	err := e.client.post(ctx, "/embeddings", embeddingRequest{Model: e.modelID, Input: text}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no embeddings in response")
+	}
+	return resp.Data[0].Embedding, nil
+}
+
+func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp embeddingResponse
+	err := e.client.post(ctx, "/embeddings", embeddingRequest{Model: e.modelID, Input: texts}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, d := range resp.Data {
+		// The API reports each embedding's input index; fall back to order if a server omits it.
+		idx := d.Index
+		if idx < 0 || idx >= len(texts) || vectors[idx] != nil {
+			idx = i
+		}
+		vectors[idx] = d.Embedding
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) Close() error {
+	return e.client.Close()
+}
@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"archon/internal/adapters/retry"
+	"archon/internal/core"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
+
+// statusError turns a non-200 response into a core.ProviderError, classified by
+// status code and the error code in the body.
+func statusError(path string, res *http.Response, raw []byte) error {
+	pe := &core.ProviderError{
+		Err: fmt.Errorf("%s returned %s: %s", path, res.Status, strings.TrimSpace(string(raw))),
+	}
+
+	var body apiError
+	json.Unmarshal(raw, &body)
+	kindOf(pe, res.StatusCode, body.Error)
+
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		pe.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return pe
+}
+
+// streamError turns an error event sent in the middle of a stream into a
+// core.ProviderError.
+func streamError(body apiErrorBody) error {
+	pe := &core.ProviderError{
+		Err: fmt.Errorf("stream failed: %s", body.Message),
+	}
+	status := 0
+	if body.Code == "rate_limit_exceeded" || body.Code == "insufficient_quota" {
+		status = http.StatusTooManyRequests
+	}
+	kindOf(pe, status, body)
+	return pe
+}
+
+// kindOf classifies pe by the status code, if any, and the error in the body.
+func kindOf(pe *core.ProviderError, status int, body apiErrorBody) {
+	code := body.Code
+	message := strings.ToLower(body.Message)
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || code == "invalid_api_key":
+		pe.Kind = core.ErrInvalidKey
+	case status == http.StatusTooManyRequests:
+		pe.Kind = core.ErrQuotaExceeded
+		// An exhausted balance does not come back by waiting
+		pe.Temporary = code != "insufficient_quota"
+	case code == "context_length_exceeded" || strings.Contains(message, "maximum context length"):
+		pe.Kind = core.ErrContextTooLong
+	case code == "content_filter" || code == "content_policy_violation":
+		pe.Kind = core.ErrSafetyBlocked
+	case status == http.StatusRequestTimeout || status >= 500:
+		pe.Temporary = true
+	}
+}
+
+// classify marks transport failures that may pass on a retry (timeouts, reset
+// connections, truncated responses) as temporary.
+func classify(err error) error {
+	if retry.Transient(err) {
+		return &core.ProviderError{Temporary: true, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		kind       error
+		temporary  bool
+		wait       time.Duration
+	}{
+		{"unauthorized", 401, "", `{"error":{"message":"Incorrect API key"}}`, core.ErrInvalidKey, false, 0},
+		{"invalid key code", 400, "", `{"error":{"code":"invalid_api_key"}}`, core.ErrInvalidKey, false, 0},
+		{"rate limited", 429, "7", `{"error":{"code":"rate_limit_exceeded"}}`, core.ErrQuotaExceeded, true, 7 * time.Second},
+		{"quota used up", 429, "", `{"error":{"code":"insufficient_quota"}}`, core.ErrQuotaExceeded, false, 0},
+		{"context too long", 400, "", `{"error":{"code":"context_length_exceeded"}}`, core.ErrContextTooLong, false, 0},
+		{"context too long by message", 400, "", `{"error":{"message":"This model's maximum context length is 8192 tokens"}}`, core.ErrContextTooLong, false, 0},
+		{"content filter", 400, "", `{"error":{"code":"content_filter"}}`, core.ErrSafetyBlocked, false, 0},
+		{"server error", 502, "", "bad gateway", nil, true, 0},
+		{"request timeout", 408, "", "", nil, true, 0},
+		{"other client error", 400, "soon", `{"error":{"message":"bad"}}`, nil, false, 0},
+	}
+	for _, tt := range tests {
+		res := &http.Response{StatusCode: tt.status, Status: strconv.Itoa(tt.status), Header: http.Header{}}
+		if tt.retryAfter != "" {
+			res.Header.Set("Retry-After", tt.retryAfter)
+		}
+		err := statusError("/chat/completions", res, []byte(tt.body))
+
+		var pe *core.ProviderError
+		if !errors.As(err, &pe) {
+			t.Fatalf("%s: statusError = %T, want a *core.ProviderError", tt.name, err)
+		}
+		if pe.Kind != tt.kind || pe.Temporary != tt.temporary || pe.RetryAfter != tt.wait {
+			t.Errorf("%s: kind %v, temporary %v, retry after %v; want %v, %v, %v",
+				tt.name, pe.Kind, pe.Temporary, pe.RetryAfter, tt.kind, tt.temporary, tt.wait)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	urlErr := func(err error) error { return &url.Error{Op: "Post", URL: "http://localhost/v1", Err: err} }
+	tests := []struct {
+		name      string
+		err       error
+		temporary bool
+	}{
+		{"timeout", urlErr(os.ErrDeadlineExceeded), true},
+		{"truncated body", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"caller's deadline", urlErr(context.DeadlineExceeded), false},
+		{"cancelled", urlErr(context.Canceled), false},
+		{"other transport failure", urlErr(errors.New("unsupported protocol scheme")), false},
+	}
+	for _, tt := range tests {
+		var pe *core.ProviderError
+		got := classify(tt.err)
+		if temporary := errors.As(got, &pe) && pe.Temporary; temporary != tt.temporary {
+			t.Errorf("%s: classify(%v) temporary = %v, want %v", tt.name, tt.err, temporary, tt.temporary)
+		}
+		if !errors.Is(got, tt.err) {
+			t.Errorf("%s: classify lost the original error", tt.name)
+		}
+	}
+	if classify(nil) != nil {
+		t.Error("classify(nil) != nil")
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	tests := []struct {
+		body      apiErrorBody
+		kind      error
+		temporary bool
+	}{
+		{apiErrorBody{Code: "rate_limit_exceeded"}, core.ErrQuotaExceeded, true},
+		{apiErrorBody{Code: "insufficient_quota"}, core.ErrQuotaExceeded, false},
+		{apiErrorBody{Code: "content_filter"}, core.ErrSafetyBlocked, false},
+		{apiErrorBody{Message: "overloaded"}, nil, false},
+	}
+	for _, tt := range tests {
+		var pe *core.ProviderError
+		if !errors.As(streamError(tt.body), &pe) || pe.Kind != tt.kind || pe.Temporary != tt.temporary {
+			t.Errorf("streamError(%+v) = %+v, want kind %v, temporary %v", tt.body, pe, tt.kind, tt.temporary)
+		}
+	}
+}
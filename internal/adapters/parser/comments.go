@@ -0,0 +1,110 @@
+package parser
+
+import "strings"
+
+// StripComments returns code without its comments and Python docstrings, with
+// blank lines dropped and runs of spaces inside a line collapsed, so that two
+// versions of a file that only differ in documentation compare equal. String
+// literals are kept as they are.
+func StripComments(lang Language, code string) string {
+	var lineComment []string
+	blockComment := false
+	switch lang {
+	case Python, Ruby:
+		lineComment = []string{"#"}
+	case Php:
+		lineComment = []string{"//", "#"}
+		blockComment = true
+	default:
+		lineComment = []string{"//"}
+		blockComment = true
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		switch {
+		case blockComment && strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return normalizeCode(out.String())
+			}
+			i += end + 4
+			out.WriteByte(' ')
+		case hasAnyPrefix(rest, lineComment):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				return normalizeCode(out.String())
+			}
+			i += end
+		case lang == Python && (strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''")):
+			quote := rest[:3]
+			end := strings.Index(rest[3:], quote)
+			if end < 0 {
+				end = len(rest) - 6
+			}
+			literal := rest[:end+6]
+			// A string that is a statement of its own is a docstring
+			if !atLineStart(code, i) {
+				out.WriteString(literal)
+			}
+			i += len(literal)
+		case rest[0] == '"' || rest[0] == '\'' || rest[0] == '`':
+			n := stringLiteral(rest)
+			out.WriteString(rest[:n])
+			i += n
+		default:
+			out.WriteByte(rest[0])
+			i++
+		}
+	}
+	return normalizeCode(out.String())
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// atLineStart reports whether only indentation precedes offset i on its line.
+func atLineStart(code string, i int) bool {
+	start := strings.LastIndexByte(code[:i], '\n') + 1
+	return strings.TrimSpace(code[start:i]) == ""
+}
+
+// stringLiteral returns the length of the string literal s starts with. Quotes
+// and apostrophes end at the end of the line at the latest, so that a stray
+// apostrophe (a Rust lifetime, say) does not swallow the rest of the file.
+func stringLiteral(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == quote:
+			return i + 1
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == '\n' && quote != '`':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// normalizeCode drops blank lines and trailing spaces and collapses the spaces
+// after the indentation of every line.
+func normalizeCode(code string) string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		body := strings.TrimLeft(line, " \t")
+		if body == "" {
+			continue
+		}
+		indent := line[:len(line)-len(body)]
+		lines = append(lines, indent+strings.Join(strings.Fields(body), " "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package parser
+
+import "testing"
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   Language
+		before string
+		after  string
+		same   bool
+	}{
+		{
+			name:   "go doc comment added",
+			lang:   Go,
+			before: "func Add(a, b int) int {\n\treturn a + b\n}\n",
+			after:  "// Add sums a and b.\nfunc Add(a, b int) int {\n\treturn a + b /* no overflow check */\n}\n",
+			same:   true,
+		},
+		{
+			name:   "go struct realigned for field comments",
+			lang:   Go,
+			before: "type T struct {\n\tName string\n\tAge  int\n}\n",
+			after:  "type T struct {\n\tName string // display name\n\tAge  int    // in years\n}\n",
+			same:   true,
+		},
+		{
+			name:   "go code changed",
+			lang:   Go,
+			before: "func Add(a, b int) int {\n\treturn a + b\n}\n",
+			after:  "// Add sums a and b.\nfunc Add(a, b int) int {\n\treturn a - b\n}\n",
+		},
+		{
+			name:   "comment markers in strings are code",
+			lang:   Go,
+			before: "var url = \"http://example.com\"\n",
+			after:  "var url = \"http:\"\n",
+		},
+		{
+			name:   "python docstring added",
+			lang:   Python,
+			before: "def f(x):\n    return x  # identity\n",
+			after:  "def f(x):\n    \"\"\"Return x.\"\"\"\n    return x\n",
+			same:   true,
+		},
+		{
+			name:   "python string assignment is code",
+			lang:   Python,
+			before: "x = \"\"\"a\"\"\"\n",
+			after:  "x = \"\"\"b\"\"\"\n",
+		},
+		{
+			name:   "python indentation is code",
+			lang:   Python,
+			before: "if a:\n    b()\nc()\n",
+			after:  "if a:\n    b()\n    c()\n",
+		},
+		{
+			name:   "js block and line comments",
+			lang:   JavaScript,
+			before: "const f = (a) => a;\n",
+			after:  "/**\n * Identity.\n * @param a value\n */\nconst f = (a) => a; // same\n",
+			same:   true,
+		},
+		{
+			name:   "apostrophe does not hide later comments",
+			lang:   Rust,
+			before: "fn f<'a>(x: &'a str) {}\n",
+			after:  "/// Does nothing.\nfn f<'a>(x: &'a str) {}\n",
+			same:   true,
+		},
+	}
+	for _, tt := range tests {
+		before, after := StripComments(tt.lang, tt.before), StripComments(tt.lang, tt.after)
+		if (before == after) != tt.same {
+			t.Errorf("%s: stripped versions equal = %v, want %v\nbefore: %q\nafter:  %q", tt.name, before == after, tt.same, before, after)
+		}
+	}
+}
@@ -50,6 +50,33 @@ func DetectLanguage(filename string) Language {
 	}
 }
 
+// ParseLanguage resolves a language name or common alias ("py", "ts", "golang").
+func ParseLanguage(name string) (Language, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "go", "golang":
+		return Go, true
+	case "python", "py":
+		return Python, true
+	case "typescript", "ts", "tsx":
+		return TypeScript, true
+	case "javascript", "js", "jsx":
+		return JavaScript, true
+	case "java":
+		return Java, true
+	case "rust", "rs":
+		return Rust, true
+	case "cpp", "c++", "cc":
+		return Cpp, true
+	case "php":
+		return Php, true
+	case "ruby", "rb":
+		return Ruby, true
+	case "csharp", "c#", "cs":
+		return Csharp, true
+	}
+	return Unknown, false
+}
+
 func ExtractSymbols(lang Language, content []byte) ([]Symbol, error) {
 	strContent := string(content)
 	symbols := []Symbol{}
@@ -91,11 +118,7 @@ func ExtractSymbols(lang Language, content []byte) ([]Symbol, error) {
 
 	// If no patterns or language not supported, return the entire file
 	if len(patterns) == 0 {
-		return []Symbol{{
-			Name: "entire_file",
-			Type: "file",
-			Code: strContent,
-		}}, nil
+		return []Symbol{wholeFile(lang, strContent)}, nil
 	}
 
 	// Pre-split lines for all patterns
@@ -128,20 +151,70 @@ func ExtractSymbols(lang Language, content []byte) ([]Symbol, error) {
 			}
 
 			symbols = append(symbols, Symbol{
-				Name: name,
-				Type: p.symType,
-				Code: code,
+				Name:        name,
+				Type:        p.symType,
+				Code:        code,
+				Signature:   strings.TrimRight(strings.TrimSpace(lines[startLine]), " {:"),
+				Doc:         commentAbove(lines, startLine),
+				Language:    lang,
+				StartByte:   m[0],
+				EndByte:     min(m[0]+len(code), len(strContent)),
+				StartLine:   startLine + 1,
+				EndLine:     endLine,
+				StartColumn: 1,
+				EndColumn:   len(lines[endLine-1]) + 1,
 			})
 		}
 	}
 
 	if len(symbols) == 0 {
-		symbols = append(symbols, Symbol{
-			Name: "entire_file",
-			Type: "file",
-			Code: strContent,
-		})
+		symbols = append(symbols, wholeFile(lang, strContent))
 	}
 
 	return symbols, nil
 }
+
+// wholeFile is the single symbol emitted when nothing more specific was found.
+func wholeFile(lang Language, content string) Symbol {
+	lines := strings.Split(content, "\n")
+	return Symbol{
+		Name:        "entire_file",
+		Type:        "file",
+		Code:        content,
+		Language:    lang,
+		EndByte:     len(content),
+		StartLine:   1,
+		EndLine:     len(lines),
+		StartColumn: 1,
+		EndColumn:   len(lines[len(lines)-1]) + 1,
+	}
+}
+
+// commentAbove collects the line comments directly preceding line idx.
+func commentAbove(lines []string, idx int) string {
+	var doc []string
+	for i := idx - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "/*") {
+			break
+		}
+		doc = append([]string{line}, doc...)
+	}
+	return cleanComment(strings.Join(doc, "\n"))
+}
+
+// cleanComment strips comment markers, leaving the comment text.
+func cleanComment(comment string) string {
+	var out []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"///", "//", "/**", "/*", "*/", "#", "*"} {
+			line = strings.TrimPrefix(line, prefix)
+		}
+		line = strings.TrimSpace(strings.TrimSuffix(line, "*/"))
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -12,6 +12,26 @@ type Symbol struct {
 	Name string
 	Type string
 	Code string
+	// Parent is the receiver type or enclosing class of a method, empty for top-level symbols.
+	Parent    string
+	Signature string
+	Doc       string
+	Language  Language
+	StartByte int
+	EndByte   int
+	// Lines and columns are 1-based and inclusive.
+	StartLine   int
+	EndLine     int
+	StartColumn int
+	EndColumn   int
+}
+
+// QualifiedName returns Parent.Name for nested symbols and Name otherwise.
+func (s Symbol) QualifiedName() string {
+	if s.Parent == "" {
+		return s.Name
+	}
+	return s.Parent + "." + s.Name
 }
 
 type GenericParser struct {
@@ -0,0 +1,310 @@
+//go:build cgo
+
+package parser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tsgo "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tsjavascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tspython "github.com/tree-sitter/tree-sitter-python/bindings/go"
+	tstypescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// Backend names the symbol extractor compiled in, for `archon status`.
+const Backend = "tree-sitter"
+
+// TreeSitterParser extracts symbols from the syntax tree, so every symbol carries
+// its exact body and range. Languages without a bundled grammar fall back to the
+// regex extractor.
+type TreeSitterParser struct {
+	fallback *GenericParser
+}
+
+func NewTreeSitterParser() *TreeSitterParser {
+	return &TreeSitterParser{
+		fallback: NewGenericParser(),
+	}
+}
+
+func grammarFor(filename string, lang Language) *sitter.Language {
+	switch lang {
+	case Go:
+		return sitter.NewLanguage(tsgo.Language())
+	case Python:
+		return sitter.NewLanguage(tspython.Language())
+	case JavaScript:
+		return sitter.NewLanguage(tsjavascript.Language())
+	case TypeScript:
+		if filepath.Ext(filename) == ".tsx" {
+			return sitter.NewLanguage(tstypescript.LanguageTSX())
+		}
+		return sitter.NewLanguage(tstypescript.LanguageTypescript())
+	}
+	return nil
+}
+
+func (p *TreeSitterParser) Parse(ctx context.Context, filename string, content []byte) ([]Symbol, error) {
+	lang := DetectLanguage(filename)
+	grammar := grammarFor(filename, lang)
+	if grammar == nil {
+		return p.fallback.Parse(ctx, filename, content)
+	}
+
+	tsParser := sitter.NewParser()
+	defer tsParser.Close()
+	if err := tsParser.SetLanguage(grammar); err != nil {
+		return nil, fmt.Errorf("failed to load %s grammar: %w", lang, err)
+	}
+
+	tree := tsParser.ParseCtx(ctx, content, nil)
+	if tree == nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to parse %s", filename)
+	}
+	defer tree.Close()
+
+	e := &extractor{lang: lang, source: content}
+	e.visit(tree.RootNode(), "")
+
+	if len(e.symbols) == 0 {
+		return []Symbol{wholeFile(lang, string(content))}, nil
+	}
+	return e.symbols, nil
+}
+
+type extractor struct {
+	lang    Language
+	source  []byte
+	symbols []Symbol
+}
+
+func (e *extractor) visit(node *sitter.Node, parent string) {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child := node.NamedChild(i)
+		if child == nil {
+			continue
+		}
+		switch e.lang {
+		case Go:
+			e.visitGo(child)
+		case Python:
+			e.visitPython(child, parent)
+		case JavaScript, TypeScript:
+			e.visitJS(child, parent)
+		}
+	}
+}
+
+func (e *extractor) visitGo(node *sitter.Node) {
+	switch node.Kind() {
+	case "function_declaration":
+		e.add(node, node, "function", "")
+	case "method_declaration":
+		e.add(node, node, "method", e.goReceiverType(node))
+	case "type_declaration":
+		// A grouped `type ( ... )` block yields one symbol per spec.
+		specs := 0
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			if c := node.NamedChild(i); c != nil && c.Kind() == "type_spec" {
+				specs++
+			}
+		}
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			spec := node.NamedChild(i)
+			if spec == nil || (spec.Kind() != "type_spec" && spec.Kind() != "type_alias") {
+				continue
+			}
+			outer := spec
+			if specs == 1 {
+				outer = node
+			}
+			e.add(outer, spec, "type", "")
+		}
+	}
+}
+
+func (e *extractor) goReceiverType(method *sitter.Node) string {
+	receiver := method.ChildByFieldName("receiver")
+	if receiver == nil {
+		return ""
+	}
+	for i := uint(0); i < receiver.NamedChildCount(); i++ {
+		param := receiver.NamedChild(i)
+		if param == nil {
+			continue
+		}
+		if typ := param.ChildByFieldName("type"); typ != nil {
+			name := strings.TrimLeft(typ.Utf8Text(e.source), "*")
+			if idx := strings.Index(name, "["); idx >= 0 {
+				name = name[:idx]
+			}
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+func (e *extractor) visitPython(node *sitter.Node, parent string) {
+	outer := node
+	if node.Kind() == "decorated_definition" {
+		if def := node.ChildByFieldName("definition"); def != nil {
+			node = def
+		}
+	}
+
+	switch node.Kind() {
+	case "function_definition":
+		if parent != "" {
+			e.add(outer, node, "method", parent)
+		} else {
+			e.add(outer, node, "function", "")
+		}
+	case "class_definition":
+		name := e.name(node)
+		e.add(outer, node, "class", parent)
+		if body := node.ChildByFieldName("body"); body != nil {
+			e.visit(body, qualify(parent, name))
+		}
+	}
+}
+
+func (e *extractor) visitJS(node *sitter.Node, parent string) {
+	outer := node
+	if node.Kind() == "export_statement" {
+		if decl := node.ChildByFieldName("declaration"); decl != nil {
+			node = decl
+		} else {
+			return
+		}
+	}
+
+	switch node.Kind() {
+	case "function_declaration", "generator_function_declaration":
+		e.add(outer, node, "function", "")
+	case "class_declaration", "abstract_class_declaration":
+		name := e.name(node)
+		e.add(outer, node, "class", parent)
+		if body := node.ChildByFieldName("body"); body != nil {
+			e.visit(body, qualify(parent, name))
+		}
+	case "method_definition":
+		if parent != "" {
+			e.add(outer, node, "method", parent)
+		}
+	case "interface_declaration":
+		e.add(outer, node, "interface", "")
+	case "type_alias_declaration", "enum_declaration":
+		e.add(outer, node, "type", "")
+	case "lexical_declaration", "variable_declaration":
+		// const handler = () => {...} / const f = function () {...}
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			decl := node.NamedChild(i)
+			if decl == nil || decl.Kind() != "variable_declarator" {
+				continue
+			}
+			value := decl.ChildByFieldName("value")
+			if value == nil {
+				continue
+			}
+			switch value.Kind() {
+			case "arrow_function", "function_expression", "function", "generator_function":
+				e.add(outer, decl, "function", "")
+			}
+		}
+	}
+}
+
+func (e *extractor) name(node *sitter.Node) string {
+	if n := node.ChildByFieldName("name"); n != nil {
+		return n.Utf8Text(e.source)
+	}
+	return ""
+}
+
+// add records a symbol named after decl whose body spans outer, so export
+// keywords, decorators and grouping are kept with the declaration.
+func (e *extractor) add(outer *sitter.Node, decl *sitter.Node, symType string, parent string) {
+	name := e.name(decl)
+	if name == "" {
+		return
+	}
+	start, end := outer.StartByte(), outer.EndByte()
+	e.symbols = append(e.symbols, Symbol{
+		Name:        name,
+		Type:        symType,
+		Code:        string(e.source[start:end]),
+		Parent:      parent,
+		Signature:   e.signature(decl),
+		Doc:         e.doc(outer, decl),
+		Language:    e.lang,
+		StartByte:   int(start),
+		EndByte:     int(end),
+		StartLine:   int(outer.StartPosition().Row) + 1,
+		EndLine:     int(outer.EndPosition().Row) + 1,
+		StartColumn: int(outer.StartPosition().Column) + 1,
+		EndColumn:   int(outer.EndPosition().Column) + 1,
+	})
+}
+
+// signature is the declaration text up to its body, collapsed onto one line.
+func (e *extractor) signature(decl *sitter.Node) string {
+	end := decl.EndByte()
+	if body := decl.ChildByFieldName("body"); body != nil {
+		end = body.StartByte()
+	} else if value := decl.ChildByFieldName("value"); value != nil {
+		// const f = (a, b) => ... : keep the parameter list of the function value
+		if vb := value.ChildByFieldName("body"); vb != nil {
+			end = vb.StartByte()
+		}
+	}
+	text := string(e.source[decl.StartByte():end])
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 && decl.ChildByFieldName("body") == nil {
+		text = text[:idx]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.TrimRight(text, " {:=>")
+}
+
+// doc returns the comment block directly above the declaration, or the
+// docstring for Python definitions.
+func (e *extractor) doc(outer *sitter.Node, decl *sitter.Node) string {
+	if e.lang == Python {
+		body := decl.ChildByFieldName("body")
+		if body == nil || body.NamedChildCount() == 0 {
+			return ""
+		}
+		first := body.NamedChild(0)
+		if first == nil || first.Kind() != "expression_statement" || first.NamedChildCount() == 0 {
+			return ""
+		}
+		if str := first.NamedChild(0); str != nil && str.Kind() == "string" {
+			return strings.Trim(str.Utf8Text(e.source), "\"' \n\t")
+		}
+		return ""
+	}
+
+	var lines []string
+	row := outer.StartPosition().Row
+	for prev := outer.PrevNamedSibling(); prev != nil && prev.Kind() == "comment"; prev = prev.PrevNamedSibling() {
+		if prev.EndPosition().Row+1 != row {
+			break
+		}
+		lines = append([]string{prev.Utf8Text(e.source)}, lines...)
+		row = prev.StartPosition().Row
+	}
+	return cleanComment(strings.Join(lines, "\n"))
+}
+
+func qualify(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,26 @@
+//go:build !cgo
+
+package parser
+
+import (
+	"context"
+)
+
+// Backend names the symbol extractor compiled in, for `archon status`.
+const Backend = "regex (built without cgo, no Tree-sitter)"
+
+// TreeSitterParser without cgo: the Tree-sitter grammars are C code, so
+// cgo-less builds (e.g. plain cross-compilation) use the regex extractor for every language.
+type TreeSitterParser struct {
+	fallback *GenericParser
+}
+
+func NewTreeSitterParser() *TreeSitterParser {
+	return &TreeSitterParser{
+		fallback: NewGenericParser(),
+	}
+}
+
+func (p *TreeSitterParser) Parse(ctx context.Context, filename string, content []byte) ([]Symbol, error) {
+	return p.fallback.Parse(ctx, filename, content)
+}
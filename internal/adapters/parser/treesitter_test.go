@@ -0,0 +1,263 @@
+//go:build cgo
+
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, filename, src string) []Symbol {
+	t.Helper()
+	symbols, err := NewTreeSitterParser().Parse(context.Background(), filename, []byte(src))
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", filename, err)
+	}
+	return symbols
+}
+
+func find(t *testing.T, symbols []Symbol, name string) Symbol {
+	t.Helper()
+	for _, s := range symbols {
+		if s.Name == name {
+			return s
+		}
+	}
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Name)
+	}
+	t.Fatalf("no symbol %q in %q", name, names)
+	return Symbol{}
+}
+
+// span is the part of a Symbol the extractor tests compare.
+type span struct {
+	Type, Parent           string
+	StartLine, EndLine     int
+	StartColumn, EndColumn int
+	Signature, Doc         string
+}
+
+func spanOf(s Symbol) span {
+	return span{s.Type, s.Parent, s.StartLine, s.EndLine, s.StartColumn, s.EndColumn, s.Signature, s.Doc}
+}
+
+func TestTreeSitterGo(t *testing.T) {
+	src := `package demo
+
+// Add sums two numbers.
+// It never overflows.
+func Add(a, b int) int {
+	return a + b
+}
+
+type Box[T any] struct {
+	v T
+}
+
+func (b *Box[T]) Get() T { return b.v }
+
+func (s Stack) Len() int { return 0 }
+
+type (
+	Stack []int
+	ID    string
+)
+`
+	symbols := parse(t, "demo.go", src)
+
+	tests := []struct {
+		name string
+		want span
+	}{
+		{"Add", span{"function", "", 5, 7, 1, 2, "func Add(a, b int) int", "Add sums two numbers.\nIt never overflows."}},
+		{"Box", span{"type", "", 9, 11, 1, 2, "Box[T any] struct", ""}},
+		{"Get", span{"method", "Box", 13, 13, 1, 40, "func (b *Box[T]) Get() T", ""}},
+		{"Len", span{"method", "Stack", 15, 15, 1, 38, "func (s Stack) Len() int", ""}},
+		// Specs in a grouped declaration are one symbol each, spanning only the spec
+		{"Stack", span{"type", "", 18, 18, 2, 13, "Stack []int", ""}},
+		{"ID", span{"type", "", 19, 19, 2, 14, "ID string", ""}},
+	}
+	for _, tt := range tests {
+		if got := spanOf(find(t, symbols, tt.name)); got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if len(symbols) != len(tests) {
+		t.Errorf("got %d symbols, want %d", len(symbols), len(tests))
+	}
+
+	// Byte offsets slice the symbol's code out of the source exactly
+	for _, s := range symbols {
+		if src[s.StartByte:s.EndByte] != s.Code {
+			t.Errorf("%s: source[%d:%d] = %q, want Code %q", s.Name, s.StartByte, s.EndByte, src[s.StartByte:s.EndByte], s.Code)
+		}
+	}
+	add := find(t, symbols, "Add")
+	if want := strings.Index(src, "func Add"); add.StartByte != want {
+		t.Errorf("Add.StartByte = %d, want %d", add.StartByte, want)
+	}
+	if !strings.HasSuffix(add.Code, "return a + b\n}") {
+		t.Errorf("Add.Code = %q, want the whole body", add.Code)
+	}
+	// A single type spec keeps its `type` keyword
+	if box := find(t, symbols, "Box"); !strings.HasPrefix(box.Code, "type Box[T any]") {
+		t.Errorf("Box.Code = %q, want it to start at the type keyword", box.Code)
+	}
+}
+
+func TestTreeSitterPython(t *testing.T) {
+	src := `import functools
+
+@functools.cache
+def top(x):
+    """Return x."""
+    return x
+
+class Outer:
+    """The outer class."""
+
+    class Inner:
+        def method(self):
+            pass
+
+    def run(self, n: int) -> None:
+        pass
+`
+	symbols := parse(t, "demo.py", src)
+
+	tests := []struct {
+		name string
+		want span
+	}{
+		// The decorator belongs to the function's range
+		{"top", span{"function", "", 3, 6, 1, 13, "def top(x)", "Return x."}},
+		{"Outer", span{"class", "", 8, 16, 1, 13, "class Outer", "The outer class."}},
+		{"Inner", span{"class", "Outer", 11, 13, 5, 17, "class Inner", ""}},
+		{"method", span{"method", "Outer.Inner", 12, 13, 9, 17, "def method(self)", ""}},
+		{"run", span{"method", "Outer", 15, 16, 5, 13, "def run(self, n: int) -> None", ""}},
+	}
+	for _, tt := range tests {
+		if got := spanOf(find(t, symbols, tt.name)); got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if top := find(t, symbols, "top"); !strings.HasPrefix(top.Code, "@functools.cache\n") {
+		t.Errorf("top.Code = %q, want the decorator included", top.Code)
+	}
+}
+
+func TestTreeSitterJavaScript(t *testing.T) {
+	src := `/** Adds one. */
+export const inc = (n) => n + 1;
+
+const named = function (a, b) {
+  return a;
+};
+
+const value = 42;
+
+export class Shape {
+  area() {
+    return 0;
+  }
+}
+
+class Outer {
+  static Inner = class {};
+  run() {}
+}
+`
+	symbols := parse(t, "demo.js", src)
+
+	tests := []struct {
+		name string
+		want span
+	}{
+		{"inc", span{"function", "", 2, 2, 1, 33, "inc = (n)", "Adds one."}},
+		{"named", span{"function", "", 4, 6, 1, 3, "named = function (a, b)", ""}},
+		{"Shape", span{"class", "", 10, 14, 1, 2, "class Shape", ""}},
+		{"area", span{"method", "Shape", 11, 13, 3, 4, "area()", ""}},
+		{"run", span{"method", "Outer", 18, 18, 3, 11, "run()", ""}},
+	}
+	for _, tt := range tests {
+		if got := spanOf(find(t, symbols, tt.name)); got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	for _, s := range symbols {
+		if s.Name == "value" {
+			t.Error("a constant that is not a function was extracted")
+		}
+	}
+}
+
+func TestTreeSitterTypeScriptNestedClass(t *testing.T) {
+	src := `export interface Named {
+  name: string;
+}
+
+export type ID = string;
+
+namespace NS {}
+
+export abstract class Base {
+  abstract kind(): string;
+  describe(): string {
+    return this.kind();
+  }
+}
+`
+	symbols := parse(t, "demo.ts", src)
+
+	tests := []struct {
+		name string
+		want span
+	}{
+		{"Named", span{"interface", "", 1, 3, 1, 2, "interface Named", ""}},
+		{"ID", span{"type", "", 5, 5, 1, 25, "type ID = string;", ""}},
+		{"Base", span{"class", "", 9, 14, 1, 2, "abstract class Base", ""}},
+		{"describe", span{"method", "Base", 11, 13, 3, 4, "describe(): string", ""}},
+	}
+	for _, tt := range tests {
+		if got := spanOf(find(t, symbols, tt.name)); got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	// The export keyword is part of the exported declaration's code
+	if named := find(t, symbols, "Named"); !strings.HasPrefix(named.Code, "export interface") {
+		t.Errorf("Named.Code = %q, want it to include export", named.Code)
+	}
+}
+
+func TestTreeSitterFallback(t *testing.T) {
+	// Rust has no bundled grammar, so the regex extractor's result is used as is
+	src := "fn main() {}\n"
+	got := parse(t, "main.rs", src)
+	want, _ := ExtractSymbols(Rust, []byte(src))
+	if len(got) != 1 || len(want) != 1 || got[0] != want[0] {
+		t.Errorf("Parse(main.rs) = %+v, want the fallback result %+v", got, want)
+	}
+
+	java := "public class App {\n  public void run() {\n  }\n}\n"
+	got = parse(t, "App.java", java)
+	if s := find(t, got, "App"); s.Type != "class" || s.StartLine != 1 {
+		t.Errorf("App = %+v, want a class on line 1", s)
+	}
+	find(t, got, "run")
+}
+
+func TestTreeSitterWholeFile(t *testing.T) {
+	// A file the grammar parses but which declares nothing is one whole-file symbol
+	src := "package demo\n\nvar x = 1\n"
+	got := parse(t, "vars.go", src)
+	if len(got) != 1 {
+		t.Fatalf("got %d symbols, want 1", len(got))
+	}
+	s := got[0]
+	if s.Name != "entire_file" || s.Code != src || s.StartLine != 1 || s.EndLine != 4 || s.EndByte != len(src) {
+		t.Errorf("whole file symbol = %+v", s)
+	}
+}
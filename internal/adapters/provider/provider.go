@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"archon/internal/adapters/gemini"
+	"archon/internal/adapters/openai"
+	"archon/internal/adapters/vectordb"
+	"archon/internal/config"
+	"archon/internal/core"
+	"context"
+	"fmt"
+	"io"
+)
+
+// NewLLM builds the LLM adapter selected by the `provider` key in the configuration.
+func NewLLM(ctx context.Context, cfg *config.Config) (core.LLM, error) {
+	switch cfg.Provider {
+	case config.ProviderGemini:
+		if cfg.GeminiKey == "" {
+			return nil, fmt.Errorf("Gemini API key not found. Use 'archon auth' or set ARCHON_GEMINI_KEY environment variable.")
+		}
+		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case config.ProviderOpenAI:
+		return openai.NewClient(cfg.OpenAIBaseURL, cfg.OpenAIKey, cfg.ModelID), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (supported: %s, %s)", cfg.Provider, config.ProviderGemini, config.ProviderOpenAI)
+	}
+}
+
+// NewEmbedder builds the embedding backend selected by the `embedder` key in the configuration.
+func NewEmbedder(ctx context.Context, cfg *config.Config) (vectordb.Embedder, error) {
+	switch cfg.Embedder {
+	case config.ProviderGemini:
+		if cfg.GeminiKey == "" {
+			return nil, fmt.Errorf("Gemini API key not found, required by the gemini embedder. Use 'archon auth', or set 'embedder: hash' for offline indexing or 'embedder: none' for keyword search only.")
+		}
+		embedder, err := gemini.NewEmbedder(ctx, cfg.GeminiKey, cfg.EmbeddingModel)
+		if err != nil {
+			return nil, err
+		}
+		return embedder, nil
+	case config.ProviderOpenAI:
+		return openai.NewEmbedder(cfg.OpenAIBaseURL, cfg.OpenAIKey, cfg.EmbeddingModel), nil
+	case config.EmbedderHash:
+		return vectordb.NewHashEmbedder(0), nil
+	case config.EmbedderNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown embedder %q (supported: %s, %s, %s, %s)", cfg.Embedder, config.ProviderGemini, config.ProviderOpenAI, config.EmbedderHash, config.EmbedderNone)
+	}
+}
+
+// NewStore opens the project's vector store using the configured embedder.
+// Only `embedder: none` gives a keyword-only store; an embedder that cannot be
+// used (e.g. Gemini without an API key) is an error rather than a silent fallback.
+func NewStore(ctx context.Context, cfg *config.Config) (*vectordb.Store, error) {
+	embedder, err := NewEmbedder(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := vectordb.NewStore(ctx, vectordb.DefaultPath, embedder)
+	if err != nil {
+		if closer, ok := embedder.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewBatchProcessor builds a batch processor limited by the batch_* settings.
+func NewBatchProcessor(cfg *config.Config) *gemini.BatchProcessor {
+	return gemini.NewBatchProcessor(gemini.BatchOptions{
+		Concurrency: cfg.BatchConcurrency,
+		RPM:         cfg.BatchRPM,
+		TPM:         cfg.BatchTPM,
+	})
+}
@@ -0,0 +1,98 @@
+package retry
+
+import (
+	"archon/internal/core"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Policy retries temporary provider failures (rate limits, 5xx, timeouts) with
+// exponential backoff and jitter.
+type Policy struct {
+	// Attempts is the total number of tries, including the first one.
+	Attempts int
+	// BaseDelay is the delay before the first retry; it doubles for every further one.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay.
+	MaxDelay time.Duration
+	// MaxRetryAfter is the longest server retry hint that is honored. A server
+	// asking for a longer wait (e.g. a daily quota) fails immediately.
+	MaxRetryAfter time.Duration
+}
+
+// Default is the policy used by the provider adapters.
+var Default = Policy{
+	Attempts:      5,
+	BaseDelay:     time.Second,
+	MaxDelay:      30 * time.Second,
+	MaxRetryAfter: 2 * time.Minute,
+}
+
+// Do calls op until it succeeds, fails permanently or runs out of attempts.
+// Only a *core.ProviderError marked Temporary is retried. The error of the
+// last attempt is returned.
+func (p Policy) Do(ctx context.Context, op func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = op()
+		if err == nil {
+			return nil
+		}
+
+		delay, ok := p.next(attempt, err)
+		if !ok {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// next returns the delay before retrying after the given failed attempt, or
+// false if err must not be retried.
+func (p Policy) next(attempt int, err error) (time.Duration, bool) {
+	var pe *core.ProviderError
+	if !errors.As(err, &pe) || !pe.Temporary || attempt+1 >= p.Attempts {
+		return 0, false
+	}
+
+	if pe.RetryAfter > 0 {
+		if pe.RetryAfter > p.MaxRetryAfter {
+			return 0, false
+		}
+		return pe.RetryAfter, true
+	}
+
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Jitter between half and the full delay, so parallel workers spread out
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)), true
+}
+
+// Transient reports whether a transport failure is worth retrying: a network
+// timeout, a reset connection or a response cut short. The caller's own
+// cancellation or deadline never is, and neither are other failures such as an
+// unknown host or a refused connection.
+func Transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,114 @@
+package retry
+
+import (
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Attempts:      3,
+	BaseDelay:     time.Second,
+	MaxDelay:      3 * time.Second,
+	MaxRetryAfter: time.Minute,
+}
+
+func TestPolicyNext(t *testing.T) {
+	temporary := &core.ProviderError{Temporary: true, Err: errors.New("503")}
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		ok      bool
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"plain error", 0, errors.New("bad request"), false, 0, 0},
+		{"permanent provider error", 0, &core.ProviderError{Kind: core.ErrInvalidKey, Err: errors.New("401")}, false, 0, 0},
+		{"first retry", 0, temporary, true, 500 * time.Millisecond, time.Second},
+		{"backoff doubles", 1, fmt.Errorf("wrapped: %w", temporary), true, time.Second, 2 * time.Second},
+		{"out of attempts", 2, temporary, false, 0, 0},
+		{"retry after is honored", 0, &core.ProviderError{Temporary: true, RetryAfter: 20 * time.Second, Err: errors.New("429")}, true, 20 * time.Second, 20 * time.Second},
+		{"retry after too long", 0, &core.ProviderError{Temporary: true, RetryAfter: time.Hour, Err: errors.New("429")}, false, 0, 0},
+	}
+	for _, tt := range tests {
+		delay, ok := testPolicy.next(tt.attempt, tt.err)
+		if ok != tt.ok || delay < tt.min || delay > tt.max {
+			t.Errorf("%s: next = %v, %v, want %v in [%v, %v]", tt.name, delay, ok, tt.ok, tt.min, tt.max)
+		}
+	}
+
+	// The delay is capped, also when the shift overflows
+	for _, attempt := range []int{5, 70} {
+		p := testPolicy
+		p.Attempts = 100
+		if delay, ok := p.next(attempt, temporary); !ok || delay < p.MaxDelay/2 || delay > p.MaxDelay {
+			t.Errorf("attempt %d: next = %v, %v, want at most %v", attempt, delay, ok, p.MaxDelay)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	p := Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetryAfter: time.Second}
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return &core.ProviderError{Temporary: true, Err: errors.New("503")}
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("Do = %v after %d calls, want the last error after 3", err, calls)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return &core.ProviderError{Temporary: true, Err: errors.New("503")}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Do = %v after %d calls, want success after 2", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	p.BaseDelay, p.MaxDelay = time.Hour, time.Hour
+	p.Do(ctx, func() error {
+		calls++
+		return &core.ProviderError{Temporary: true, Err: errors.New("503")}
+	})
+	if calls != 1 {
+		t.Errorf("cancelled Do made %d calls, want 1", calls)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network timeout", &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, true},
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"truncated response", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, false},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{"caller's deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("invalid json"), false},
+	}
+	for _, tt := range tests {
+		if got := Transient(tt.err); got != tt.want {
+			t.Errorf("%s: Transient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
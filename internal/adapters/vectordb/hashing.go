@@ -0,0 +1,112 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const defaultHashDimensions = 1024
+
+// HashEmbedder is a fully offline, deterministic embedder. It hashes identifier
+// tokens, token bigrams and character trigrams into a fixed-size vector
+// (the "hashing trick"), weighted by sublinear term frequency. It needs no
+// network access and no API key, at the cost of purely lexical similarity.
+type HashEmbedder struct {
+	dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = defaultHashDimensions
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash:v1:%d", e.dims)
+}
+
+func (e *HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	counts := make(map[string]int)
+	tokens := Tokenize(text)
+	for i, tok := range tokens {
+		counts["w:"+tok]++
+		if i > 0 {
+			counts["b:"+tokens[i-1]+"_"+tok]++
+		}
+		padded := "^" + tok + "$"
+		for j := 0; j+3 <= len(padded); j++ {
+			counts["c:"+padded[j:j+3]]++
+		}
+	}
+
+	vec := make([]float32, e.dims)
+	for feature, n := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		weight := float32(1 + math.Log(float64(n)))
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vec[sum%uint64(e.dims)] += weight
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		// chromem-go rejects zero vectors, so give empty input a stable direction.
+		vec[0] = 1
+		return vec, nil
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+	return vec, nil
+}
+
+// Tokenize splits text into lowercase word tokens, breaking identifiers on
+// camelCase, snake_case and digits so "CalculateProjectHash" matches "project hash".
+func Tokenize(text string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			tokens = append(tokens, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r):
+			if unicode.IsUpper(r) && len(cur) > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					flush()
+				}
+			} else if len(cur) > 0 && unicode.IsDigit(cur[len(cur)-1]) {
+				flush()
+			}
+			cur = append(cur, r)
+		case unicode.IsDigit(r):
+			if len(cur) > 0 && !unicode.IsDigit(cur[len(cur)-1]) {
+				flush()
+			}
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
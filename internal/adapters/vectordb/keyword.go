@@ -0,0 +1,261 @@
+package vectordb
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/philippgille/chromem-go"
+)
+
+const keywordFile = "keywords.gob"
+
+// BM25 parameters, the usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// nameBoost repeats the tokens of a symbol's name and signature, so a query for an
+// identifier ranks its definition above code that merely mentions it.
+const nameBoost = 3
+
+var identifierRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// KeywordIndex is an inverted index over symbol names and code, ranked with BM25.
+// It finds exact identifiers that embedding similarity misses and works without
+// any embedder.
+type KeywordIndex struct {
+	mu    sync.RWMutex
+	path  string
+	dirty bool
+
+	docs     map[string]*keywordDoc
+	postings map[string]map[string]int
+	totalLen int
+}
+
+type keywordDoc struct {
+	Content  string
+	Metadata map[string]string
+	Terms    map[string]int
+	Length   int
+}
+
+// NewKeywordIndex loads the index persisted at path, or starts an empty one.
+func NewKeywordIndex(path string) (*KeywordIndex, error) {
+	k := &KeywordIndex{
+		path:     path,
+		docs:     make(map[string]*keywordDoc),
+		postings: make(map[string]map[string]int),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return k, nil
+		}
+		return nil, fmt.Errorf("failed to open keyword index: %w", err)
+	}
+	defer f.Close()
+
+	var docs map[string]*keywordDoc
+	if err := gob.NewDecoder(f).Decode(&docs); err != nil {
+		// A corrupt index is rebuilt by the next `archon index`
+		return k, nil
+	}
+	for id, doc := range docs {
+		k.insert(id, doc)
+	}
+	return k, nil
+}
+
+// keywordTerms tokenizes text for the index. Besides the split tokens of Tokenize,
+// compound identifiers are also kept whole ("calculateprojecthash"), so an exact
+// identifier query scores much higher than its common parts.
+func keywordTerms(text string) []string {
+	terms := Tokenize(text)
+	for _, ident := range identifierRe.FindAllString(text, -1) {
+		if len(Tokenize(ident)) > 1 {
+			terms = append(terms, strings.ToLower(ident))
+		}
+	}
+	return terms
+}
+
+func (k *KeywordIndex) Add(id, content string, metadata map[string]string) {
+	doc := &keywordDoc{Content: content, Metadata: metadata, Terms: make(map[string]int)}
+	terms := keywordTerms(content)
+	for i := 0; i < nameBoost; i++ {
+		terms = append(terms, keywordTerms(metadata["name"]+" "+metadata["parent"])...)
+	}
+	for _, t := range terms {
+		doc.Terms[t]++
+	}
+	doc.Length = len(terms)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.remove(id)
+	k.insert(id, doc)
+	k.dirty = true
+}
+
+func (k *KeywordIndex) Delete(ids ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, id := range ids {
+		k.remove(id)
+	}
+	k.dirty = true
+}
+
+func (k *KeywordIndex) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.docs = make(map[string]*keywordDoc)
+	k.postings = make(map[string]map[string]int)
+	k.totalLen = 0
+	k.dirty = true
+}
+
+func (k *KeywordIndex) insert(id string, doc *keywordDoc) {
+	k.docs[id] = doc
+	k.totalLen += doc.Length
+	for t, tf := range doc.Terms {
+		if k.postings[t] == nil {
+			k.postings[t] = make(map[string]int)
+		}
+		k.postings[t][id] = tf
+	}
+}
+
+func (k *KeywordIndex) remove(id string) {
+	doc, ok := k.docs[id]
+	if !ok {
+		return
+	}
+	for t := range doc.Terms {
+		delete(k.postings[t], id)
+		if len(k.postings[t]) == 0 {
+			delete(k.postings, t)
+		}
+	}
+	k.totalLen -= doc.Length
+	delete(k.docs, id)
+}
+
+// Get returns the stored document with the given ID.
+func (k *KeywordIndex) Get(id string) (chromem.Document, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	doc, ok := k.docs[id]
+	if !ok {
+		return chromem.Document{}, false
+	}
+	return chromem.Document{ID: id, Content: doc.Content, Metadata: doc.Metadata}, true
+}
+
+func (k *KeywordIndex) Count() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.docs)
+}
+
+// Search returns up to n documents ranked by BM25, only considering documents
+// whose metadata passes match (if not nil). Similarity holds the BM25 score,
+// which is only comparable within one result set.
+func (k *KeywordIndex) Search(query string, n int, match func(metadata map[string]string) bool) []chromem.Result {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.docs) == 0 || n <= 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	scores := make(map[string]float64)
+	avgLen := float64(k.totalLen) / float64(len(k.docs))
+	for _, term := range keywordTerms(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := k.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(len(k.docs))-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			if match != nil && !match(k.docs[id].Metadata) {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(k.docs[id].Length)/avgLen)
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+
+	results := make([]chromem.Result, 0, len(ids))
+	for _, id := range ids {
+		doc := k.docs[id]
+		results = append(results, chromem.Result{
+			ID:         id,
+			Content:    doc.Content,
+			Metadata:   doc.Metadata,
+			Similarity: float32(scores[id]),
+		})
+	}
+	return results
+}
+
+// Save persists the index if it changed since it was loaded or last saved.
+func (k *KeywordIndex) Save() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return err
+	}
+	tmp := k.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(k.docs); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		return err
+	}
+	k.dirty = false
+	return nil
+}
@@ -0,0 +1,131 @@
+package vectordb
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"CalculateProjectHash", []string{"calculate", "project", "hash"}},
+		{"snake_case_name", []string{"snake", "case", "name"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"utf8Decode", []string{"utf", "8", "decode"}},
+		{"a.b(c)", []string{"a", "b", "c"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordTermsKeepCompoundIdentifiers(t *testing.T) {
+	terms := keywordTerms("func CalculateProjectHash()")
+	if !slices.Contains(terms, "calculateprojecthash") || !slices.Contains(terms, "project") {
+		t.Errorf("keywordTerms = %q, want the whole identifier and its parts", terms)
+	}
+	// Single-word identifiers are not counted twice
+	if got := keywordTerms("run"); !slices.Equal(got, []string{"run"}) {
+		t.Errorf("keywordTerms(\"run\") = %q, want [run]", got)
+	}
+}
+
+func newTestIndex(t *testing.T) *KeywordIndex {
+	t.Helper()
+	k, err := NewKeywordIndex(filepath.Join(t.TempDir(), keywordFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Add("hash", "func CalculateProjectHash(dir string) string { return sha(dir) }",
+		map[string]string{"file": "internal/gemini/caching.go", "name": "CalculateProjectHash", "type": "function", "language": "go"})
+	k.Add("caller", "func status() { h := CalculateProjectHash(\".\"); print(h) }",
+		map[string]string{"file": "internal/cli/status.go", "name": "status", "type": "function", "language": "go"})
+	k.Add("other", "func LoadConfig() (*Config, error) { return read(project) }",
+		map[string]string{"file": "internal/config/config.go", "name": "LoadConfig", "type": "function", "language": "go"})
+	k.Add("py", "def project_hash(): pass",
+		map[string]string{"file": "tools/hash.py", "name": "project_hash", "type": "function", "language": "python"})
+	return k
+}
+
+func resultIDs(k *KeywordIndex, query string, n int, match func(map[string]string) bool) []string {
+	var ids []string
+	for _, r := range k.Search(query, n, match) {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestKeywordSearchRanking(t *testing.T) {
+	k := newTestIndex(t)
+
+	tests := []struct {
+		query string
+		n     int
+		want  []string
+	}{
+		// The definition ranks above code that merely calls it, thanks to the name boost
+		{"CalculateProjectHash", 2, []string{"hash", "caller"}},
+		{"LoadConfig", 1, []string{"other"}},
+		{"nothing matches this", 5, nil},
+		{"CalculateProjectHash", 0, nil},
+	}
+	for _, tt := range tests {
+		if got := resultIDs(k, tt.query, tt.n, nil); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q, %d) = %q, want %q", tt.query, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordSearchFilter(t *testing.T) {
+	k := newTestIndex(t)
+	opts := SearchOptions{Language: "python"}
+	if got := resultIDs(k, "project hash", 5, opts.Match); !slices.Equal(got, []string{"py"}) {
+		t.Errorf("Search with language filter = %q, want [py]", got)
+	}
+}
+
+func TestKeywordDeleteAndReplace(t *testing.T) {
+	k := newTestIndex(t)
+	k.Delete("hash")
+	if got := resultIDs(k, "calculateprojecthash", 5, nil); !slices.Equal(got, []string{"caller"}) {
+		t.Errorf("after Delete = %q, want [caller]", got)
+	}
+	if _, ok := k.Get("hash"); ok {
+		t.Error("Get after Delete still finds the document")
+	}
+
+	// Adding an existing ID replaces its terms
+	k.Add("caller", "func status() {}", map[string]string{"name": "status"})
+	if got := resultIDs(k, "calculateprojecthash", 5, nil); len(got) != 0 {
+		t.Errorf("after replacing = %q, want no results", got)
+	}
+	if k.Count() != 3 {
+		t.Errorf("Count = %d, want 3", k.Count())
+	}
+}
+
+func TestKeywordIndexPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), keywordFile)
+	k, err := NewKeywordIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Add("a", "func Alpha() {}", map[string]string{"name": "Alpha"})
+	if err := k.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewKeywordIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultIDs(loaded, "alpha", 5, nil); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("reloaded Search = %q, want [a]", got)
+	}
+}
@@ -0,0 +1,74 @@
+package vectordb
+
+import (
+	"archon/internal/utils"
+	"path/filepath"
+	"strings"
+)
+
+// SearchOptions scopes a search. The zero value searches everything.
+type SearchOptions struct {
+	// PathPrefix keeps only files at or below this path.
+	PathPrefix string
+	// Language keeps only symbols of this language (e.g. "go").
+	Language string
+	// SymbolType keeps only symbols of this type (e.g. "function", "method", "class").
+	SymbolType string
+	// ExcludeTests drops test files and fixtures.
+	ExcludeTests bool
+	// MinSimilarity drops vector results below this cosine similarity. Keyword
+	// results have no comparable score and are not affected.
+	MinSimilarity float32
+}
+
+// where returns the exact-match metadata filters chromem-go can apply itself.
+func (o SearchOptions) where() map[string]string {
+	where := make(map[string]string)
+	if o.Language != "" {
+		where["language"] = o.Language
+	}
+	if o.SymbolType != "" {
+		where["type"] = o.SymbolType
+	}
+	if len(where) == 0 {
+		return nil
+	}
+	return where
+}
+
+// postFiltered reports whether some filters have to be applied to the results.
+func (o SearchOptions) postFiltered() bool {
+	return o.PathPrefix != "" || o.ExcludeTests || o.MinSimilarity > 0
+}
+
+// Match reports whether a document with metadata passes every filter except
+// MinSimilarity.
+func (o SearchOptions) Match(metadata map[string]string) bool {
+	if o.Language != "" && metadata["language"] != o.Language {
+		return false
+	}
+	if o.SymbolType != "" && metadata["type"] != o.SymbolType {
+		return false
+	}
+	file := metadata["file"]
+	if o.ExcludeTests && utils.IsTestFile(file) {
+		return false
+	}
+	if o.PathPrefix != "" && !underPath(o.PathPrefix, file) {
+		return false
+	}
+	return true
+}
+
+// underPath reports whether file is prefix itself or lies below it.
+func underPath(prefix, file string) bool {
+	prefix, err := filepath.Abs(prefix)
+	if err != nil {
+		return false
+	}
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	return file == prefix || strings.HasPrefix(file, prefix+string(filepath.Separator))
+}
@@ -1,103 +1,335 @@
-
 package vectordb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
-	"github.com/google/generative-ai-go/genai"
 	"github.com/philippgille/chromem-go"
-	"golang.org/x/time/rate"
-	"google.golang.org/api/option"
 )
 
+const (
+	DefaultPath    = "./chromem_db"
+	collectionName = "codebase"
+
+	// embedderFile records the embedder of the vectors, in a file of our own
+	// rather than chromem-go's persistence format.
+	embedderFile = "embedder.txt"
+
+	// legacyEmbedder is what every collection created before embedders were recorded used.
+	legacyEmbedder = "gemini:text-embedding-004"
+
+	// NoEmbedder is the embedder name of a keyword-only store.
+	NoEmbedder = "none"
+)
+
+// ErrEmbedderMismatch is returned when the index was built with a different embedder
+// than the one configured, since similarity scores across embedding spaces are meaningless.
+var ErrEmbedderMismatch = errors.New("embedder mismatch")
+
+// ErrNoEmbedder is returned by vector searches on a keyword-only store.
+var ErrNoEmbedder = errors.New("no embedder configured, only keyword search is available")
+
+// Embedder turns text into a vector. Name identifies the backend and model
+// (e.g. "gemini:text-embedding-004") and is recorded next to the index.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	Name() string
+}
+
+// BatchEmbedder is implemented by embedders that can vectorize many texts in
+// one request, which AddDocuments prefers to save round trips and quota.
+type BatchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
 type Store struct {
-	db          *chromem.DB
-	col         *chromem.Collection
-	genaiClient *genai.Client
-	limiter     *rate.Limiter
-	embFunc     chromem.EmbeddingFunc
+	path     string
+	db       *chromem.DB
+	col      *chromem.Collection
+	embedder Embedder
+	mismatch error
+	keywords *KeywordIndex
 }
 
-func NewStore(ctx context.Context, path string, apiKey string) (*Store, error) {
+// NewStore opens the persistent store at path. The store takes ownership of the
+// embedder and closes it on Close if it implements io.Closer. With a nil embedder
+// the store only maintains the keyword index.
+func NewStore(ctx context.Context, path string, embedder Embedder) (*Store, error) {
+	keywords, err := NewKeywordIndex(filepath.Join(path, keywordFile))
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := chromem.NewPersistentDB(path, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create persistent db: %w", err)
 	}
 
-	// Rate limiter: 1500 RPM (Requests Per Minute) -> 25 RPS
-	limiter := rate.NewLimiter(rate.Limit(25), 1)
-
-	var embFunc chromem.EmbeddingFunc
-	var genaiClient *genai.Client
-	if apiKey != "" {
-		genaiClient, err = genai.NewClient(ctx, option.WithAPIKey(apiKey))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create genai client: %w", err)
-		}
-		
-		embFunc = func(ctx context.Context, text string) ([]float32, error) {
-			// Wait for rate limiter
-			if err := limiter.Wait(ctx); err != nil {
-				return nil, err
-			}
+	s := &Store{
+		path:     path,
+		db:       db,
+		embedder: embedder,
+		keywords: keywords,
+	}
+	if embedder == nil {
+		return s, nil
+	}
 
-			model := genaiClient.EmbeddingModel("text-embedding-004")
-			res, err := model.EmbedContent(ctx, genai.Text(text))
-			if err != nil {
-				return nil, err
-			}
-			return res.Embedding.Values, nil
+	recorded, ok := readEmbedder(path)
+	if !ok {
+		// Indexes from before the embedder was recorded were all built with the old default
+		if old := db.GetCollection(collectionName, nil); old != nil && old.Count() > 0 {
+			recorded, ok = legacyEmbedder, true
 		}
 	}
+	if ok && recorded != embedder.Name() {
+		s.mismatch = fmt.Errorf("%w: index was built with %q but %q is configured, run 'archon index --force' to rebuild it",
+			ErrEmbedderMismatch, recorded, embedder.Name())
+	}
 
-	col, err := db.GetOrCreateCollection("codebase", nil, embFunc)
+	col, err := db.GetOrCreateCollection(collectionName, s.collectionMetadata(), embedder.Embed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get or create collection: %w", err)
 	}
+	s.col = col
 
-	return &Store{
-		db:          db,
-		col:         col,
-		genaiClient: genaiClient,
-		limiter:     limiter,
-		embFunc:     embFunc,
-	}, nil
+	if s.mismatch == nil {
+		if err := writeEmbedder(path, embedder.Name()); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) collectionMetadata() map[string]string {
+	return map[string]string{"embedder": s.embedder.Name()}
+}
+
+// Compatible reports whether the existing index was built with the configured embedder.
+func (s *Store) Compatible() error {
+	return s.mismatch
+}
+
+// EmbedderName returns the name of the configured embedder.
+func (s *Store) EmbedderName() string {
+	if s.embedder == nil {
+		return NoEmbedder
+	}
+	return s.embedder.Name()
+}
+
+// HasEmbedder reports whether vector search is available.
+func (s *Store) HasEmbedder() bool {
+	return s.embedder != nil
 }
 
 func (s *Store) Clear(ctx context.Context) error {
-	err := s.db.DeleteCollection("codebase")
+	err := s.db.DeleteCollection(collectionName)
 	if err != nil {
 		return fmt.Errorf("failed to delete collection: %w", err)
 	}
+	s.keywords.Reset()
+	if err := os.Remove(filepath.Join(s.path, embedderFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if s.embedder == nil {
+		return nil
+	}
 
-	col, err := s.db.GetOrCreateCollection("codebase", nil, s.embFunc)
+	col, err := s.db.GetOrCreateCollection(collectionName, s.collectionMetadata(), s.embedder.Embed)
 	if err != nil {
 		return fmt.Errorf("failed to recreate collection: %w", err)
 	}
 
 	s.col = col
-	return nil
+	s.mismatch = nil
+	return writeEmbedder(s.path, s.embedder.Name())
 }
 
 func (s *Store) AddDocument(ctx context.Context, id string, content string, metadata map[string]string) error {
+	if s.mismatch != nil {
+		return s.mismatch
+	}
+
 	doc := chromem.Document{
 		ID:       id,
 		Content:  content,
 		Metadata: metadata,
 	}
 
-	return s.col.AddDocuments(ctx, []chromem.Document{doc}, runtime.NumCPU())
+	return s.AddDocuments(ctx, []chromem.Document{doc})
+}
+
+// AddDocuments stores docs in one go. Embeddings are computed in a single batch
+// when the embedder supports it, otherwise concurrently one by one.
+func (s *Store) AddDocuments(ctx context.Context, docs []chromem.Document) error {
+	if s.mismatch != nil {
+		return s.mismatch
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	if s.embedder == nil {
+		s.addKeywords(docs)
+		return nil
+	}
+
+	if be, ok := s.embedder.(BatchEmbedder); ok {
+		texts := make([]string, len(docs))
+		for i, doc := range docs {
+			texts[i] = doc.Content
+		}
+		vectors, err := be.EmbedBatch(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(docs) {
+			return fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(docs))
+		}
+		for i := range docs {
+			docs[i].Embedding = vectors[i]
+		}
+	}
+
+	if err := s.col.AddDocuments(ctx, docs, runtime.NumCPU()); err != nil {
+		return err
+	}
+	s.addKeywords(docs)
+	return nil
 }
 
-func (s *Store) Search(ctx context.Context, query string, n int) ([]chromem.Result, error) {
-	return s.col.Query(ctx, query, n, nil, nil)
+func (s *Store) addKeywords(docs []chromem.Document) {
+	for _, doc := range docs {
+		s.keywords.Add(doc.ID, doc.Content, doc.Metadata)
+	}
+}
+
+// DeleteDocuments removes the documents with the given IDs.
+func (s *Store) DeleteDocuments(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s.keywords.Delete(ids...)
+	col := s.col
+	if col == nil {
+		// Keyword-only, but vectors of an earlier run with an embedder must not come back
+		col = s.db.GetCollection(collectionName, nil)
+	}
+	if col == nil {
+		return nil
+	}
+	return col.Delete(ctx, nil, nil, ids...)
+}
+
+// Count returns the number of indexed documents.
+func (s *Store) Count() int {
+	if s.embedder == nil {
+		return s.keywords.Count()
+	}
+	return s.col.Count()
+}
+
+// KeywordCount returns the number of documents in the keyword index.
+func (s *Store) KeywordCount() int {
+	return s.keywords.Count()
+}
+
+// Path returns the directory the store persists to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Search returns the n documents most similar to query that pass opts.
+func (s *Store) Search(ctx context.Context, query string, n int, opts SearchOptions) ([]chromem.Result, error) {
+	if s.embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	if s.mismatch != nil {
+		return nil, s.mismatch
+	}
+
+	// chromem-go only filters on exact metadata values. For the other filters rank
+	// every document (the search is exhaustive anyway) and filter afterwards.
+	limit := n
+	if opts.postFiltered() {
+		limit = s.col.Count()
+	}
+	// chromem-go refuses to return more results than documents it holds
+	limit = min(limit, s.col.Count())
+	if n <= 0 || limit == 0 {
+		return nil, nil
+	}
+
+	results, err := s.col.Query(ctx, query, limit, opts.where(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.postFiltered() {
+		return results, nil
+	}
+
+	var filtered []chromem.Result
+	for _, res := range results {
+		if res.Similarity < opts.MinSimilarity {
+			// Results are sorted by similarity
+			break
+		}
+		if opts.Match(res.Metadata) {
+			filtered = append(filtered, res)
+			if len(filtered) == n {
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// Document returns the indexed document with the given ID.
+func (s *Store) Document(id string) (chromem.Document, bool) {
+	return s.keywords.Get(id)
+}
+
+// KeywordSearch ranks documents that pass opts by BM25 over symbol names and code.
+func (s *Store) KeywordSearch(query string, n int, opts SearchOptions) []chromem.Result {
+	return s.keywords.Search(query, n, opts.Match)
+}
+
+// Flush persists the keyword index. The vector collection is persisted on every write.
+func (s *Store) Flush() error {
+	return s.keywords.Save()
 }
 
 func (s *Store) Close() error {
-	if s.genaiClient != nil {
-		s.genaiClient.Close()
+	err := s.Flush()
+	if closer, ok := s.embedder.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// readEmbedder returns the embedder recorded for the vectors at path, if any.
+func readEmbedder(path string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(path, embedderFile))
+	if err != nil {
+		return "", false
+	}
+	name := strings.TrimSpace(string(data))
+	return name, name != ""
+}
+
+// writeEmbedder records the embedder the vectors at path are built with.
+func writeEmbedder(path, name string) error {
+	if err := os.WriteFile(filepath.Join(path, embedderFile), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record the embedder: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,82 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type namedEmbedder struct {
+	*HashEmbedder
+	name string
+}
+
+func (e namedEmbedder) Name() string { return e.name }
+
+func openStore(t *testing.T, path, embedder string) *Store {
+	t.Helper()
+	s, err := NewStore(context.Background(), path, namedEmbedder{NewHashEmbedder(0), embedder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreRecordsEmbedder(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+
+	s := openStore(t, path, "hash:a")
+	if err := s.AddDocument(ctx, "x", "func X() {}", map[string]string{"file": "x.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := readEmbedder(path); !ok || name != "hash:a" {
+		t.Fatalf("recorded embedder = %q, %v", name, ok)
+	}
+
+	if err := openStore(t, path, "hash:a").Compatible(); err != nil {
+		t.Errorf("same embedder: %v", err)
+	}
+	other := openStore(t, path, "hash:b")
+	if err := other.Compatible(); !errors.Is(err, ErrEmbedderMismatch) {
+		t.Errorf("other embedder: Compatible = %v, want a mismatch", err)
+	}
+	// A mismatch must not overwrite what the vectors were built with
+	if name, _ := readEmbedder(path); name != "hash:a" {
+		t.Errorf("recorded embedder = %q after a mismatch", name)
+	}
+
+	if err := other.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Compatible(); err != nil {
+		t.Errorf("after Clear: %v", err)
+	}
+	if name, _ := readEmbedder(path); name != "hash:b" {
+		t.Errorf("recorded embedder = %q after Clear, want hash:b", name)
+	}
+}
+
+func TestStoreLegacyIndex(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+
+	s := openStore(t, path, legacyEmbedder)
+	if err := s.AddDocument(ctx, "x", "func X() {}", map[string]string{"file": "x.go"}); err != nil {
+		t.Fatal(err)
+	}
+	// Indexes from before the embedder was recorded have no file
+	if err := os.Remove(filepath.Join(path, embedderFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := openStore(t, path, "hash:a").Compatible(); !errors.Is(err, ErrEmbedderMismatch) {
+		t.Errorf("Compatible = %v, want a mismatch with the legacy embedder", err)
+	}
+	if err := openStore(t, path, legacyEmbedder).Compatible(); err != nil {
+		t.Errorf("legacy embedder: %v", err)
+	}
+}
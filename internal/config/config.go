@@ -7,11 +7,62 @@ import (
 	"github.com/spf13/viper"
 )
 
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+
+	// EmbedderHash selects the offline hashed n-gram embedder.
+	EmbedderHash = "hash"
+	// EmbedderNone disables embeddings, leaving keyword search only.
+	EmbedderNone = "none"
+)
+
 type Config struct {
-	GeminiKey   string `mapstructure:"gemini_key"`
-	ModelID     string `mapstructure:"model_id"`
-	ProjectHash string `mapstructure:"project_hash"`
-	CacheName   string `mapstructure:"cache_name"`
+	Provider       string `mapstructure:"provider"`
+	GeminiKey      string `mapstructure:"gemini_key"`
+	OpenAIKey      string `mapstructure:"openai_key"`
+	OpenAIBaseURL  string `mapstructure:"openai_base_url"`
+	ModelID        string `mapstructure:"model_id"`
+	Embedder       string `mapstructure:"embedder"`
+	EmbeddingModel string `mapstructure:"embedding_model"`
+	// WatchDebounceMs is how long a file must stay unchanged before the watcher re-indexes it.
+	WatchDebounceMs int `mapstructure:"watch_debounce_ms"`
+	// Ignore holds extra gitignore-style patterns excluded from indexing and hashing.
+	Ignore      []string `mapstructure:"ignore"`
+	ProjectHash string   `mapstructure:"project_hash"`
+	CacheName   string   `mapstructure:"cache_name"`
+	// SearchBlend weighs vector against keyword search: 0 is keyword only, 1 vector only.
+	SearchBlend float64 `mapstructure:"search_blend"`
+	// ContextBudget overrides the token budget of the retrieved context per command (e.g. review: 40000).
+	ContextBudget map[string]int `mapstructure:"context_budget"`
+	// ChatWindow is how many tokens of chat history are sent before older turns are summarized.
+	ChatWindow int `mapstructure:"chat_window"`
+	// BatchConcurrency, BatchRPM and BatchTPM limit bulk generation (e.g. `archon test --all`).
+	// A limit of 0 means unlimited.
+	BatchConcurrency int `mapstructure:"batch_concurrency"`
+	BatchRPM         int `mapstructure:"batch_rpm"`
+	BatchTPM         int `mapstructure:"batch_tpm"`
+	// TestCommand runs the tests for `archon test --verify`, e.g. "go test {pkg}/...".
+	// Empty means the default command of the file's language.
+	TestCommand string `mapstructure:"test_command"`
+	// FormatCommand formats each file changed by an edit plan, e.g. "goimports -w {file}".
+	// Empty means gofmt for Go files.
+	FormatCommand string `mapstructure:"format_command"`
+	// BuildCommand checks the project after an edit plan is applied. Empty means
+	// "go build ./..." in Go modules.
+	BuildCommand string `mapstructure:"build_command"`
+	// DiffChunkTokens is the largest part of a diff reviewed in one request; larger
+	// diffs are split between files.
+	DiffChunkTokens int `mapstructure:"diff_chunk_tokens"`
+	// CommitStyle is the format of generated commit messages: conventional, gitmoji or custom.
+	CommitStyle string `mapstructure:"commit_style"`
+	// CommitTemplate is the subject line of the custom style, e.g. "{scope}: {subject}".
+	CommitTemplate string `mapstructure:"commit_template"`
+	// CommitMaxSubject is the longest subject line allowed.
+	CommitMaxSubject int `mapstructure:"commit_max_subject"`
+	// CommitBody and CommitFooter are "optional", "required" or "none".
+	CommitBody   string `mapstructure:"commit_body"`
+	CommitFooter string `mapstructure:"commit_footer"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -20,7 +71,7 @@ func LoadConfig() (*Config, error) {
 	// Coba cari file .archon.yaml di folder saat ini atau home
 	home, _ := os.UserHomeDir()
 	searchPaths := []string{".", home}
-	
+
 	for _, p := range searchPaths {
 		if p == "" {
 			continue
@@ -42,8 +93,20 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	viper.SetDefault("search_blend", 0.5)
+	viper.SetDefault("chat_window", 32000)
+	viper.SetDefault("batch_concurrency", 4)
+	viper.SetDefault("batch_rpm", 60)
+	viper.SetDefault("batch_tpm", 1000000)
+	viper.SetDefault("diff_chunk_tokens", 30000)
+	viper.SetDefault("commit_style", "conventional")
+	viper.SetDefault("commit_max_subject", 72)
+
 	viper.SetEnvPrefix("ARCHON")
 	viper.AutomaticEnv()
+	for _, key := range []string{"provider", "gemini_key", "openai_key", "openai_base_url", "model_id", "embedder", "embedding_model", "watch_debounce_ms", "search_blend", "chat_window", "batch_concurrency", "batch_rpm", "batch_tpm", "test_command", "format_command", "build_command", "diff_chunk_tokens", "commit_style", "commit_template", "commit_max_subject", "commit_body", "commit_footer"} {
+		viper.BindEnv(key)
+	}
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -56,8 +119,24 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderGemini
+	}
+
+	if cfg.Embedder == "" {
+		cfg.Embedder = cfg.Provider
+	}
+
+	if cfg.OpenAIBaseURL == "" {
+		cfg.OpenAIBaseURL = "https://api.openai.com/v1"
+	}
+
 	if cfg.ModelID == "" {
-		cfg.ModelID = "gemini-3-pro-preview"
+		if cfg.Provider == ProviderOpenAI {
+			cfg.ModelID = "gpt-4o-mini"
+		} else {
+			cfg.ModelID = "gemini-3-pro-preview"
+		}
 	}
 
 	return &cfg, nil
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultBackupDir is where the originals of applied edits are kept, relative to the project root.
+const DefaultBackupDir = ".archon/backups"
+
+// maxBackups is the number of edits kept in the journal; older ones are dropped.
+const maxBackups = 50
+
+// FileChange is an edit of one file: its contents before (if it existed) and after.
+type FileChange struct {
+	Path    string
+	Existed bool
+	Old     []byte
+	New     []byte
+}
+
+// BackupFile is one file of a journal entry. The original contents are stored
+// next to the entry as Backup; Hash is the SHA-256 of what was written, to tell
+// whether the file was changed again afterwards.
+type BackupFile struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"`
+	Backup  string `json:"backup,omitempty"`
+	Hash    string `json:"hash"`
+}
+
+// BackupEntry records one applied edit, which may span several files.
+type BackupEntry struct {
+	ID      string       `json:"id"`
+	Command string       `json:"command"`
+	Time    time.Time    `json:"time"`
+	Files   []BackupFile `json:"files"`
+}
+
+// BackupJournal keeps one directory per applied edit, holding entry.json and
+// the original files.
+type BackupJournal struct {
+	dir string
+}
+
+func NewBackupJournal(dir string) *BackupJournal {
+	return &BackupJournal{dir: dir}
+}
+
+// HashContent returns the hex SHA-256 of content.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record stores the originals of changes before they are written, so the edit
+// can be undone even if writing is interrupted.
+func (j *BackupJournal) Record(command string, changes []FileChange) (*BackupEntry, error) {
+	now := time.Now()
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	entry := &BackupEntry{
+		ID:      now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Command: command,
+		Time:    now,
+	}
+
+	dir := filepath.Join(j.dir, entry.ID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+	for i, change := range changes {
+		file := BackupFile{Path: change.Path, Existed: change.Existed, Hash: HashContent(change.New)}
+		if change.Existed {
+			file.Backup = strconv.Itoa(i) + ".orig"
+			if err := os.WriteFile(filepath.Join(dir, file.Backup), change.Old, 0o600); err != nil {
+				os.RemoveAll(dir)
+				return nil, fmt.Errorf("failed to create backup: %w", err)
+			}
+		}
+		entry.Files = append(entry.Files, file)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "entry.json"), data, 0o600); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if entries, err := j.List(); err == nil {
+		for _, old := range entries[min(len(entries), maxBackups):] {
+			os.RemoveAll(filepath.Join(j.dir, old.ID))
+		}
+	}
+	return entry, nil
+}
+
+// List returns the recorded edits, most recent first. Unreadable entries are skipped.
+func (j *BackupJournal) List() ([]*BackupEntry, error) {
+	dirs, err := os.ReadDir(j.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []*BackupEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, d.Name(), "entry.json"))
+		if err != nil {
+			continue
+		}
+		var entry BackupEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.ID != d.Name() {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].Time.Equal(entries[b].Time) {
+			return entries[a].ID > entries[b].ID
+		}
+		return entries[a].Time.After(entries[b].Time)
+	})
+	return entries, nil
+}
+
+// Refresh records the current contents of entry's files as what the edit
+// wrote, after they were reformatted for example.
+func (j *BackupJournal) Refresh(entry *BackupEntry) error {
+	for i, file := range entry.Files {
+		content, err := os.ReadFile(file.Path)
+		if err != nil {
+			return err
+		}
+		entry.Files[i].Hash = HashContent(content)
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(j.dir, entry.ID, "entry.json"), data, 0o600)
+}
+
+// Modified returns the files of entry that no longer hold what the edit wrote.
+func (j *BackupJournal) Modified(entry *BackupEntry) []string {
+	var modified []string
+	for _, file := range entry.Files {
+		content, err := os.ReadFile(file.Path)
+		if err != nil || HashContent(content) != file.Hash {
+			modified = append(modified, file.Path)
+		}
+	}
+	return modified
+}
+
+// Restore puts back the originals of entry, removing files the edit created,
+// and drops the entry from the journal.
+func (j *BackupJournal) Restore(entry *BackupEntry) error {
+	dir := filepath.Join(j.dir, entry.ID)
+	for _, file := range entry.Files {
+		if !file.Existed {
+			// A file that cannot be stat'ed was never written, e.g. by an edit rolled back
+			if _, err := os.Lstat(file.Path); err != nil {
+				continue
+			}
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, file.Backup))
+		if err != nil {
+			return fmt.Errorf("backup of %s is missing: %w", file.Path, err)
+		}
+		perm := os.FileMode(0o644)
+		if info, err := os.Stat(file.Path); err == nil {
+			perm = info.Mode().Perm()
+		} else if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file.Path, content, perm); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+	}
+	return os.RemoveAll(dir)
+}
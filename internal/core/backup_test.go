@@ -0,0 +1,145 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBackupRecordRestore(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "a.go")
+	created := filepath.Join(root, "sub", "b.go")
+	writeFile(t, existing, "old a\n")
+
+	journal := NewBackupJournal(filepath.Join(root, "backups"))
+	entry, err := journal.Record("refactor", []FileChange{
+		{Path: existing, Existed: true, Old: []byte("old a\n"), New: []byte("new a\n")},
+		{Path: created, New: []byte("new b\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, existing, "new a\n")
+	writeFile(t, created, "new b\n")
+
+	entries, err := journal.List()
+	if err != nil || len(entries) != 1 || entries[0].ID != entry.ID || entries[0].Command != "refactor" {
+		t.Fatalf("List = %+v, %v, want the recorded entry", entries, err)
+	}
+	if modified := journal.Modified(entry); len(modified) != 0 {
+		t.Errorf("Modified = %q right after the edit, want none", modified)
+	}
+
+	if err := journal.Restore(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, existing); got != "old a\n" {
+		t.Errorf("restored a.go = %q, want the original", got)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created b.go still exists after Restore: %v", err)
+	}
+	if entries, _ := journal.List(); len(entries) != 0 {
+		t.Errorf("List after Restore = %+v, want the entry dropped", entries)
+	}
+}
+
+func TestBackupModified(t *testing.T) {
+	root := t.TempDir()
+	a, b, c := filepath.Join(root, "a.go"), filepath.Join(root, "b.go"), filepath.Join(root, "c.go")
+	journal := NewBackupJournal(filepath.Join(root, "backups"))
+	entry, err := journal.Record("doc", []FileChange{
+		{Path: a, New: []byte("a\n")},
+		{Path: b, New: []byte("b\n")},
+		{Path: c, New: []byte("c\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b changed again\n")
+	// c.go was never written or was deleted since
+
+	if got := journal.Modified(entry); !slices.Equal(got, []string{b, c}) {
+		t.Errorf("Modified = %q, want b.go and c.go", got)
+	}
+
+	// Refresh accepts the current contents, e.g. after formatting
+	writeFile(t, c, "c formatted\n")
+	if err := journal.Refresh(entry); err != nil {
+		t.Fatal(err)
+	}
+	if got := journal.Modified(entry); len(got) != 0 {
+		t.Errorf("Modified after Refresh = %q, want none", got)
+	}
+	entries, _ := journal.List()
+	if len(entries) != 1 || entries[0].Files[2].Hash != HashContent([]byte("c formatted\n")) {
+		t.Error("Refresh did not persist the new hashes")
+	}
+}
+
+func TestBackupPrunesOldEntries(t *testing.T) {
+	root := t.TempDir()
+	journal := NewBackupJournal(filepath.Join(root, "backups"))
+	var first *BackupEntry
+	for i := 0; i < maxBackups+2; i++ {
+		entry, err := journal.Record("refactor", []FileChange{{Path: filepath.Join(root, "a.go"), Existed: true, Old: []byte("x"), New: []byte("y")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = entry
+		}
+	}
+
+	entries, err := journal.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxBackups {
+		t.Errorf("kept %d entries, want %d", len(entries), maxBackups)
+	}
+	if _, err := os.Stat(filepath.Join(root, "backups", first.ID)); !os.IsNotExist(err) {
+		t.Error("the oldest entry was not pruned")
+	}
+}
+
+func TestBackupRestoreMissingBackup(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.go")
+	journal := NewBackupJournal(filepath.Join(root, "backups"))
+	entry, err := journal.Record("refactor", []FileChange{{Path: path, Existed: true, Old: []byte("old"), New: []byte("new")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(root, "backups", entry.ID, entry.Files[0].Backup))
+	writeFile(t, path, "new")
+
+	if err := journal.Restore(entry); err == nil {
+		t.Fatal("Restore succeeded without the backup")
+	}
+	if got := readFile(t, path); got != "new" {
+		t.Errorf("a.go = %q, want it left alone", got)
+	}
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/utils"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxChangedSymbols caps the symbols of a diff used as retrieval queries.
+	maxChangedSymbols = 40
+	// maxCallers is how many references to a changed symbol are retrieved.
+	maxCallers = 3
+)
+
+// ChangedSymbol is a symbol enclosing changed lines of a diff.
+type ChangedSymbol struct {
+	File      string
+	Name      string
+	Parent    string
+	Type      string
+	Language  string
+	StartLine int
+	EndLine   int
+	// Changes are the changed lines within the symbol, in the new version.
+	Changes []utils.LineRange
+}
+
+// QualifiedName returns Parent.Name for nested symbols and Name otherwise.
+func (s ChangedSymbol) QualifiedName() string {
+	if s.Parent == "" {
+		return s.Name
+	}
+	return s.Parent + "." + s.Name
+}
+
+// ChangedSymbols maps the hunks of a diff to the innermost symbols enclosing
+// their changed lines, reading the new version of each file with read. Deleted,
+// binary and unreadable files are skipped, as are lines outside any symbol.
+func (o *Orchestrator) ChangedSymbols(ctx context.Context, files []utils.DiffFile, read func(path string) ([]byte, error)) []ChangedSymbol {
+	var changed []ChangedSymbol
+	for _, file := range files {
+		if file.IsDeleted || file.IsBinary || len(file.Hunks) == 0 {
+			continue
+		}
+		content, err := read(file.NewPath)
+		if err != nil {
+			continue
+		}
+		symbols, err := o.parser.Parse(ctx, file.NewPath, content)
+		if err != nil {
+			continue
+		}
+
+		index := make(map[int]int)
+		for _, hunk := range file.Hunks {
+			for _, r := range hunk.Changes() {
+				for line := r.Start; line <= r.End; line++ {
+					sym, ok := innermostSymbol(symbols, line)
+					if !ok {
+						continue
+					}
+					i, seen := index[sym]
+					if !seen {
+						s := symbols[sym]
+						i = len(changed)
+						index[sym] = i
+						changed = append(changed, ChangedSymbol{
+							File:      file.NewPath,
+							Name:      s.Name,
+							Parent:    s.Parent,
+							Type:      s.Type,
+							Language:  string(s.Language),
+							StartLine: s.StartLine,
+							EndLine:   s.EndLine,
+						})
+					}
+					c := &changed[i]
+					if n := len(c.Changes); n > 0 && c.Changes[n-1].End >= line-1 {
+						c.Changes[n-1].End = max(c.Changes[n-1].End, line)
+					} else {
+						c.Changes = append(c.Changes, utils.LineRange{Start: line, End: line})
+					}
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// innermostSymbol returns the index of the smallest symbol containing line.
+// Whole-file symbols do not count.
+func innermostSymbol(symbols []parser.Symbol, line int) (int, bool) {
+	best := -1
+	for i, s := range symbols {
+		if s.Type == "file" || s.StartLine > line || s.EndLine < line {
+			continue
+		}
+		if best < 0 || s.EndLine-s.StartLine < symbols[best].EndLine-symbols[best].StartLine {
+			best = i
+		}
+	}
+	return best, best >= 0
+}
+
+// ChangeContext assembles a prompt context for a diff out of the changed
+// symbols: first their indexed definitions, then code that refers to them. It
+// falls back to a search for the changed files when no symbol is known.
+func (o *Orchestrator) ChangeContext(ctx context.Context, files []utils.DiffFile, symbols []ChangedSymbol, budget int, opts SearchOptions) (*ContextResult, error) {
+	if budget <= 0 {
+		budget = defaultContextBudget
+	}
+	if len(symbols) > maxChangedSymbols {
+		// The most changed symbols first
+		symbols = append([]ChangedSymbol(nil), symbols...)
+		sort.SliceStable(symbols, func(i, j int) bool { return changedLines(symbols[i]) > changedLines(symbols[j]) })
+		symbols = symbols[:maxChangedSymbols]
+	}
+
+	var hits []Snippet
+	seen := make(map[string]bool)
+	add := func(snip Snippet) {
+		if !seen[snip.ID] {
+			seen[snip.ID] = true
+			hits = append(hits, snip)
+		}
+	}
+
+	for _, sym := range symbols {
+		if def, ok := o.definition(sym); ok {
+			add(def)
+		}
+	}
+	for _, sym := range symbols {
+		for _, snip := range o.references(sym, opts) {
+			add(snip)
+		}
+	}
+
+	if len(hits) == 0 {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path())
+		}
+		if len(paths) == 0 {
+			return o.assembleContext(nil, budget), nil
+		}
+		return o.SearchContext(ctx, "Changes in "+strings.Join(paths, ", "), budget, opts)
+	}
+	return o.assembleContext(hits, budget), nil
+}
+
+func changedLines(s ChangedSymbol) int {
+	n := 0
+	for _, r := range s.Changes {
+		n += r.End - r.Start + 1
+	}
+	return n
+}
+
+// definition returns the indexed document of a symbol, as buildDocuments names it.
+func (o *Orchestrator) definition(sym ChangedSymbol) (Snippet, bool) {
+	id := sym.File + ":" + sym.QualifiedName()
+	if doc, ok := o.store.Document(id); ok {
+		return snippetFromDocument(doc.ID, doc.Content, doc.Metadata), true
+	}
+	// A symbol split into chunks has no document of its own
+	doc, ok := o.store.Document(id + "[1]")
+	if !ok {
+		return Snippet{}, false
+	}
+	return snippetFromDocument(doc.ID, doc.Content, doc.Metadata), true
+}
+
+// references finds indexed code that mentions a symbol by name, outside of its
+// own definition: callers of functions and methods, users of types.
+func (o *Orchestrator) references(sym ChangedSymbol, opts SearchOptions) []Snippet {
+	if commonName(sym.Name) {
+		return nil
+	}
+	pattern := `\b` + regexp.QuoteMeta(sym.Name) + `\b`
+	if sym.Type == "method" {
+		pattern = `\.` + regexp.QuoteMeta(sym.Name) + `\b`
+	}
+	re := regexp.MustCompile(pattern)
+
+	var refs []Snippet
+	for _, res := range o.store.KeywordSearch(sym.Name, maxCallers*rrfDepth, opts) {
+		snip := snippetFromResult(res)
+		if snip.File == sym.File && snip.StartLine <= sym.EndLine && sym.StartLine <= snip.EndLine {
+			continue
+		}
+		if !re.MatchString(snip.Content) {
+			continue
+		}
+		refs = append(refs, snip)
+		if len(refs) == maxCallers {
+			break
+		}
+	}
+	return refs
+}
+
+// FormatChangedSymbols lists changed symbols for prompts and logs, e.g.
+// "Orchestrator.Search (internal/core/orchestrator.go:120)".
+func FormatChangedSymbols(symbols []ChangedSymbol) string {
+	var names []string
+	for _, s := range symbols {
+		names = append(names, fmt.Sprintf("%s (%s:%d)", s.QualifiedName(), s.File, s.StartLine))
+	}
+	return strings.Join(names, ", ")
+}
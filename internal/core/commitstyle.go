@@ -0,0 +1,369 @@
+package core
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/utils"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Commit message styles.
+const (
+	CommitConventional = "conventional"
+	CommitGitmoji      = "gitmoji"
+	CommitCustom       = "custom"
+)
+
+// Rules for the body and footer of a commit message.
+const (
+	PartOptional = "optional"
+	PartRequired = "required"
+	PartNone     = "none"
+)
+
+// DefaultMaxSubject is the longest subject line accepted by default.
+const DefaultMaxSubject = 72
+
+var conventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+var (
+	conventionalRe = regexp.MustCompile(`^(` + strings.Join(conventionalTypes, "|") + `)(\([\w./-]+\))?!?: \S`)
+	// A :shortcode: or a non-ASCII emoji, then an optional "(scope)" or "scope:"
+	gitmojiRe = regexp.MustCompile(`^(:[a-z0-9_+-]+:|[^\x00-\x7F]+)\s+(\([\w./-]+\):?\s+|[\w./-]+:\s+)?\S`)
+	footerRe  = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*|BREAKING CHANGE)(: | #)\S`)
+)
+
+// templateFields are the placeholders of a custom subject template and what they match.
+var templateFields = map[string]string{
+	"{type}":    `[a-z]+`,
+	"{scope}":   `[\w./-]+`,
+	"{subject}": `\S.*`,
+}
+
+// CommitStyle is the format commit messages must follow.
+type CommitStyle struct {
+	Name string
+	// Template is the subject line of the custom style, e.g. "{scope}: {subject}".
+	Template   string
+	MaxSubject int
+	Body       string
+	Footer     string
+
+	subjectRe *regexp.Regexp
+}
+
+// NewCommitStyle checks a style as configured. Empty values get the defaults:
+// conventional, 72 characters and an optional body and footer.
+func NewCommitStyle(name, template string, maxSubject int, body, footer string) (*CommitStyle, error) {
+	s := &CommitStyle{Name: strings.ToLower(name), Template: template, MaxSubject: maxSubject, Body: strings.ToLower(body), Footer: strings.ToLower(footer)}
+	if s.Name == "" {
+		s.Name = CommitConventional
+	}
+	if s.MaxSubject <= 0 {
+		s.MaxSubject = DefaultMaxSubject
+	}
+	for _, part := range []*string{&s.Body, &s.Footer} {
+		switch *part {
+		case "":
+			*part = PartOptional
+		case PartOptional, PartRequired, PartNone:
+		default:
+			return nil, fmt.Errorf("commit_body and commit_footer must be optional, required or none, not %q", *part)
+		}
+	}
+
+	switch s.Name {
+	case CommitConventional:
+		s.subjectRe = conventionalRe
+	case CommitGitmoji:
+		s.subjectRe = gitmojiRe
+	case CommitCustom:
+		if !strings.Contains(template, "{subject}") {
+			return nil, fmt.Errorf("commit_template must contain {subject}, e.g. \"{scope}: {subject}\"")
+		}
+		pattern := regexp.QuoteMeta(template)
+		for field, re := range templateFields {
+			pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta(field), re)
+		}
+		s.subjectRe = regexp.MustCompile("^" + pattern + "$")
+	default:
+		return nil, fmt.Errorf("commit style must be conventional, gitmoji or custom, not %q", name)
+	}
+	return s, nil
+}
+
+// Instructions describes the style for the prompt. scopes are the scopes
+// inferred from the changed paths, most changed first.
+func (s *CommitStyle) Instructions(scopes []string) string {
+	var b strings.Builder
+	switch s.Name {
+	case CommitConventional:
+		fmt.Fprintf(&b, "Use the Conventional Commits format for the subject line: type(scope): description, where type is one of %s. Add \"!\" after the scope for breaking changes.\n", strings.Join(conventionalTypes, ", "))
+	case CommitGitmoji:
+		b.WriteString("Use the gitmoji format for the subject line: an emoji for the intention, then the optional scope and the description, e.g. \"✨ (parser): support generics\". Emojis: ✨ new feature, 🐛 bug fix, 📝 docs, ♻️ refactor, ⚡️ performance, ✅ tests, 🔧 configuration, 🔥 removal, 🎨 structure or format, ⬆️ dependencies, 🚑️ critical hotfix.\n")
+	case CommitCustom:
+		fmt.Fprintf(&b, "The subject line must follow this template exactly: %s\n", s.Template)
+		if strings.Contains(s.Template, "{type}") {
+			fmt.Fprintf(&b, "{type} is a lowercase word such as %s.\n", strings.Join(conventionalTypes[:5], ", "))
+		}
+	}
+	if s.Name != CommitCustom || strings.Contains(s.Template, "{scope}") {
+		switch len(scopes) {
+		case 0:
+		case 1:
+			fmt.Fprintf(&b, "The scope is %q, the package that changed.\n", scopes[0])
+		default:
+			fmt.Fprintf(&b, "Scopes of the changed packages, most changed first: %s. Use the main one.\n", strings.Join(scopes, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "The subject line is at most %d characters, in the imperative mood, without a final period.\n", s.MaxSubject)
+
+	switch s.Body {
+	case PartRequired:
+		b.WriteString("After a blank line, a body explaining WHAT changed and WHY is required.\n")
+	case PartNone:
+		b.WriteString("Do not write a body.\n")
+	default:
+		b.WriteString("After a blank line, add a short body explaining WHAT changed and WHY if the subject does not say it all.\n")
+	}
+	switch s.Footer {
+	case PartRequired:
+		b.WriteString("End with a footer after a blank line, made of \"Token: value\" lines (e.g. \"Refs: #123\", \"BREAKING CHANGE: ...\").\n")
+	case PartNone:
+		b.WriteString("Do not write a footer.\n")
+	default:
+		b.WriteString("Only add a footer (\"BREAKING CHANGE: ...\", \"Refs: ...\") when it applies.\n")
+	}
+	return b.String()
+}
+
+// Validate lists how msg breaks the style; nil means it follows it.
+func (s *CommitStyle) Validate(msg string) []string {
+	var problems []string
+	lines := strings.Split(msg, "\n")
+	subject := lines[0]
+	if strings.TrimSpace(subject) == "" {
+		return []string{"the subject line is empty"}
+	}
+	if !s.subjectRe.MatchString(subject) {
+		switch s.Name {
+		case CommitCustom:
+			problems = append(problems, fmt.Sprintf("the subject line does not follow the template %q", s.Template))
+		default:
+			problems = append(problems, fmt.Sprintf("the subject line is not in the %s format", s.Name))
+		}
+	}
+	if n := utf8.RuneCountInString(subject); n > s.MaxSubject {
+		problems = append(problems, fmt.Sprintf("the subject line has %d characters, at most %d are allowed", n, s.MaxSubject))
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "the subject line must be followed by a blank line")
+	}
+
+	body, footer := splitMessage(lines[1:])
+	switch {
+	case s.Body == PartRequired && body == "":
+		problems = append(problems, "a body is required")
+	case s.Body == PartNone && body != "":
+		problems = append(problems, "a body is not allowed")
+	}
+	switch {
+	case s.Footer == PartRequired && footer == "":
+		problems = append(problems, "a footer of \"Token: value\" lines is required")
+	case s.Footer == PartNone && footer != "":
+		problems = append(problems, "a footer is not allowed")
+	}
+	return problems
+}
+
+// splitMessage splits what follows the subject line into the body and the
+// footer, which is the last paragraph if all its lines are "Token: value".
+func splitMessage(lines []string) (body, footer string) {
+	paragraphs := strings.Split(strings.TrimSpace(strings.Join(lines, "\n")), "\n\n")
+	if paragraphs[0] == "" {
+		return "", ""
+	}
+	last := paragraphs[len(paragraphs)-1]
+	isFooter := true
+	for _, line := range strings.Split(last, "\n") {
+		if !footerRe.MatchString(line) {
+			isFooter = false
+			break
+		}
+	}
+	if isFooter {
+		footer = last
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	return strings.TrimSpace(strings.Join(paragraphs, "\n\n")), footer
+}
+
+// CleanCommitMessage strips what models wrap a commit message in: code fences,
+// quotes and a label line.
+func CleanCommitMessage(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+			text = text[nl+1:]
+		}
+		if end := strings.LastIndex(text, "```"); end >= 0 {
+			text = text[:end]
+		}
+		text = strings.TrimSpace(text)
+	}
+	// A first line like "Commit message:" or "Here is the message:" is never the subject
+	if label, rest, ok := strings.Cut(text, "\n"); ok && strings.HasSuffix(strings.TrimSpace(label), ":") && strings.TrimSpace(rest) != "" {
+		text = strings.TrimSpace(rest)
+	}
+	if len(text) > 1 && (text[0] == '"' && text[len(text)-1] == '"' || text[0] == '\'' && text[len(text)-1] == '\'') {
+		text = text[1 : len(text)-1]
+	}
+
+	// No trailing spaces, at most one blank line in a row
+	lines := strings.Split(text, "\n")
+	var out []string
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" && len(out) > 0 && out[len(out)-1] == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// packageOf returns the directory of a changed file, the package it belongs to.
+func packageOf(file string) string {
+	return path.Dir(file)
+}
+
+// InferScopes returns the commit scopes of the changed files: the name of the
+// directory (package) of each, most changed lines first. Files at the root of
+// the project have no scope.
+func InferScopes(files []utils.DiffFile) []string {
+	changed := make(map[string]int)
+	var scopes []string
+	for _, f := range files {
+		dir := packageOf(f.Path())
+		if dir == "." {
+			continue
+		}
+		scope := path.Base(dir)
+		if _, ok := changed[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		for _, h := range f.Hunks {
+			for _, line := range h.Lines {
+				if line.Kind != utils.LineContext {
+					changed[scope]++
+				}
+			}
+		}
+	}
+	sort.SliceStable(scopes, func(i, j int) bool { return changed[scopes[i]] > changed[scopes[j]] })
+	return scopes
+}
+
+// SplitGroups groups the changed source files of a diff by package, joining
+// packages when one is inside the other or when the changed lines of one
+// mention a changed symbol of the other. More than one group means the diff
+// spans unrelated packages and could be split into several commits. Files
+// that are not code, like docs, belong to no group.
+func SplitGroups(files []utils.DiffFile, symbols []ChangedSymbol) [][]string {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(p string) string {
+		if parent[p] == p {
+			return p
+		}
+		parent[p] = find(parent[p])
+		return parent[p]
+	}
+	union := func(a, b string) { parent[find(a)] = find(b) }
+
+	var pkgs []string
+	for _, f := range files {
+		if parser.DetectLanguage(f.Path()) == parser.Unknown {
+			continue
+		}
+		pkg := packageOf(f.Path())
+		if _, ok := parent[pkg]; !ok {
+			parent[pkg] = pkg
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	if len(pkgs) < 2 {
+		return nil
+	}
+
+	for _, a := range pkgs {
+		for _, b := range pkgs {
+			if a != "." && strings.HasPrefix(b, a+"/") {
+				union(a, b)
+			}
+		}
+	}
+
+	// A package that uses a symbol changed in another one belongs with it
+	for _, sym := range symbols {
+		symPkg := packageOf(sym.File)
+		if _, ok := parent[symPkg]; !ok || commonName(sym.Name) {
+			continue
+		}
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(sym.Name) + `\b`)
+		for _, f := range files {
+			pkg := packageOf(f.Path())
+			if _, ok := parent[pkg]; !ok || find(pkg) == find(symPkg) {
+				continue
+			}
+			if mentions(f, re) {
+				union(pkg, symPkg)
+			}
+		}
+	}
+
+	byRoot := make(map[string][]string)
+	var roots []string
+	for _, f := range files {
+		pkg := packageOf(f.Path())
+		if _, ok := parent[pkg]; !ok || parser.DetectLanguage(f.Path()) == parser.Unknown {
+			continue
+		}
+		root := find(pkg)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], f.Path())
+	}
+	if len(roots) < 2 {
+		return nil
+	}
+	groups := make([][]string, 0, len(roots))
+	for _, root := range roots {
+		groups = append(groups, byRoot[root])
+	}
+	return groups
+}
+
+// mentions reports whether a changed line of f matches re.
+func mentions(f utils.DiffFile, re *regexp.Regexp) bool {
+	for _, h := range f.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind != utils.LineContext && re.MatchString(line.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commonName reports whether a symbol name is too short or common to tell
+// whether code refers to that symbol.
+func commonName(name string) bool {
+	return len(name) < 3 || name == "init" || name == "main"
+}
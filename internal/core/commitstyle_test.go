@@ -0,0 +1,199 @@
+package core
+
+import (
+	"archon/internal/utils"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewCommitStyle(t *testing.T) {
+	s, err := NewCommitStyle("", "", 0, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != CommitConventional || s.MaxSubject != DefaultMaxSubject || s.Body != PartOptional || s.Footer != PartOptional {
+		t.Errorf("defaults = %+v", s)
+	}
+
+	tests := []struct {
+		name, template, body, footer string
+		err                          string
+	}{
+		{"GitMoji", "", "Required", "none", ""},
+		{"custom", "[{scope}] {subject}", "", "", ""},
+		{"custom", "{scope}: text", "", "", "must contain {subject}"},
+		{"angular", "", "", "", "must be conventional, gitmoji or custom"},
+		{"conventional", "", "always", "", "must be optional, required or none"},
+	}
+	for _, tt := range tests {
+		_, err := NewCommitStyle(tt.name, tt.template, 50, tt.body, tt.footer)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("NewCommitStyle(%q, %q, %q, %q) = %v, want %q", tt.name, tt.template, tt.body, tt.footer, err, tt.err)
+		}
+	}
+}
+
+func TestCommitStyleValidate(t *testing.T) {
+	style := func(name, template string, maxSubject int, body, footer string) *CommitStyle {
+		s, err := NewCommitStyle(name, template, maxSubject, body, footer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	conventional := style(CommitConventional, "", 50, "", "")
+	gitmoji := style(CommitGitmoji, "", 50, "", "")
+	custom := style(CommitCustom, "[{scope}] {subject}", 50, "", "")
+	strict := style(CommitConventional, "", 50, PartRequired, PartRequired)
+	bare := style(CommitConventional, "", 50, PartNone, PartNone)
+
+	tests := []struct {
+		style    *CommitStyle
+		msg      string
+		problems []string
+	}{
+		{conventional, "feat(parser): support generics", nil},
+		{conventional, "fix: handle empty input", nil},
+		{conventional, "refactor(core/search)!: drop the old ranking", nil},
+		{conventional, "feature: add x", []string{"not in the conventional format"}},
+		{conventional, "feat(parser):missing space", []string{"not in the conventional format"}},
+		{conventional, "Add a thing", []string{"not in the conventional format"}},
+		{conventional, "", []string{"subject line is empty"}},
+		{conventional, "feat: " + strings.Repeat("a", 44), nil}, // exactly 50 characters
+		{conventional, "feat: " + strings.Repeat("a", 45), []string{"has 51 characters, at most 50"}},
+		{conventional, "feat: x\nbody right away", []string{"followed by a blank line"}},
+		{gitmoji, "✨ (parser): support generics", nil},
+		{gitmoji, ":bug: parser: fix a crash", nil},
+		{gitmoji, "🐛 fix a crash", nil},
+		{gitmoji, "fix a crash", []string{"not in the gitmoji format"}},
+		// The limit counts characters, not bytes
+		{gitmoji, "✨ " + strings.Repeat("é", 48), nil},
+		{custom, "[cli] add a flag", nil},
+		{custom, "cli: add a flag", []string{`does not follow the template "[{scope}] {subject}"`}},
+		{strict, "feat: x\n\nWhy it changed.\n\nRefs: #12", nil},
+		{strict, "feat: x\n\nRefs: #12", []string{"a body is required"}},
+		{strict, "feat: x\n\nWhy it changed.", []string{"a footer"}},
+		{strict, "feat: x\n\nWhy.\n\nBREAKING CHANGE: the API moved\nReviewed-by: Z", nil},
+		{bare, "feat: x", nil},
+		{bare, "feat: x\n\nWhy.", []string{"a body is not allowed"}},
+		{bare, "feat: x\n\nFixes #3", []string{"a footer is not allowed"}},
+	}
+	for _, tt := range tests {
+		got := tt.style.Validate(tt.msg)
+		if len(got) != len(tt.problems) {
+			t.Errorf("%s: Validate(%q) = %q, want %q", tt.style.Name, tt.msg, got, tt.problems)
+			continue
+		}
+		for i, p := range tt.problems {
+			if !strings.Contains(got[i], p) {
+				t.Errorf("%s: Validate(%q) = %q, want %q", tt.style.Name, tt.msg, got, tt.problems)
+			}
+		}
+	}
+}
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"feat: x", "feat: x"},
+		{"```\nfeat: x\n\nbody\n```", "feat: x\n\nbody"},
+		{"```text\nfeat: x\n```\n", "feat: x"},
+		{"Commit message:\nfeat: x", "feat: x"},
+		{"Here is the message:\n\n\"fix: y\"", "fix: y"},
+		{"'fix: y'", "fix: y"},
+		{"feat: x  \n\n\n\nbody\t\n", "feat: x\n\nbody"},
+		// A subject ending with ":" alone is kept
+		{"docs: explain why:", "docs: explain why:"},
+	}
+	for _, tt := range tests {
+		if got := CleanCommitMessage(tt.text); got != tt.want {
+			t.Errorf("CleanCommitMessage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// diffOf returns a diff adding n lines (with text) to each file.
+func diffOf(files map[string]int, text string) []utils.DiffFile {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -1,0 +1,%d @@\n", name, name, name, name, files[name])
+		for i := 0; i < files[name]; i++ {
+			b.WriteString("+" + text + "\n")
+		}
+	}
+	return utils.ParseDiff(b.String())
+}
+
+func TestInferScopes(t *testing.T) {
+	tests := []struct {
+		files map[string]int
+		want  []string
+	}{
+		{map[string]int{"internal/core/a.go": 1, "internal/core/b.go": 1}, []string{"core"}},
+		{map[string]int{"internal/cli/a.go": 1, "internal/core/a.go": 5}, []string{"core", "cli"}},
+		{map[string]int{"README.md": 9, "docs/USAGE.md": 1}, []string{"docs"}},
+		{map[string]int{"main.go": 3}, nil},
+	}
+	for _, tt := range tests {
+		if got := InferScopes(diffOf(tt.files, "x")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("InferScopes(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]int
+		text    string
+		symbols []ChangedSymbol
+		want    [][]string
+	}{
+		{
+			name:  "one package",
+			files: map[string]int{"internal/core/a.go": 1, "internal/core/b.go": 1},
+			text:  "x",
+		},
+		{
+			name:  "unrelated packages",
+			files: map[string]int{"internal/core/a.go": 1, "internal/lsp/b.go": 1},
+			text:  "x",
+			want:  [][]string{{"internal/core/a.go"}, {"internal/lsp/b.go"}},
+		},
+		{
+			name:  "docs belong to no group",
+			files: map[string]int{"internal/core/a.go": 1, "docs/USAGE.md": 1},
+			text:  "x",
+		},
+		{
+			name:  "nested packages",
+			files: map[string]int{"internal/core/a.go": 1, "internal/core/sub/b.go": 1},
+			text:  "x",
+		},
+		{
+			name:    "uses a changed symbol",
+			files:   map[string]int{"internal/core/a.go": 1, "internal/cli/b.go": 1},
+			text:    "core.FuseRankings(lists)",
+			symbols: []ChangedSymbol{{File: "internal/core/a.go", Name: "FuseRankings"}},
+		},
+		{
+			name:    "common names do not join",
+			files:   map[string]int{"internal/core/a.go": 1, "internal/cli/b.go": 1},
+			text:    "init()",
+			symbols: []ChangedSymbol{{File: "internal/core/a.go", Name: "init"}},
+			want:    [][]string{{"internal/cli/b.go"}, {"internal/core/a.go"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitGroups(diffOf(tt.files, tt.text), tt.symbols); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitGroups = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultContextBudgets is the token budget of the retrieved context per command.
+// Broad tasks like review and analyze get far more than a single question. They
+// can be overridden with `context_budget` in .archon.yaml.
+var DefaultContextBudgets = map[string]int{
+	"ask":      4000,
+	"commit":   4000,
+	"explain":  6000,
+	"test":     6000,
+	"doc":      6000,
+	"refactor": 8000,
+	"diagram":  12000,
+	"review":   24000,
+	"analyze":  32000,
+}
+
+const defaultContextBudget = 4000
+
+// maxParentShare limits the enclosing symbol a hit is expanded to (e.g. the
+// class around a method) to 1/maxParentShare of the budget, so that one large
+// class does not crowd out the other hits.
+const maxParentShare = 4
+
+// ContextBudget returns the token budget for command, preferring overrides.
+func ContextBudget(command string, overrides map[string]int) int {
+	if budget, ok := overrides[command]; ok && budget > 0 {
+		return budget
+	}
+	if budget, ok := DefaultContextBudgets[command]; ok {
+		return budget
+	}
+	return defaultContextBudget
+}
+
+// EstimateTokens approximates the token count of text (about 4 characters per
+// token for code), which is good enough for budgeting without an API call.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+type DropReason string
+
+const (
+	// DropDuplicate means the snippet is already covered by an included one.
+	DropDuplicate DropReason = "duplicate"
+	// DropBudget means the snippet did not fit in the remaining token budget.
+	DropBudget DropReason = "over budget"
+)
+
+type DroppedSnippet struct {
+	Snippet Snippet
+	Reason  DropReason
+}
+
+// ContextResult is the assembled prompt context and what went into it.
+type ContextResult struct {
+	Text     string
+	Snippets []Snippet
+	Dropped  []DroppedSnippet
+	Tokens   int
+	Budget   int
+}
+
+// Summary is a one-line description of the context, e.g. for the CLI.
+func (r *ContextResult) Summary() string {
+	s := fmt.Sprintf("%d snippets, ~%d/%d tokens", len(r.Snippets), r.Tokens, r.Budget)
+	if len(r.Dropped) == 0 {
+		return s
+	}
+
+	counts := make(map[DropReason]int)
+	var names []string
+	for _, d := range r.Dropped {
+		counts[d.Reason]++
+		names = append(names, d.Snippet.Citation())
+	}
+	var reasons []string
+	for _, reason := range []DropReason{DropBudget, DropDuplicate} {
+		if counts[reason] > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s", counts[reason], reason))
+		}
+	}
+	if len(names) > 5 {
+		names = append(names[:5], fmt.Sprintf("and %d more", len(names)-5))
+	}
+	return fmt.Sprintf("%s; dropped %s: %s", s, strings.Join(reasons, ", "), strings.Join(names, ", "))
+}
+
+// SearchContext retrieves the code relevant to query and assembles it into a
+// prompt context of at most budget tokens. Hits are expanded to their whole
+// symbol, or the symbol enclosing it (e.g. the class of a method), when it
+// fits. Snippets covered by another are dropped, overlapping ones are merged,
+// and the result is ordered by file and line. opts restricts which code is considered.
+func (o *Orchestrator) SearchContext(ctx context.Context, query string, budget int, opts SearchOptions) (*ContextResult, error) {
+	if budget <= 0 {
+		budget = defaultContextBudget
+	}
+
+	// Over-fetch (roughly one candidate per 150 tokens of budget): a part of the
+	// candidates is always lost to duplicates or the budget.
+	hits, err := o.Search(ctx, query, min(max(budget/150, 5), 200), opts)
+	if err != nil {
+		return nil, err
+	}
+	return o.assembleContext(hits, budget), nil
+}
+
+func (o *Orchestrator) assembleContext(hits []Snippet, budget int) *ContextResult {
+	res := &ContextResult{Budget: budget}
+	used := EstimateTokens(contextHeader)
+
+	var selected []Snippet
+	for _, hit := range hits {
+		// Prefer the enclosing symbol, then the whole symbol, and fall back to
+		// the matching chunk if neither fits
+		var options []Snippet
+		if parent, ok := o.parentSnippet(hit); ok && EstimateTokens(formatSnippet(parent)) <= budget/maxParentShare {
+			options = append(options, parent)
+		}
+		if full, ok := o.expandSnippet(hit); ok {
+			options = append(options, full)
+		}
+		options = append(options, hit)
+
+		reason := DropBudget
+		for _, snip := range options {
+			if coveredBy(selected, snip) {
+				reason = DropDuplicate
+				break
+			}
+
+			merged, rest, freed := absorb(selected, snip)
+			cost := EstimateTokens(formatSnippet(merged))
+			if used-freed+cost > budget {
+				continue
+			}
+			selected = append(rest, merged)
+			used += cost - freed
+			reason = ""
+			break
+		}
+		if reason != "" {
+			res.Dropped = append(res.Dropped, DroppedSnippet{Snippet: hit, Reason: reason})
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].File != selected[j].File {
+			return selected[i].File < selected[j].File
+		}
+		return selected[i].StartLine < selected[j].StartLine
+	})
+
+	res.Snippets = selected
+	res.Text = FormatContext(selected)
+	res.Tokens = EstimateTokens(res.Text)
+	return res
+}
+
+// expandSnippet rebuilds the whole symbol a chunk belongs to from all its chunks.
+func (o *Orchestrator) expandSnippet(snip Snippet) (Snippet, bool) {
+	if snip.SymbolID == "" || snip.Chunks < 2 {
+		return snip, false
+	}
+
+	var full Snippet
+	var content strings.Builder
+	for i := 1; i <= snip.Chunks; i++ {
+		doc, ok := o.store.Document(fmt.Sprintf("%s[%d]", snip.SymbolID, i))
+		if !ok {
+			return snip, false
+		}
+		part := snippetFromDocument(doc.ID, doc.Content, doc.Metadata)
+		if i == 1 {
+			full = part
+		}
+		full.EndLine = part.EndLine
+		content.WriteString(part.Content)
+	}
+
+	full.ID = snip.SymbolID
+	full.Content = content.String()
+	full.Chunk, full.Chunks = 0, 0
+	full.Score = snip.Score
+	return full, true
+}
+
+// parentSnippet returns the symbol enclosing snip, e.g. the class around a
+// method. Go methods name their receiver type as parent, which does not
+// enclose them and is not returned.
+func (o *Orchestrator) parentSnippet(snip Snippet) (Snippet, bool) {
+	if snip.Parent == "" || snip.StartLine == 0 {
+		return Snippet{}, false
+	}
+
+	id := snip.File + ":" + snip.Parent
+	var parent Snippet
+	if doc, ok := o.store.Document(id); ok {
+		parent = snippetFromDocument(doc.ID, doc.Content, doc.Metadata)
+	} else if doc, ok := o.store.Document(id + "[1]"); ok {
+		// A large parent is only useful whole
+		if parent, ok = o.expandSnippet(snippetFromDocument(doc.ID, doc.Content, doc.Metadata)); !ok {
+			return Snippet{}, false
+		}
+	} else {
+		return Snippet{}, false
+	}
+
+	if !contains(parent, snip) {
+		return Snippet{}, false
+	}
+	parent.Score = snip.Score
+	return parent, true
+}
+
+// absorb folds the selected snippets that snip contains or partly overlaps into
+// it, so that shared lines are only paid for once. It returns the combined
+// snippet, the snippets left over and the tokens the absorbed ones took.
+func absorb(selected []Snippet, snip Snippet) (Snippet, []Snippet, int) {
+	rest := selected
+	freed := 0
+	for changed := true; changed; {
+		changed = false
+		var keep []Snippet
+		for _, s := range rest {
+			if contains(snip, s) {
+				freed += EstimateTokens(formatSnippet(s))
+				continue
+			}
+			if merged, ok := mergeSnippets(snip, s); ok {
+				// The merged snippet may now contain snippets kept before
+				snip = merged
+				freed += EstimateTokens(formatSnippet(s))
+				changed = true
+				continue
+			}
+			keep = append(keep, s)
+		}
+		rest = keep
+	}
+	return snip, rest, freed
+}
+
+// mergeSnippets joins two snippets of the same file whose line ranges overlap
+// into one covering both.
+func mergeSnippets(a, b Snippet) (Snippet, bool) {
+	if a.File != b.File || a.Type == "file" || b.Type == "file" || a.StartLine == 0 || b.StartLine == 0 {
+		return Snippet{}, false
+	}
+	if b.StartLine < a.StartLine {
+		a, b = b, a
+	}
+	if b.StartLine > a.EndLine {
+		return Snippet{}, false
+	}
+
+	// The content must line up with the line range to be stitched together
+	aLines := snippetLines(a)
+	bLines := snippetLines(b)
+	if aLines == nil || bLines == nil {
+		return Snippet{}, false
+	}
+	if b.EndLine > a.EndLine {
+		aLines = append(aLines, bLines[a.EndLine-b.StartLine+1:]...)
+	}
+
+	merged := a
+	merged.ID = a.ID + "+" + b.ID
+	merged.Name = qualifiedName(a) + ", " + qualifiedName(b)
+	merged.Parent = ""
+	if a.Type != b.Type {
+		merged.Type = "symbols"
+	}
+	merged.Signature = ""
+	merged.EndLine = max(a.EndLine, b.EndLine)
+	merged.StartColumn, merged.EndColumn = 0, 0
+	merged.Content = strings.Join(aLines, "\n")
+	merged.Score = max(a.Score, b.Score)
+	merged.SymbolID, merged.Chunk, merged.Chunks = "", 0, 0
+	return merged, true
+}
+
+// snippetLines splits the content of snip into its lines, or returns nil when
+// their number does not match its line range.
+func snippetLines(snip Snippet) []string {
+	lines := strings.Split(strings.TrimSuffix(snip.Content, "\n"), "\n")
+	if len(lines) != snip.EndLine-snip.StartLine+1 {
+		return nil
+	}
+	return lines
+}
+
+func qualifiedName(snip Snippet) string {
+	if snip.Parent == "" {
+		return snip.Name
+	}
+	return snip.Parent + "." + snip.Name
+}
+
+// contains reports whether a covers all of b.
+func contains(a, b Snippet) bool {
+	if a.ID == b.ID {
+		return true
+	}
+	if a.File != b.File {
+		return false
+	}
+	if a.Type == "file" {
+		return true
+	}
+	return a.StartLine > 0 && b.StartLine > 0 && a.StartLine <= b.StartLine && b.EndLine <= a.EndLine
+}
+
+func coveredBy(selected []Snippet, snip Snippet) bool {
+	for _, s := range selected {
+		if contains(s, snip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"archon/internal/adapters/vectordb"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// lines returns the code of lines start..end, one "line N" per line.
+func lines(start, end int) string {
+	var l []string
+	for i := start; i <= end; i++ {
+		l = append(l, fmt.Sprintf("line %d", i))
+	}
+	return strings.Join(l, "\n")
+}
+
+func doc(id, file, name, parent, typ string, start, end int) chromem.Document {
+	metadata := map[string]string{
+		"file":       file,
+		"name":       name,
+		"type":       typ,
+		"start_line": strconv.Itoa(start),
+		"end_line":   strconv.Itoa(end),
+	}
+	if parent != "" {
+		metadata["parent"] = parent
+	}
+	return chromem.Document{ID: id, Content: lines(start, end), Metadata: metadata}
+}
+
+func testOrchestrator(t *testing.T, docs ...chromem.Document) *Orchestrator {
+	t.Helper()
+	store, err := vectordb.NewStore(context.Background(), t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.AddDocuments(context.Background(), docs); err != nil {
+		t.Fatal(err)
+	}
+	return NewOrchestrator(store)
+}
+
+func snippet(d chromem.Document) Snippet {
+	return snippetFromDocument(d.ID, d.Content, d.Metadata)
+}
+
+func TestAssembleContextMergesOverlaps(t *testing.T) {
+	a := doc("a.go:A", "a.go", "A", "", "function", 1, 5)
+	b := doc("a.go:B", "a.go", "B", "", "function", 4, 8)
+	c := doc("a.go:C", "a.go", "C", "", "function", 7, 12)
+	o := testOrchestrator(t, a, b, c)
+
+	res := o.assembleContext([]Snippet{snippet(a), snippet(c), snippet(b)}, 4000)
+	if len(res.Snippets) != 1 {
+		t.Fatalf("got %d snippets, want 1 merged: %+v", len(res.Snippets), res.Snippets)
+	}
+	got := res.Snippets[0]
+	if got.StartLine != 1 || got.EndLine != 12 || got.Content != lines(1, 12) {
+		t.Errorf("merged lines %d-%d with content %q, want 1-12", got.StartLine, got.EndLine, got.Content)
+	}
+	if got.Name != "A, B, C" {
+		t.Errorf("merged name = %q", got.Name)
+	}
+
+	// The shared lines are only paid for once
+	separate := EstimateTokens(contextHeader + formatSnippet(snippet(a)) + formatSnippet(snippet(b)) + formatSnippet(snippet(c)))
+	if res.Tokens >= separate {
+		t.Errorf("merged context takes %d tokens, separate snippets %d", res.Tokens, separate)
+	}
+}
+
+func TestAssembleContextKeepsDisjointSnippets(t *testing.T) {
+	a := doc("a.go:A", "a.go", "A", "", "function", 1, 3)
+	b := doc("a.go:B", "a.go", "B", "", "function", 5, 8)
+	other := doc("b.go:A", "b.go", "A", "", "function", 1, 3)
+	o := testOrchestrator(t, a, b, other)
+
+	res := o.assembleContext([]Snippet{snippet(b), snippet(other), snippet(a), snippet(a)}, 4000)
+	var ids []string
+	for _, s := range res.Snippets {
+		ids = append(ids, s.ID)
+	}
+	if strings.Join(ids, " ") != "a.go:A a.go:B b.go:A" {
+		t.Errorf("snippets = %q, want them sorted by file and line", ids)
+	}
+	if len(res.Dropped) != 1 || res.Dropped[0].Reason != DropDuplicate {
+		t.Errorf("dropped = %+v, want the repeated hit as duplicate", res.Dropped)
+	}
+}
+
+func TestAssembleContextExpandsToParent(t *testing.T) {
+	class := doc("a.py:Shape", "a.py", "Shape", "", "class", 1, 20)
+	method := doc("a.py:Shape.area", "a.py", "area", "Shape", "method", 5, 8)
+	// A Go receiver type does not enclose its methods
+	typ := doc("t.go:Point", "t.go", "Point", "", "type", 1, 3)
+	goMethod := doc("t.go:Point.Norm", "t.go", "Norm", "Point", "method", 5, 7)
+	o := testOrchestrator(t, class, method, typ, goMethod)
+
+	tests := []struct {
+		name   string
+		hit    chromem.Document
+		budget int
+		want   string
+	}{
+		{"class fits", method, 4000, "a.py:Shape"},
+		{"class too large", method, 150, "a.py:Shape.area"},
+		{"receiver type", goMethod, 4000, "t.go:Point.Norm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := o.assembleContext([]Snippet{snippet(tt.hit)}, tt.budget)
+			if len(res.Snippets) != 1 || res.Snippets[0].ID != tt.want {
+				t.Errorf("snippets = %+v, want %s", res.Snippets, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package core
+
+import (
+	"archon/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Edit is one step of an EditPlan. It either creates File with Content, replaces
+// the only occurrence of Search with Replace, or replaces lines StartLine to
+// EndLine (1-based, inclusive) with Replace. An EndLine of StartLine-1 inserts
+// Replace before StartLine.
+type Edit struct {
+	File      string `json:"file"`
+	Search    string `json:"search,omitempty"`
+	Replace   string `json:"replace,omitempty"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Create    bool   `json:"create,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// EditPlan is a set of edits across files proposed by the model, applied as a whole.
+type EditPlan struct {
+	Summary string `json:"summary"`
+	Edits   []Edit `json:"edits"`
+}
+
+// EditPlanFormat describes the JSON the model must answer with, for prompts.
+const EditPlanFormat = `Answer with ONLY a JSON object in a single Markdown code block (` + "```json" + `), in this format:
+{
+  "summary": "one paragraph describing the change",
+  "edits": [
+    {"file": "path/to/file.go", "search": "exact existing text", "replace": "new text"},
+    {"file": "path/to/file.go", "start_line": 10, "end_line": 12, "replace": "new lines 10 to 12"},
+    {"file": "path/to/new_file.go", "create": true, "content": "complete contents of the new file"}
+  ]
+}
+Rules: "search" must be copied exactly from the current file, including indentation, and must occur exactly once in it; include enough surrounding lines to make it unique. Prefer "search" over line ranges. Edits of the same file must not overlap. Paths are relative to the project root.`
+
+// ParseEditPlan reads an edit plan from a model response, which may wrap the
+// JSON in a code block or surround it with text.
+func ParseEditPlan(text string) (*EditPlan, error) {
+	data, err := extractJSON(text)
+	if err != nil {
+		return nil, fmt.Errorf("no JSON edit plan in the response")
+	}
+
+	var plan EditPlan
+	if err := json.Unmarshal([]byte(data), &plan); err != nil {
+		return nil, fmt.Errorf("invalid edit plan: %w", err)
+	}
+	if len(plan.Edits) == 0 {
+		return nil, fmt.Errorf("the edit plan has no edits")
+	}
+	return &plan, nil
+}
+
+// extractJSON returns the JSON object in a model response: the contents of its
+// code block if there is one, from the first "{" to the last "}".
+func extractJSON(text string) (string, error) {
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:]
+		}
+		if end := strings.LastIndex(body, "```"); end >= 0 {
+			body = body[:end]
+		}
+		text = body
+	}
+	start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return "", fmt.Errorf("no JSON object found")
+	}
+	return text[start : end+1], nil
+}
+
+// span is a resolved edit: the bytes [start, end) of a file are replaced.
+type span struct {
+	start, end int
+	text       string
+	edit       int
+}
+
+// Resolve checks every edit against the current contents of its file, as
+// returned by read, and returns the resulting change of each file. Nothing is
+// written; any edit that does not fit the current contents fails the whole plan.
+func (p *EditPlan) Resolve(read func(path string) ([]byte, error)) ([]FileChange, error) {
+	var order []string
+	byFile := make(map[string][]int)
+	for i, edit := range p.Edits {
+		path, err := planPath(edit.File)
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: %w", i+1, err)
+		}
+		if _, ok := byFile[path]; !ok {
+			order = append(order, path)
+		}
+		byFile[path] = append(byFile[path], i)
+	}
+
+	var changes []FileChange
+	for _, path := range order {
+		indexes := byFile[path]
+		old, err := read(path)
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		if p.Edits[indexes[0]].Create {
+			if exists {
+				return nil, fmt.Errorf("edit %d: %s already exists", indexes[0]+1, path)
+			}
+			if len(indexes) > 1 {
+				return nil, fmt.Errorf("edit %d: %s is created by edit %d, put all of its contents there", indexes[1]+1, path, indexes[0]+1)
+			}
+			content := p.Edits[indexes[0]].Content
+			if content != "" && !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			changes = append(changes, FileChange{Path: path, New: []byte(content)})
+			continue
+		}
+		if !exists {
+			return nil, fmt.Errorf("edit %d: %s does not exist", indexes[0]+1, path)
+		}
+
+		var spans []span
+		for _, i := range indexes {
+			s, err := resolveEdit(string(old), p.Edits[i])
+			if err != nil {
+				return nil, fmt.Errorf("edit %d (%s): %w", i+1, path, err)
+			}
+			s.edit = i
+			spans = append(spans, s)
+		}
+		sort.Slice(spans, func(a, b int) bool { return spans[a].start < spans[b].start })
+		for k := 1; k < len(spans); k++ {
+			if spans[k].start < spans[k-1].end {
+				return nil, fmt.Errorf("edits %d and %d of %s overlap", spans[k-1].edit+1, spans[k].edit+1, path)
+			}
+		}
+
+		var b strings.Builder
+		pos := 0
+		for _, s := range spans {
+			b.WriteString(string(old[pos:s.start]))
+			b.WriteString(s.text)
+			pos = s.end
+		}
+		b.WriteString(string(old[pos:]))
+
+		if b.String() != string(old) {
+			changes = append(changes, FileChange{Path: path, Existed: true, Old: old, New: []byte(b.String())})
+		}
+	}
+	return changes, nil
+}
+
+// planPath cleans a path of the plan and keeps it inside the project. Plans are
+// applied without looking at every file, so paths into .git, the config file,
+// ignored files and symlinks leading out of the project are refused as well.
+func planPath(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("missing file")
+	}
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the project", path)
+	}
+
+	// Case-insensitive file systems treat .GIT like .git
+	for _, part := range strings.Split(clean, string(filepath.Separator)) {
+		if strings.EqualFold(part, ".git") {
+			return "", fmt.Errorf("%s is inside .git", path)
+		}
+	}
+	if strings.EqualFold(filepath.Base(clean), ".archon.yaml") {
+		return "", fmt.Errorf("%s is the archon configuration", path)
+	}
+	if utils.IsIgnored(clean) {
+		return "", fmt.Errorf("%s is ignored", path)
+	}
+
+	root, err := os.Getwd()
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolveExisting(filepath.Join(root, clean))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s leads outside the project through a symlink", path)
+	}
+	return clean, nil
+}
+
+// resolveExisting resolves the symlinks of path. For a file that does not exist
+// yet, those of its nearest existing parent are resolved.
+func resolveExisting(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	// Writing through a dangling symlink would create its target, wherever it is
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", fmt.Errorf("%s is a symlink to a missing file", path)
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := resolveExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+func resolveEdit(content string, edit Edit) (span, error) {
+	if edit.Create {
+		return span{}, fmt.Errorf("a file that exists cannot be created")
+	}
+
+	if edit.Search != "" {
+		switch n := strings.Count(content, edit.Search); n {
+		case 0:
+			return span{}, fmt.Errorf("search text not found in the current file")
+		case 1:
+			start := strings.Index(content, edit.Search)
+			return span{start: start, end: start + len(edit.Search), text: edit.Replace}, nil
+		default:
+			return span{}, fmt.Errorf("search text occurs %d times, it must be unique", n)
+		}
+	}
+
+	if edit.StartLine <= 0 {
+		return span{}, fmt.Errorf("an edit needs a search text or a line range")
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if edit.EndLine < edit.StartLine-1 || edit.EndLine > len(lines) || edit.StartLine > len(lines)+1 {
+		return span{}, fmt.Errorf("lines %d-%d are outside the file (%d lines)", edit.StartLine, edit.EndLine, len(lines))
+	}
+
+	start := 0
+	for _, line := range lines[:edit.StartLine-1] {
+		start += len(line)
+	}
+	end := start
+	for _, line := range lines[edit.StartLine-1 : edit.EndLine] {
+		end += len(line)
+	}
+	text := edit.Replace
+	// The replaced lines ended with a newline, or lines are appended to a file that does
+	if text != "" && !strings.HasSuffix(text, "\n") && (end < len(content) || strings.HasSuffix(content[start:end], "\n") ||
+		start == len(content) && strings.HasSuffix(content, "\n")) {
+		text += "\n"
+	}
+	if start == len(content) && content != "" && !strings.HasSuffix(content, "\n") {
+		// Appending to a last line without a newline
+		text = "\n" + text
+	}
+	return span{start: start, end: end, text: text}, nil
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"archon/internal/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveEdit(t *testing.T) {
+	content := "one\ntwo\nthree\n"
+	tests := []struct {
+		name string
+		edit Edit
+		want string
+		err  string
+	}{
+		{"search", Edit{Search: "two\n", Replace: "2\n"}, "one\n2\nthree\n", ""},
+		{"search deletes", Edit{Search: "two\n"}, "one\nthree\n", ""},
+		{"search not found", Edit{Search: "four"}, "", "not found"},
+		{"search not unique", Edit{Search: "o"}, "", "occurs 2 times"},
+		{"line range", Edit{StartLine: 2, EndLine: 3, Replace: "x"}, "one\nx\n", ""},
+		{"single line", Edit{StartLine: 1, EndLine: 1, Replace: "1"}, "1\ntwo\nthree\n", ""},
+		{"insert before", Edit{StartLine: 2, EndLine: 1, Replace: "1.5"}, "one\n1.5\ntwo\nthree\n", ""},
+		{"append", Edit{StartLine: 4, EndLine: 3, Replace: "four"}, "one\ntwo\nthree\nfour\n", ""},
+		{"delete lines", Edit{StartLine: 1, EndLine: 2}, "three\n", ""},
+		{"past the end", Edit{StartLine: 3, EndLine: 4, Replace: "x"}, "", "outside the file"},
+		{"start after end", Edit{StartLine: 5, EndLine: 4}, "", "outside the file"},
+		{"no anchor", Edit{Replace: "x"}, "", "search text or a line range"},
+		{"create", Edit{Create: true, Content: "x"}, "", "cannot be created"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := resolveEdit(content, tt.edit)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := content[:s.start] + s.text + content[s.end:]; got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEditWithoutFinalNewline(t *testing.T) {
+	content := "one\ntwo"
+	tests := []struct {
+		edit Edit
+		want string
+	}{
+		{Edit{StartLine: 2, EndLine: 2, Replace: "2"}, "one\n2"},
+		{Edit{StartLine: 1, EndLine: 1, Replace: "1"}, "1\ntwo"},
+		{Edit{StartLine: 3, EndLine: 2, Replace: "three"}, "one\ntwo\nthree"},
+	}
+	for _, tt := range tests {
+		s, err := resolveEdit(content, tt.edit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := content[:s.start] + s.text + content[s.end:]; got != tt.want {
+			t.Errorf("lines %d-%d: result = %q, want %q", tt.edit.StartLine, tt.edit.EndLine, got, tt.want)
+		}
+	}
+}
+
+// inProject runs the test in a new project directory with files.
+func inProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	utils.ResetIgnoreCache()
+	t.Cleanup(utils.ResetIgnoreCache)
+	return root
+}
+
+func TestPlanPath(t *testing.T) {
+	root := inProject(t, map[string]string{
+		".gitignore":  "secret.env\n",
+		"main.go":     "package main\n",
+		"pkg/a.go":    "package pkg\n",
+		"secret.env":  "",
+		".git/config": "",
+	})
+	outside := t.TempDir()
+	for link, target := range map[string]string{
+		"out":      outside,
+		"pkg/up":   root,
+		"dangling": filepath.Join(outside, "missing.go"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"main.go", "main.go", ""},
+		{"./pkg/../pkg/a.go", filepath.Join("pkg", "a.go"), ""},
+		{"pkg/new/b.go", filepath.Join("pkg", "new", "b.go"), ""},
+		{"pkg/up/main.go", filepath.Join("pkg", "up", "main.go"), ""}, // a symlink inside the project
+		{"", "", "missing file"},
+		{"../x.go", "", "outside the project"},
+		{"pkg/../../x.go", "", "outside the project"},
+		{filepath.Join(outside, "x.go"), "", "outside the project"},
+		{".git/config", "", "inside .git"},
+		{"sub/.GIT/hooks/pre-commit", "", "inside .git"},
+		{".archon.yaml", "", "configuration"},
+		{"secret.env", "", "ignored"},
+		{"node_modules/x/index.js", "", "ignored"},
+		{"out/x.go", "", "through a symlink"},
+		{"out/new/x.go", "", "through a symlink"},
+		{"dangling", "", "missing file"},
+	}
+	for _, tt := range tests {
+		got, err := planPath(tt.path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("planPath(%q) = %q, %v, want an error containing %q", tt.path, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("planPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestEditPlanResolve(t *testing.T) {
+	files := map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+		"b.go": "package b\n",
+	}
+	read := func(path string) ([]byte, error) {
+		content, ok := files[filepath.ToSlash(path)]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+	inProject(t, nil)
+
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  map[string]string
+		err   string
+	}{
+		{
+			name: "edits across files",
+			edits: []Edit{
+				{File: "a.go", Search: "func A() {}", Replace: "func A() int { return 1 }"},
+				{File: "a.go", StartLine: 1, EndLine: 1, Replace: "package alpha"},
+				{File: "c.go", Create: true, Content: "package c"},
+			},
+			want: map[string]string{
+				"a.go": "package alpha\n\nfunc A() int { return 1 }\n",
+				"c.go": "package c\n",
+			},
+		},
+		{
+			name:  "unchanged files are left out",
+			edits: []Edit{{File: "b.go", Search: "package b", Replace: "package b"}},
+			want:  map[string]string{},
+		},
+		{
+			name:  "overlapping edits",
+			edits: []Edit{{File: "a.go", Search: "func A", Replace: "func B"}, {File: "a.go", StartLine: 3, EndLine: 3}},
+			err:   "overlap",
+		},
+		{
+			name:  "create an existing file",
+			edits: []Edit{{File: "b.go", Create: true, Content: "x"}},
+			err:   "already exists",
+		},
+		{
+			name:  "edit a missing file",
+			edits: []Edit{{File: "d.go", Search: "x"}},
+			err:   "does not exist",
+		},
+		{
+			name:  "created file edited again",
+			edits: []Edit{{File: "c.go", Create: true}, {File: "c.go", Search: "x"}},
+			err:   "put all of its contents there",
+		},
+		{
+			name:  "unsafe path",
+			edits: []Edit{{File: ".git/config", Create: true}},
+			err:   "edit 1: .git/config is inside .git",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := (&EditPlan{Edits: tt.edits}).Resolve(read)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, c := range changes {
+				got[filepath.ToSlash(c.Path)] = string(c.New)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("changed %v, want %v", got, tt.want)
+			}
+			for path, want := range tt.want {
+				if got[path] != want {
+					t.Errorf("%s = %q, want %q", path, got[path], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of provider failures. Adapters wrap their errors in a ProviderError so
+// commands can match them with errors.Is, whatever the provider.
+var (
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+	ErrSafetyBlocked  = errors.New("blocked by safety filters")
+	ErrInvalidKey     = errors.New("invalid API key")
+	ErrContextTooLong = errors.New("prompt exceeds the model's context window")
+)
+
+// ProviderError is a failed request to an LLM or embedding API.
+type ProviderError struct {
+	// Kind is one of the Err* kinds above, or nil if the failure has no specific kind.
+	Kind error
+	// Temporary reports whether repeating the request may succeed.
+	Temporary bool
+	// RetryAfter is how long the server asked to wait before retrying, if it said so.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.Kind == nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// ErrorHint suggests what the user can do about err, or returns "" if there is
+// nothing specific to say.
+func ErrorHint(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidKey):
+		return "Check the API key with 'archon auth' or the ARCHON_GEMINI_KEY / ARCHON_OPENAI_KEY environment variables."
+	case errors.Is(err, ErrQuotaExceeded):
+		return "The provider's rate limit or quota is used up. Wait a moment, or switch to a model with a higher quota."
+	case errors.Is(err, ErrContextTooLong):
+		return "Lower the context budget of this command (context_budget in .archon.yaml) or narrow it with --path."
+	case errors.Is(err, ErrSafetyBlocked):
+		return "The model refused to answer. Rephrase the request or leave out the content that triggered the filter."
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// LLM is the provider-agnostic interface used by every command, the TUI and the LSP server.
+// Adapters (Gemini, OpenAI-compatible, ...) implement it.
+type LLM interface {
+	Generate(ctx context.Context, prompt string) (*Response, error)
+	// GenerateStream is Generate delivering the answer while it is produced.
+	// The channel yields text deltas and ends with one event carrying either
+	// the final Response or an error, after which it is closed.
+	GenerateStream(ctx context.Context, prompt string) <-chan StreamEvent
+	// ChatStream continues a conversation: messages alternate between user and
+	// assistant and end with the user message to answer. Events are delivered
+	// like GenerateStream.
+	ChatStream(ctx context.Context, messages []Message) <-chan StreamEvent
+	CountTokens(ctx context.Context, text string) (int, error)
+	Close() error
+}
+
+type Response struct {
+	Text         string
+	PromptTokens int
+	AnswerTokens int
+	TotalTokens  int
+}
+
+// StreamEvent is one event of GenerateStream. Exactly one of the fields is set.
+type StreamEvent struct {
+	Delta    string
+	Response *Response
+	Err      error
+}
+
+// CollectStream drains events, calling onDelta (if not nil) for every text
+// delta, and returns the final response.
+func CollectStream(events <-chan StreamEvent, onDelta func(string)) (*Response, error) {
+	for ev := range events {
+		switch {
+		case ev.Err != nil:
+			// Drain, so the producer can finish
+			for range events {
+			}
+			return nil, ev.Err
+		case ev.Response != nil:
+			for range events {
+			}
+			return ev.Response, nil
+		case onDelta != nil:
+			onDelta(ev.Delta)
+		}
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
+
+// Roles of a Message.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation sent to ChatStream.
+type Message struct {
+	Role    string
+	Content string
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// manifestVersion is bumped whenever symbol extraction or document layout changes,
+// so existing indexes are rebuilt instead of mixing old and new documents.
+const manifestVersion = 2
+
+const manifestFile = "manifest.json"
+
+// Manifest records what has been indexed for every file, so unchanged files
+// can be skipped and stale documents removed.
+type Manifest struct {
+	Version  int                       `json:"version"`
+	Embedder string                    `json:"embedder"`
+	Files    map[string]*ManifestEntry `json:"files"`
+
+	path string
+	mu   sync.Mutex
+
+	// stale is set when no usable manifest was found, so whatever is already in
+	// the store was indexed by an unknown version or embedder.
+	stale bool
+}
+
+type ManifestEntry struct {
+	Hash      string   `json:"hash"`
+	SymbolIDs []string `json:"symbol_ids"`
+}
+
+// LoadManifest reads the manifest at path. A missing manifest, or one written by
+// another embedder or manifest version, yields an empty manifest marked stale.
+func LoadManifest(path string, embedder string) (*Manifest, error) {
+	m := &Manifest{
+		Version:  manifestVersion,
+		Embedder: embedder,
+		Files:    make(map[string]*ManifestEntry),
+		path:     path,
+		stale:    true,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var stored Manifest
+	if err := json.Unmarshal(data, &stored); err != nil {
+		// A corrupt manifest only costs a full re-index.
+		return m, nil
+	}
+	if stored.Version != manifestVersion || stored.Embedder != embedder || stored.Files == nil {
+		return m, nil
+	}
+
+	m.Files = stored.Files
+	m.stale = false
+	return m, nil
+}
+
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return err
+	}
+
+	// Write atomically so an interrupted run never leaves a truncated manifest.
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(tmp, m.path)
+}
+
+func (m *Manifest) Get(path string) (*ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.Files[manifestKey(path)]
+	return entry, ok
+}
+
+func (m *Manifest) Set(path string, entry *ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files[manifestKey(path)] = entry
+}
+
+func (m *Manifest) Remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Files, manifestKey(path))
+}
+
+// Paths returns every file recorded in the manifest.
+func (m *Manifest) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// Reset forgets every entry, e.g. after the store was cleared.
+func (m *Manifest) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files = make(map[string]*ManifestEntry)
+	m.stale = false
+}
+
+func manifestKey(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+func contentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
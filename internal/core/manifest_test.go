@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"archon/internal/utils"
+)
+
+func TestLoadManifestStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), manifestFile)
+
+	m, err := LoadManifest(path, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.stale {
+		t.Error("a missing manifest is not stale")
+	}
+	m.Set("a.go", &ManifestEntry{Hash: "h", SymbolIDs: []string{"a.go:A"}})
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		embedder string
+		stale    bool
+	}{
+		{"same embedder", "none", false},
+		{"other embedder", "gemini:text-embedding-004", true},
+	}
+	for _, tt := range tests {
+		m, err := LoadManifest(path, tt.embedder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.stale != tt.stale || (len(m.Files) == 0) != tt.stale {
+			t.Errorf("%s: stale = %v with %d files, want stale %v", tt.name, m.stale, len(m.Files), tt.stale)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":1,"embedder":"none","files":{"a.go":{"hash":"h"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := LoadManifest(path, "none"); !m.stale || len(m.Files) != 0 {
+		t.Errorf("an older manifest version was kept: %+v", m.Files)
+	}
+}
+
+func TestIndexDirectoryClearsUnrecordedDocuments(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n\nfunc (s *S) Run() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	utils.ResetIgnoreCache()
+	t.Cleanup(utils.ResetIgnoreCache)
+
+	// A document from an older layout that no manifest accounts for
+	old := doc("a.go:Run", "a.go", "Run", "", "method", 3, 3)
+	o := testOrchestrator(t, old)
+
+	if _, err := o.IndexDirectory(context.Background(), ".", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o.store.Document(old.ID); ok {
+		t.Error("the unrecorded document survived indexing")
+	}
+	entry, ok := o.manifest.Get("a.go")
+	if !ok || !slices.Contains(entry.SymbolIDs, "a.go:S.Run") {
+		t.Fatalf("manifest entry = %+v, want a.go:S.Run", entry)
+	}
+	if _, ok := o.store.Document("a.go:S.Run"); !ok {
+		t.Error("a.go:S.Run was not indexed")
+	}
+}
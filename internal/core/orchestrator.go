@@ -5,113 +5,163 @@ import (
 	"archon/internal/adapters/vectordb"
 	"archon/internal/utils"
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
-
-	"github.com/fsnotify/fsnotify"
+	"strings"
 )
 
 type Orchestrator struct {
-	store  *vectordb.Store
-	parser *parser.GenericParser
+	store    *vectordb.Store
+	parser   parser.Parser
+	manifest *Manifest
+	blend    float64
 }
 
 func NewOrchestrator(store *vectordb.Store) *Orchestrator {
 	return &Orchestrator{
 		store:  store,
-		parser: parser.NewGenericParser(),
+		parser: parser.NewTreeSitterParser(),
+		blend:  DefaultSearchBlend,
 	}
 }
 
-func (o *Orchestrator) IndexDirectory(ctx context.Context, dir string, progress func(current, total int, file string)) error {
-	var filesToIndex []string
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if utils.IsIgnored(path) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if info.IsDir() {
-			return nil
-		}
-		lang := parser.DetectLanguage(path)
-		if lang != parser.Unknown {
-			filesToIndex = append(filesToIndex, path)
-		}
-		return nil
-	})
+// SetSearchBlend sets how vector and keyword rankings are weighed when fused:
+// 0 is keyword search only, 1 vector search only.
+func (o *Orchestrator) SetSearchBlend(blend float64) {
+	o.blend = math.Min(math.Max(blend, 0), 1)
+}
 
-	total := len(filesToIndex)
-	for i, path := range filesToIndex {
-		if progress != nil {
-			progress(i+1, total, path)
-		}
+// loadManifest lazily loads the index manifest stored next to the vector DB. A
+// store holding documents that no current manifest accounts for is cleared.
+func (o *Orchestrator) loadManifest(ctx context.Context) (*Manifest, error) {
+	if o.manifest != nil {
+		return o.manifest, nil
+	}
 
-		err := o.IndexFile(ctx, path)
-		if err != nil {
-			continue
-		}
+	m, err := LoadManifest(filepath.Join(o.store.Path(), manifestFile), o.store.EmbedderName())
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	switch {
+	case o.store.Count() == 0 || o.store.KeywordCount() == 0:
+		// The store was cleared (or never filled), whatever the manifest says is stale.
+		m.Reset()
+	case m.stale:
+		// Documents nothing records can never be replaced or removed, and may be laid
+		// out by an older version, so start over rather than mix them with new ones.
+		if err := o.store.Compatible(); err != nil {
+			return nil, err
+		}
+		if err := o.store.Clear(ctx); err != nil {
+			return nil, fmt.Errorf("failed to clear stale index: %w", err)
+		}
+		m.Reset()
+	}
+	o.manifest = m
+	return m, nil
+}
+
+// save persists the store's keyword index, then the manifest, so the manifest
+// never records documents the store lost.
+func (o *Orchestrator) save(m *Manifest) error {
+	if err := o.store.Flush(); err != nil {
+		return err
+	}
+	return m.Save()
 }
 
+// IndexFile (re-)indexes a single file and records it in the manifest.
 func (o *Orchestrator) IndexFile(ctx context.Context, path string) error {
-	content, err := os.ReadFile(path)
+	m, err := o.loadManifest(ctx)
 	if err != nil {
 		return err
 	}
 
-	symbols, err := o.parser.Parse(ctx, path, content)
+	if _, err := o.indexFile(ctx, m, path); err != nil {
+		return errors.Join(err, o.save(m))
+	}
+	return o.save(m)
+}
+
+// RemoveFile purges every document indexed for path.
+func (o *Orchestrator) RemoveFile(ctx context.Context, path string) error {
+	m, err := o.loadManifest(ctx)
 	if err != nil {
-		err = o.store.AddDocument(ctx, path, string(content), map[string]string{
-			"file": path,
-			"type": "file",
-		})
 		return err
 	}
 
-	for _, sym := range symbols {
-		id := path + ":" + sym.Name
-		metadata := map[string]string{
-			"file": path,
-			"name": sym.Name,
-			"type": sym.Type,
-		}
-		err = o.store.AddDocument(ctx, id, sym.Code, metadata)
-		if err != nil {
-			continue
-		}
+	if err := o.removeFile(ctx, m, path); err != nil {
+		return err
 	}
+	return o.save(m)
+}
+
+func (o *Orchestrator) removeFile(ctx context.Context, m *Manifest, path string) error {
+	entry, ok := m.Get(path)
+	if !ok {
+		return nil
+	}
+	if err := o.store.DeleteDocuments(ctx, entry.SymbolIDs...); err != nil {
+		return err
+	}
+	m.Remove(path)
 	return nil
 }
 
-func (o *Orchestrator) SearchContext(ctx context.Context, query string) (string, error) {
-	results, err := o.store.Search(ctx, query, 5)
-	if err != nil {
-		return "", err
+// indexFile re-indexes path unless its content hash is unchanged, replacing the
+// documents previously indexed for it. It reports whether the file was skipped.
+func (o *Orchestrator) indexFile(ctx context.Context, m *Manifest, path string) (bool, error) {
+	f := o.prepareFile(ctx, m, path)
+	if f.err != nil || f.skipped {
+		return f.skipped, f.err
+	}
+	return false, o.commitFiles(ctx, m, []*preparedFile{f})
+}
+
+// isWithin reports whether path lies inside dir.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Search returns the n code snippets most relevant to query within the scope of
+// opts. Vector and BM25 keyword rankings are fused with reciprocal rank fusion,
+// weighted by the search blend; without an embedder only keyword search is used.
+func (o *Orchestrator) Search(ctx context.Context, query string, n int, opts SearchOptions) ([]Snippet, error) {
+	vectorWeight := o.blend
+	if !o.store.HasEmbedder() {
+		vectorWeight = 0
 	}
 
-	contextText := "Here are some relevant code snippets from the codebase:\n\n"
-	for _, res := range results {
-		contextText += "File: " + res.Metadata["file"] + "\n"
-		if name, ok := res.Metadata["name"]; ok {
-			contextText += "Symbol: " + name + " (" + res.Metadata["type"] + ")\n"
+	// Fuse over a deeper pool than what is returned, so a document ranked
+	// moderately by both searches can still make the cut.
+	candidates := n * rrfDepth
+	var lists []rankedList
+	if vectorWeight > 0 {
+		results, err := o.store.Search(ctx, query, candidates, opts)
+		if err != nil {
+			return nil, err
 		}
-		contextText += "```\n" + res.Content + "\n```\n\n"
+		lists = append(lists, rankedList{results: results, weight: vectorWeight})
+	}
+	if vectorWeight < 1 {
+		lists = append(lists, rankedList{results: o.store.KeywordSearch(query, candidates, opts), weight: 1 - vectorWeight})
 	}
 
-	return contextText, nil
+	return fuseRankings(lists, n), nil
 }
 
 func (o *Orchestrator) GetFilesForIndexing(dir string) ([]string, error) {
 	var files []string
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			if path == dir {
+				return err
+			}
+			// Unreadable entries below the root are skipped
 			return nil
 		}
 		if utils.IsIgnored(path) {
@@ -131,63 +181,3 @@ func (o *Orchestrator) GetFilesForIndexing(dir string) ([]string, error) {
 	})
 	return files, err
 }
-
-func (o *Orchestrator) WatchDirectory(ctx context.Context, dir string) error {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return err
-	}
-	defer watcher.Close()
-
-	// Add directories to watch
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if utils.IsIgnored(path) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if info.IsDir() {
-			return watcher.Add(path)
-		}
-		return nil
-	})
-
-	fmt.Println("Watching for changes...")
-	for {
-		select {
-		case event, ok := <-watcher.Events:
-			if !ok {
-				return nil
-			}
-			// We care about Write and Create events
-			if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
-				info, err := os.Stat(event.Name)
-				if err != nil {
-					continue
-				}
-				if info.IsDir() {
-					// If it's a new directory, we should watch it too
-					watcher.Add(event.Name)
-					continue
-				}
-
-				lang := parser.DetectLanguage(event.Name)
-				if lang != parser.Unknown {
-					fmt.Printf("File changed: %s, re-indexing...\n", event.Name)
-					o.IndexFile(ctx, event.Name)
-				}
-			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return nil
-			}
-			fmt.Printf("Watcher error: %v\n", err)
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-}
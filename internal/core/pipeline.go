@@ -0,0 +1,348 @@
+package core
+
+import (
+	"archon/internal/adapters/parser"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+const (
+	// indexBatchSize is how many documents are collected before they are embedded
+	// and stored together.
+	indexBatchSize = 64
+	// maxChunkChars keeps every document well below the embedding models' input
+	// limits; larger symbols are split on line boundaries.
+	maxChunkChars = 6000
+)
+
+// IndexReport summarizes an indexing run.
+type IndexReport struct {
+	Total    int
+	Indexed  int
+	Skipped  int
+	Failed   int
+	Removed  int
+	Errors   []FileError
+	Duration time.Duration
+}
+
+// FileError is the reason a single file could not be indexed.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (r *IndexReport) fail(path string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, FileError{Path: path, Err: err})
+}
+
+// preparedFile is a parsed and chunked file waiting to be embedded and stored.
+type preparedFile struct {
+	path    string
+	hash    string
+	docs    []chromem.Document
+	skipped bool
+	err     error
+}
+
+func (f *preparedFile) ids() []string {
+	ids := make([]string, len(f.docs))
+	for i, doc := range f.docs {
+		ids[i] = doc.ID
+	}
+	return ids
+}
+
+// IndexDirectory indexes every supported file under dir. Files are read and parsed
+// by a pool of workers, their documents embedded and stored in batches. Files whose
+// content hash matches the manifest are skipped, and files that disappeared are
+// purged. Cancelling ctx stops the run; whatever was stored so far is kept.
+func (o *Orchestrator) IndexDirectory(ctx context.Context, dir string, progress func(current, total int, file string)) (*IndexReport, error) {
+	start := time.Now()
+	report := &IndexReport{}
+	defer func() { report.Duration = time.Since(start) }()
+
+	m, err := o.loadManifest(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	files, err := o.GetFilesForIndexing(dir)
+	if err != nil {
+		return report, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	report.Total = len(files)
+
+	jobs := make(chan string)
+	prepared := make(chan *preparedFile)
+	var wg sync.WaitGroup
+	for i := 0; i < min(runtime.NumCPU(), max(len(files), 1)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				select {
+				case prepared <- o.prepareFile(ctx, m, path):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, path := range files {
+			select {
+			case jobs <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(prepared)
+	}()
+
+	done := 0
+	finish := func(f *preparedFile) {
+		done++
+		if progress != nil {
+			progress(done, report.Total, f.path)
+		}
+	}
+
+	var batch []*preparedFile
+	pending := 0
+	flush := func() {
+		o.commitBatch(ctx, m, batch, report)
+		for _, f := range batch {
+			finish(f)
+		}
+		batch, pending = nil, 0
+	}
+
+	for f := range prepared {
+		if ctx.Err() != nil {
+			continue
+		}
+		switch {
+		case f.err != nil:
+			report.fail(f.path, f.err)
+			finish(f)
+		case f.skipped:
+			report.Skipped++
+			finish(f)
+		default:
+			batch = append(batch, f)
+			pending += len(f.docs)
+			if pending >= indexBatchSize {
+				flush()
+			}
+		}
+	}
+	if ctx.Err() == nil && len(batch) > 0 {
+		flush()
+	}
+
+	if err := ctx.Err(); err != nil {
+		// Files that were not stored keep their old manifest entry and are retried next run.
+		return report, errors.Join(err, o.save(m))
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, path := range files {
+		present[manifestKey(path)] = true
+	}
+	for _, path := range m.Paths() {
+		if present[path] || !isWithin(dir, path) {
+			continue
+		}
+		if err := o.removeFile(ctx, m, path); err != nil {
+			report.fail(path, err)
+		} else {
+			report.Removed++
+		}
+	}
+
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Path < report.Errors[j].Path })
+	return report, o.save(m)
+}
+
+// prepareFile reads, hashes, parses and chunks path. It never touches the store.
+func (o *Orchestrator) prepareFile(ctx context.Context, m *Manifest, path string) *preparedFile {
+	f := &preparedFile{path: path}
+	if err := ctx.Err(); err != nil {
+		f.err = err
+		return f
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		f.err = err
+		return f
+	}
+
+	f.hash = contentHash(content)
+	if old, ok := m.Get(path); ok && old.Hash == f.hash {
+		f.skipped = true
+		return f
+	}
+
+	f.docs = o.buildDocuments(ctx, path, content)
+	return f
+}
+
+// buildDocuments turns the symbols of a file into store documents, falling back
+// to the whole file when it cannot be parsed.
+func (o *Orchestrator) buildDocuments(ctx context.Context, path string, content []byte) []chromem.Document {
+	symbols, err := o.parser.Parse(ctx, path, content)
+	if err != nil {
+		return []chromem.Document{{
+			ID:      path,
+			Content: string(content),
+			Metadata: map[string]string{
+				"file":     path,
+				"type":     "file",
+				"language": string(parser.DetectLanguage(path)),
+			},
+		}}
+	}
+
+	var docs []chromem.Document
+	seen := make(map[string]int)
+	for _, sym := range symbols {
+		id := path + ":" + sym.QualifiedName()
+		// Overloads and repeated names (e.g. several Go init funcs) must not overwrite each other
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s#%d", id, seen[id])
+		}
+
+		chunks := chunkSymbol(sym)
+		for i, chunk := range chunks {
+			metadata := symbolMetadata(path, chunk)
+			chunkID := id
+			if len(chunks) > 1 {
+				chunkID = fmt.Sprintf("%s[%d]", id, i+1)
+				metadata["symbol_id"] = id
+				metadata["chunk"] = strconv.Itoa(i+1) + "/" + strconv.Itoa(len(chunks))
+			}
+			docs = append(docs, chromem.Document{ID: chunkID, Content: chunk.Code, Metadata: metadata})
+		}
+	}
+	return docs
+}
+
+// chunkSymbol splits a symbol whose code exceeds maxChunkChars into consecutive
+// line ranges. Small symbols are returned as is.
+func chunkSymbol(sym parser.Symbol) []parser.Symbol {
+	if len(sym.Code) <= maxChunkChars {
+		return []parser.Symbol{sym}
+	}
+
+	var chunks []parser.Symbol
+	lines := strings.SplitAfter(sym.Code, "\n")
+	if lines[len(lines)-1] == "" {
+		// Code ending in a newline has no further line
+		lines = lines[:len(lines)-1]
+	}
+	startLine := max(sym.StartLine, 1)
+	var sb strings.Builder
+	first := 0
+	// emit closes the current chunk, which covers lines first..last (0-based).
+	emit := func(last int) {
+		if sb.Len() == 0 {
+			return
+		}
+		chunk := sym
+		chunk.Code = sb.String()
+		chunk.StartLine = startLine + first
+		chunk.EndLine = startLine + last
+		chunk.StartColumn, chunk.EndColumn = 0, 0
+		chunks = append(chunks, chunk)
+		sb.Reset()
+	}
+	for i, line := range lines {
+		if sb.Len() > 0 && sb.Len()+len(line) > maxChunkChars {
+			emit(i - 1)
+		}
+		if sb.Len() == 0 {
+			first = i
+		}
+		// A single huge line (minified code) is cut as well.
+		for len(line) > maxChunkChars {
+			sb.WriteString(line[:maxChunkChars])
+			line = line[maxChunkChars:]
+			emit(i)
+			first = i
+		}
+		sb.WriteString(line)
+	}
+	emit(len(lines) - 1)
+	return chunks
+}
+
+// commitBatch stores a batch of prepared files. When the batch fails each file is
+// retried on its own, so one bad file does not fail its neighbours and every
+// failure is reported with its own reason.
+func (o *Orchestrator) commitBatch(ctx context.Context, m *Manifest, batch []*preparedFile, report *IndexReport) {
+	err := o.commitFiles(ctx, m, batch)
+	if err == nil {
+		report.Indexed += len(batch)
+		return
+	}
+	if len(batch) == 1 || ctx.Err() != nil {
+		for _, f := range batch {
+			report.fail(f.path, err)
+		}
+		return
+	}
+	for _, f := range batch {
+		if err := o.commitFiles(ctx, m, []*preparedFile{f}); err != nil {
+			report.fail(f.path, err)
+		} else {
+			report.Indexed++
+		}
+	}
+}
+
+// commitFiles replaces the documents previously indexed for files with their new
+// documents and records them in the manifest.
+func (o *Orchestrator) commitFiles(ctx context.Context, m *Manifest, files []*preparedFile) error {
+	var docs []chromem.Document
+	for _, f := range files {
+		if old, ok := m.Get(f.path); ok {
+			if err := o.store.DeleteDocuments(ctx, old.SymbolIDs...); err != nil {
+				return err
+			}
+			m.Remove(f.path)
+		}
+		docs = append(docs, f.docs...)
+	}
+
+	if err := o.store.AddDocuments(ctx, docs); err != nil {
+		// Part of the batch may have been stored. Keep track of the IDs so the next
+		// run cleans them up, but leave the hash empty so the files are retried.
+		for _, f := range files {
+			m.Set(f.path, &ManifestEntry{SymbolIDs: f.ids()})
+		}
+		return err
+	}
+
+	for _, f := range files {
+		m.Set(f.path, &ManifestEntry{Hash: f.hash, SymbolIDs: f.ids()})
+	}
+	return nil
+}
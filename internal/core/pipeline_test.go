@@ -0,0 +1,77 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"archon/internal/adapters/parser"
+)
+
+func TestChunkSymbol(t *testing.T) {
+	// line is a line of exactly n bytes including its newline
+	line := func(n int) string { return strings.Repeat("x", n-1) + "\n" }
+
+	type lines struct{ start, end, size int }
+	tests := []struct {
+		name string
+		code string
+		want []lines
+	}{
+		{
+			name: "small symbol is kept whole",
+			code: line(10) + line(10),
+			want: []lines{{10, 11, 20}},
+		},
+		{
+			name: "split on line boundaries",
+			code: strings.Repeat(line(2000), 4),
+			want: []lines{{10, 12, 6000}, {13, 13, 2000}},
+		},
+		{
+			name: "a line that does not fit starts the next chunk",
+			code: line(4000) + line(4000) + line(100),
+			want: []lines{{10, 10, 4000}, {11, 12, 4100}},
+		},
+		{
+			name: "one huge line is cut",
+			code: line(100) + line(13000) + line(100),
+			want: []lines{{10, 10, 100}, {11, 11, 6000}, {11, 11, 6000}, {11, 12, 1100}},
+		},
+		{
+			name: "huge last line without a newline",
+			code: line(100) + strings.Repeat("y", 12000),
+			want: []lines{{10, 10, 100}, {11, 11, 6000}, {11, 11, 6000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sym := parser.Symbol{Name: "F", Code: tt.code, StartLine: 10, EndLine: 9 + len(strings.SplitAfter(strings.TrimSuffix(tt.code, "\n"), "\n")), StartColumn: 1, EndColumn: 5}
+			chunks := chunkSymbol(sym)
+
+			var got []lines
+			var code strings.Builder
+			for _, c := range chunks {
+				got = append(got, lines{c.StartLine, c.EndLine, len(c.Code)})
+				code.WriteString(c.Code)
+				if len(c.Code) > maxChunkChars {
+					t.Errorf("chunk of %d bytes exceeds %d", len(c.Code), maxChunkChars)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("chunks (start, end, size) = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chunks (start, end, size) = %v, want %v", got, tt.want)
+					break
+				}
+			}
+			if code.String() != tt.code {
+				t.Error("chunks do not add up to the symbol's code")
+			}
+			if len(chunks) > 1 && (chunks[0].StartColumn != 0 || chunks[0].EndColumn != 0) {
+				t.Errorf("split chunks keep columns %d-%d of the whole symbol", chunks[0].StartColumn, chunks[0].EndColumn)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"archon/internal/utils"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severities of a review finding, from least to most severe.
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRanks = map[string]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// SeverityRank orders severities; unknown ones rank 0.
+func SeverityRank(severity string) int {
+	return severityRanks[severity]
+}
+
+// ParseSeverity accepts a severity name, case-insensitively.
+func ParseSeverity(name string) (string, bool) {
+	s := strings.ToLower(strings.TrimSpace(name))
+	_, ok := severityRanks[s]
+	return s, ok
+}
+
+// normalizeSeverity maps what a model may answer onto the known severities.
+func normalizeSeverity(name string) string {
+	if s, ok := ParseSeverity(name); ok {
+		return s
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "blocker", "severe":
+		return SeverityCritical
+	case "error", "major":
+		return SeverityHigh
+	case "minor", "note", "nit", "suggestion":
+		return SeverityLow
+	case "information", "informational":
+		return SeverityInfo
+	}
+	return SeverityMedium
+}
+
+// Finding is one issue of a code review, located in the new version of a file.
+type Finding struct {
+	File       string `json:"file"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Review is the structured answer of a code review.
+type Review struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+}
+
+// ReviewFormat describes the JSON the model must answer a review with, for prompts.
+const ReviewFormat = `Answer with ONLY a JSON object in a single Markdown code block (` + "```json" + `), in this format:
+{
+  "summary": "overall assessment of the changes in a few sentences",
+  "findings": [
+    {
+      "file": "path/of/the/file.go",
+      "start_line": 42,
+      "end_line": 45,
+      "severity": "critical | high | medium | low | info",
+      "category": "bug | security | performance | error-handling | concurrency | maintainability | style | testing | docs",
+      "message": "what is wrong and why it matters",
+      "suggestion": "the concrete fix, as code if possible"
+    }
+  ]
+}
+Line numbers are the numbers in front of the diff lines, i.e. lines of the new version of the file, and must be within the changed hunks. Only report real issues; an empty "findings" list is fine.`
+
+// ParseReview reads a structured review from a model response.
+func ParseReview(text string) (*Review, error) {
+	data, err := extractJSON(text)
+	if err != nil {
+		return nil, fmt.Errorf("no JSON review in the response")
+	}
+	var review Review
+	if err := json.Unmarshal([]byte(data), &review); err != nil {
+		return nil, fmt.Errorf("invalid review: %w", err)
+	}
+	for i := range review.Findings {
+		f := &review.Findings[i]
+		f.File = filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(f.File, "b/"), "./"))
+		f.Severity = normalizeSeverity(f.Severity)
+		f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+		if f.Category == "" {
+			f.Category = "general"
+		}
+		if f.EndLine < f.StartLine {
+			f.EndLine = f.StartLine
+		}
+	}
+	return &review, nil
+}
+
+// ValidateFindings keeps the findings that point into the changed lines of a
+// diff, as given by utils.ChangedRanges, and returns the others separately.
+// Findings are sorted by file, line and severity.
+func ValidateFindings(findings []Finding, changed map[string][]utils.LineRange) (valid, dropped []Finding) {
+	for _, f := range findings {
+		if f.StartLine <= 0 || strings.TrimSpace(f.Message) == "" {
+			dropped = append(dropped, f)
+			continue
+		}
+		lines := utils.LineRange{Start: f.StartLine, End: f.EndLine}
+		ok := false
+		for _, r := range changed[f.File] {
+			if r.Overlaps(lines) {
+				ok = true
+				break
+			}
+		}
+		if ok {
+			valid = append(valid, f)
+		} else {
+			dropped = append(dropped, f)
+		}
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		a, b := valid[i], valid[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return SeverityRank(a.Severity) > SeverityRank(b.Severity)
+	})
+	return valid, dropped
+}
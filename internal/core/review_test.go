@@ -0,0 +1,179 @@
+package core
+
+import (
+	"archon/internal/utils"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseReview(t *testing.T) {
+	text := "Here is my review:\n```json\n" + `{
+  "summary": "Mostly fine.",
+  "findings": [
+    {"file": "b/internal/a.go", "start_line": 7, "end_line": 3, "severity": "Major", "category": " Bug ", "message": "nil map"},
+    {"file": "./b.go", "start_line": 2, "end_line": 4, "severity": "nit", "message": "naming"},
+    {"file": "c.go", "start_line": 1, "severity": "whatever", "category": "style", "message": "x"}
+  ]
+}` + "\n```\nHope this helps."
+
+	review, err := ParseReview(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{File: "internal/a.go", StartLine: 7, EndLine: 7, Severity: SeverityHigh, Category: "bug", Message: "nil map"},
+		{File: "b.go", StartLine: 2, EndLine: 4, Severity: SeverityLow, Category: "general", Message: "naming"},
+		{File: "c.go", StartLine: 1, EndLine: 1, Severity: SeverityMedium, Category: "style", Message: "x"},
+	}
+	if review.Summary != "Mostly fine." || !reflect.DeepEqual(review.Findings, want) {
+		t.Errorf("ParseReview = %+v, want %+v", review, want)
+	}
+
+	for _, bad := range []string{"no json here", "```json\n{\"findings\": 3}\n```"} {
+		if _, err := ParseReview(bad); err == nil {
+			t.Errorf("ParseReview(%q) should fail", bad)
+		}
+	}
+}
+
+func TestValidateFindings(t *testing.T) {
+	changed := map[string][]utils.LineRange{
+		"a.go": {{Start: 10, End: 20}, {Start: 40, End: 40}},
+		"b.go": {{Start: 1, End: 5}},
+	}
+	finding := func(file string, start, end int, severity string) Finding {
+		return Finding{File: file, StartLine: start, EndLine: end, Severity: severity, Message: "m"}
+	}
+
+	tests := []struct {
+		name  string
+		f     Finding
+		valid bool
+	}{
+		{"inside a hunk", finding("a.go", 12, 12, SeverityLow), true},
+		{"first line of a hunk", finding("a.go", 10, 10, SeverityLow), true},
+		{"last line of a hunk", finding("a.go", 40, 40, SeverityLow), true},
+		{"overlaps the start", finding("a.go", 5, 10, SeverityLow), true},
+		{"overlaps the end", finding("a.go", 20, 25, SeverityLow), true},
+		{"just before", finding("a.go", 9, 9, SeverityLow), false},
+		{"just after", finding("a.go", 21, 21, SeverityLow), false},
+		{"between hunks", finding("a.go", 30, 35, SeverityLow), false},
+		{"unchanged file", finding("c.go", 1, 1, SeverityLow), false},
+		{"no line", finding("b.go", 0, 0, SeverityLow), false},
+		{"no message", Finding{File: "b.go", StartLine: 1, EndLine: 1, Message: "  "}, false},
+	}
+	for _, tt := range tests {
+		valid, dropped := ValidateFindings([]Finding{tt.f}, changed)
+		if got := len(valid) == 1; got != tt.valid || len(valid)+len(dropped) != 1 {
+			t.Errorf("%s: valid %v, dropped %v, want valid %v", tt.name, valid, dropped, tt.valid)
+		}
+	}
+
+	// Sorted by file, line and then severity, most severe first
+	valid, _ := ValidateFindings([]Finding{
+		finding("b.go", 2, 2, SeverityLow),
+		finding("a.go", 15, 15, SeverityLow),
+		finding("a.go", 15, 15, SeverityCritical),
+		finding("a.go", 11, 11, SeverityInfo),
+	}, changed)
+	want := []Finding{
+		finding("a.go", 11, 11, SeverityInfo),
+		finding("a.go", 15, 15, SeverityCritical),
+		finding("a.go", 15, 15, SeverityLow),
+		finding("b.go", 2, 2, SeverityLow),
+	}
+	if !reflect.DeepEqual(valid, want) {
+		t.Errorf("order = %+v, want %+v", valid, want)
+	}
+}
+
+func TestSeverities(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"HIGH", SeverityHigh, true},
+		{" info ", SeverityInfo, true},
+		{"error", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSeverity(tt.name)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseSeverity(%q) = %q, %v", tt.name, got, ok)
+		}
+	}
+	if SeverityRank(SeverityCritical) <= SeverityRank(SeverityHigh) || SeverityRank("unknown") != 0 {
+		t.Error("severities are not ranked")
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	findings := []Finding{
+		{File: "a.go", StartLine: 3, EndLine: 5, Severity: SeverityHigh, Category: "security", Message: "injection", Suggestion: "quote it"},
+		{File: "dir/b.go", StartLine: 1, EndLine: 1, Severity: SeverityMedium, Category: "bug", Message: "off by one"},
+		{File: "a.go", StartLine: 9, EndLine: 9, Severity: SeverityInfo, Category: "bug", Message: "note"},
+	}
+	data, err := SARIF(findings, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("log = %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "archon" || run.Tool.Driver.Version != "1.2.3" {
+		t.Errorf("driver = %+v", run.Tool.Driver)
+	}
+	var rules []string
+	for _, r := range run.Tool.Driver.Rules {
+		rules = append(rules, r.ID)
+	}
+	if !reflect.DeepEqual(rules, []string{"bug", "security"}) {
+		t.Errorf("rules = %v, want one per category, sorted", rules)
+	}
+
+	tests := []struct {
+		rule    string
+		index   int
+		level   string
+		message string
+		uri     string
+		region  sarifRegion
+	}{
+		{"security", 1, "error", "injection\n\nSuggested fix:\nquote it", "a.go", sarifRegion{3, 5}},
+		{"bug", 0, "warning", "off by one", "dir/b.go", sarifRegion{1, 1}},
+		{"bug", 0, "note", "note", "a.go", sarifRegion{9, 9}},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(tests))
+	}
+	for i, tt := range tests {
+		r := run.Results[i]
+		loc := r.Locations[0].PhysicalLocation
+		if r.RuleID != tt.rule || r.RuleIndex != tt.index || r.Level != tt.level || r.Message.Text != tt.message ||
+			loc.ArtifactLocation.URI != tt.uri || loc.ArtifactLocation.URIBaseID != "%SRCROOT%" || loc.Region != tt.region {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+
+	// No findings is still a valid log, with empty lists rather than null
+	data, err = SARIF(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var empty map[string]any
+	if err := json.Unmarshal(data, &empty); err != nil {
+		t.Fatal(err)
+	}
+	run0 := empty["runs"].([]any)[0].(map[string]any)
+	if results, ok := run0["results"].([]any); !ok || len(results) != 0 {
+		t.Errorf("results of an empty log = %v, want []", run0["results"])
+	}
+}
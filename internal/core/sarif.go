@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// SARIF 2.1.0 log, with only the parts used for review findings.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// sarifLevel maps a severity onto the levels of SARIF.
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	}
+	return "note"
+}
+
+// SARIF renders review findings as a SARIF 2.1.0 log, with one rule per
+// category. Paths are relative to the project root (%SRCROOT%).
+func SARIF(findings []Finding, version string) ([]byte, error) {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if !seen[f.Category] {
+			seen[f.Category] = true
+			categories = append(categories, f.Category)
+		}
+	}
+	sort.Strings(categories)
+
+	driver := sarifDriver{
+		Name:           "archon",
+		Version:        version,
+		InformationURI: "https://github.com/rexreus/archon",
+		Rules:          []sarifRule{},
+	}
+	index := make(map[string]int)
+	for i, c := range categories {
+		index[c] = i
+		driver.Rules = append(driver.Rules, sarifRule{ID: c, ShortDescription: sarifMessage{Text: "Archon review: " + c}})
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		text := f.Message
+		if f.Suggestion != "" {
+			text += "\n\nSuggested fix:\n" + f.Suggestion
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.Category,
+			RuleIndex: index[f.Category],
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: text},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: f.File, URIBaseID: "%SRCROOT%"},
+				Region:           sarifRegion{StartLine: f.StartLine, EndLine: f.EndLine},
+			}}},
+			Properties: map[string]any{"severity": f.Severity},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/adapters/vectordb"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// SearchOptions scopes retrieval to a path, language, symbol type, etc.
+type SearchOptions = vectordb.SearchOptions
+
+// DefaultSearchBlend weighs vector and keyword rankings equally.
+const DefaultSearchBlend = 0.5
+
+const (
+	// rrfK dampens the advantage of the very first ranks in reciprocal rank
+	// fusion; 60 is the value from the original paper.
+	rrfK = 60
+	// rrfDepth is how many candidates per requested result each search returns.
+	rrfDepth = 4
+)
+
+// Snippet is a retrieved piece of code together with where it lives.
+type Snippet struct {
+	ID          string
+	File        string
+	Name        string
+	Type        string
+	Parent      string
+	Signature   string
+	Language    string
+	StartLine   int
+	EndLine     int
+	StartColumn int
+	EndColumn   int
+	Content     string
+	Score       float32
+	// SymbolID, Chunk and Chunks are set when the snippet is one chunk of a symbol
+	// that was split for indexing.
+	SymbolID string
+	Chunk    int
+	Chunks   int
+}
+
+// Citation returns the clickable file:line location of the snippet.
+func (s Snippet) Citation() string {
+	if s.StartLine == 0 {
+		return s.File
+	}
+	return fmt.Sprintf("%s:%d", s.File, s.StartLine)
+}
+
+// symbolMetadata is the document metadata persisted for a symbol.
+func symbolMetadata(path string, sym parser.Symbol) map[string]string {
+	metadata := map[string]string{
+		"file":       path,
+		"name":       sym.Name,
+		"type":       sym.Type,
+		"language":   string(sym.Language),
+		"start_line": strconv.Itoa(sym.StartLine),
+		"end_line":   strconv.Itoa(sym.EndLine),
+		"start_col":  strconv.Itoa(sym.StartColumn),
+		"end_col":    strconv.Itoa(sym.EndColumn),
+	}
+	if sym.Parent != "" {
+		metadata["parent"] = sym.Parent
+	}
+	if sym.Signature != "" {
+		metadata["signature"] = sym.Signature
+	}
+	if sym.Doc != "" {
+		metadata["doc"] = sym.Doc
+	}
+	return metadata
+}
+
+func snippetFromResult(res chromem.Result) Snippet {
+	snip := snippetFromDocument(res.ID, res.Content, res.Metadata)
+	snip.Score = res.Similarity
+	return snip
+}
+
+func snippetFromDocument(id, content string, metadata map[string]string) Snippet {
+	atoi := func(key string) int {
+		n, _ := strconv.Atoi(metadata[key])
+		return n
+	}
+	snip := Snippet{
+		ID:          id,
+		File:        metadata["file"],
+		Name:        metadata["name"],
+		Type:        metadata["type"],
+		Parent:      metadata["parent"],
+		Signature:   metadata["signature"],
+		Language:    metadata["language"],
+		StartLine:   atoi("start_line"),
+		EndLine:     atoi("end_line"),
+		StartColumn: atoi("start_col"),
+		EndColumn:   atoi("end_col"),
+		Content:     content,
+		SymbolID:    metadata["symbol_id"],
+	}
+	// "chunk" is "i/n"
+	if i, n, ok := strings.Cut(metadata["chunk"], "/"); ok {
+		snip.Chunk, _ = strconv.Atoi(i)
+		snip.Chunks, _ = strconv.Atoi(n)
+	}
+	return snip
+}
+
+type rankedList struct {
+	results []chromem.Result
+	weight  float64
+}
+
+// fuseRankings merges ranked result lists with weighted reciprocal rank fusion,
+// scoring each document sum(weight / (rrfK + rank)). Only ranks are used, so
+// cosine similarities and BM25 scores never need to be compared directly.
+func fuseRankings(lists []rankedList, n int) []Snippet {
+	scores := make(map[string]float64)
+	byID := make(map[string]chromem.Result)
+	var order []string
+	for _, list := range lists {
+		for rank, res := range list.results {
+			if _, ok := byID[res.ID]; !ok {
+				byID[res.ID] = res
+				order = append(order, res.ID)
+			}
+			scores[res.ID] += list.weight / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+
+	snippets := make([]Snippet, 0, len(order))
+	for _, id := range order {
+		snip := snippetFromResult(byID[id])
+		snip.Score = float32(scores[id])
+		snippets = append(snippets, snip)
+	}
+	return snippets
+}
+
+// FormatContext renders snippets as a prompt context block, each headed by its file:line citation.
+func FormatContext(snippets []Snippet) string {
+	if len(snippets) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(contextHeader)
+	for _, snip := range snippets {
+		sb.WriteString(formatSnippet(snip))
+	}
+	return sb.String()
+}
+
+const contextHeader = "Here are some relevant code snippets from the codebase. Cite them as file:line when you refer to them:\n\n"
+
+func formatSnippet(snip Snippet) string {
+	var sb strings.Builder
+	sb.WriteString("File: " + snip.Citation())
+	if snip.EndLine > snip.StartLine {
+		sb.WriteString(fmt.Sprintf(" (lines %d-%d)", snip.StartLine, snip.EndLine))
+	}
+	sb.WriteString("\n")
+	if snip.Name != "" {
+		sb.WriteString("Symbol: " + qualifiedName(snip) + " (" + snip.Type + ")\n")
+	}
+	sb.WriteString("```" + snip.Language + "\n" + snip.Content + "\n```\n\n")
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func results(ids ...string) []chromem.Result {
+	res := make([]chromem.Result, len(ids))
+	for i, id := range ids {
+		res[i] = chromem.Result{ID: id, Metadata: map[string]string{"file": id + ".go"}}
+	}
+	return res
+}
+
+func TestFuseRankings(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists []rankedList
+		n     int
+		want  []string
+	}{
+		{
+			name:  "single list keeps its order",
+			lists: []rankedList{{results("a", "b", "c"), 1}},
+			n:     5,
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "found by both searches ranks first",
+			lists: []rankedList{{results("a", "b"), 0.5}, {results("c", "b"), 0.5}},
+			n:     5,
+			want:  []string{"b", "a", "c"},
+		},
+		{
+			name:  "weight favours one search",
+			lists: []rankedList{{results("a"), 0.2}, {results("k"), 0.8}},
+			n:     5,
+			want:  []string{"k", "a"},
+		},
+		{
+			name:  "ties keep first-seen order",
+			lists: []rankedList{{results("a"), 0.5}, {results("k"), 0.5}},
+			n:     5,
+			want:  []string{"a", "k"},
+		},
+		{
+			name:  "truncated to n",
+			lists: []rankedList{{results("a", "b", "c"), 0.5}, {results("d", "e"), 0.5}},
+			n:     2,
+			want:  []string{"a", "d"},
+		},
+		{
+			name:  "zero weight list only adds candidates",
+			lists: []rankedList{{results("a", "b"), 1}, {results("c"), 0}},
+			n:     5,
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name: "no results",
+			n:    5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range fuseRankings(tt.lists, tt.n) {
+				got = append(got, s.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fuseRankings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseRankingsScore(t *testing.T) {
+	got := fuseRankings([]rankedList{{results("a", "b"), 0.5}, {results("b"), 0.5}}, 5)
+	want := float32(0.5/float64(rrfK+2) + 0.5/float64(rrfK+1))
+	if got[0].ID != "b" || got[0].Score != want {
+		t.Errorf("top result = %s with score %v, want b with %v", got[0].ID, got[0].Score, want)
+	}
+	if got[0].File != "b.go" {
+		t.Errorf("File = %q, want the metadata of the result", got[0].File)
+	}
+}
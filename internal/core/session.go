@@ -0,0 +1,278 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSessionDir is where chat sessions are stored, relative to the project root.
+const DefaultSessionDir = ".archon/sessions"
+
+// DefaultChatWindow is the number of history tokens sent verbatim before the
+// oldest turns are summarized.
+const DefaultChatWindow = 32000
+
+// keepTurns is the number of most recent turns that are never summarized.
+const keepTurns = 4
+
+// Turn is one message of a session.
+type Turn struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Session is a multi-turn conversation. Turns holds the whole conversation for
+// display; the model gets Summary in place of the first Summarized turns.
+type Session struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	Summary    string    `json:"summary,omitempty"`
+	Summarized int       `json:"summarized"`
+	Turns      []Turn    `json:"turns"`
+	Tokens     int       `json:"tokens"`
+}
+
+func NewSession() *Session {
+	now := time.Now()
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return &Session{
+		ID:      now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Created: now,
+		Updated: now,
+	}
+}
+
+// Messages returns the history sent to the model: the summary of older turns,
+// if any, followed by the turns that were not summarized.
+func (s *Session) Messages() []Message {
+	var messages []Message
+	if s.Summary != "" {
+		messages = append(messages,
+			Message{Role: RoleUser, Content: "Summary of our conversation so far:\n" + s.Summary},
+			Message{Role: RoleAssistant, Content: "Understood, I will keep this in mind."},
+		)
+	}
+	for _, turn := range s.Turns[s.Summarized:] {
+		messages = append(messages, Message{Role: turn.Role, Content: turn.Content})
+	}
+	return messages
+}
+
+func (s *Session) historyTokens(from int) int {
+	tokens := EstimateTokens(s.Summary)
+	for _, turn := range s.Turns[from:] {
+		tokens += EstimateTokens(turn.Content)
+	}
+	return tokens
+}
+
+// Compact summarizes the oldest turns once the history exceeds window tokens,
+// until it fits in half of the window. The last keepTurns turns are always
+// kept verbatim. It reports whether a summary was made.
+func (s *Session) Compact(ctx context.Context, llm LLM, window int) (bool, error) {
+	if window <= 0 {
+		window = DefaultChatWindow
+	}
+	if s.historyTokens(s.Summarized) <= window {
+		return false, nil
+	}
+
+	// Turns are added in user/assistant pairs, fold them two at a time
+	end := s.Summarized
+	for end+2 <= len(s.Turns)-keepTurns && s.historyTokens(end) > window/2 {
+		end += 2
+	}
+	if end == s.Summarized {
+		return false, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Summarize the conversation below between a developer and an AI assistant about their codebase. ")
+	b.WriteString("Keep every fact, decision, file and symbol name that later questions may refer to. Be concise.\n\n")
+	if s.Summary != "" {
+		fmt.Fprintf(&b, "Earlier summary:\n%s\n\n", s.Summary)
+	}
+	b.WriteString("Conversation:\n")
+	for _, turn := range s.Turns[s.Summarized:end] {
+		name := "User"
+		if turn.Role == RoleAssistant {
+			name = "Assistant"
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", name, turn.Content)
+	}
+
+	resp, err := llm.Generate(ctx, b.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to summarize session: %w", err)
+	}
+	s.Summary = strings.TrimSpace(resp.Text)
+	s.Summarized = end
+	s.Tokens += resp.TotalTokens
+	return true, nil
+}
+
+// Ask answers question within the conversation. prompt is what is actually sent
+// for it (typically the question with retrieved code context); only question is
+// kept in the history. Events are delivered like LLM.ChatStream, and the turns
+// are added to the session before the final Response event. The session must
+// not be used by anything else until the channel is closed.
+func (s *Session) Ask(ctx context.Context, llm LLM, question, prompt string, window int) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		send := func(ev StreamEvent) {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+			}
+		}
+
+		if _, err := s.Compact(ctx, llm, window); err != nil {
+			send(StreamEvent{Err: err})
+			return
+		}
+
+		messages := append(s.Messages(), Message{Role: RoleUser, Content: prompt})
+		var resp *Response
+		for ev := range llm.ChatStream(ctx, messages) {
+			if ev.Response != nil {
+				resp = ev.Response
+				continue
+			}
+			send(ev)
+		}
+		if resp == nil {
+			return
+		}
+
+		now := time.Now()
+		s.Turns = append(s.Turns,
+			Turn{Role: RoleUser, Content: question, Time: now},
+			Turn{Role: RoleAssistant, Content: resp.Text, Time: now},
+		)
+		if s.Title == "" {
+			s.Title = sessionTitle(question)
+		}
+		s.Updated = now
+		s.Tokens += resp.TotalTokens
+		send(StreamEvent{Response: resp})
+	}()
+	return events
+}
+
+func sessionTitle(question string) string {
+	title := strings.Join(strings.Fields(question), " ")
+	if r := []rune(title); len(r) > 60 {
+		title = string(r[:57]) + "..."
+	}
+	return title
+}
+
+// SessionStore keeps sessions as JSON files in a directory.
+type SessionStore struct {
+	dir string
+}
+
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{dir: dir}
+}
+
+func (st *SessionStore) path(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+func (st *SessionStore) Save(s *Session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp := st.path(s.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return os.Rename(tmp, st.path(s.ID))
+}
+
+// Load reads the session with the given ID, or the only one starting with it.
+func (st *SessionStore) Load(id string) (*Session, error) {
+	if _, err := os.Stat(st.path(id)); err != nil {
+		sessions, err := st.List()
+		if err != nil {
+			return nil, err
+		}
+		var matches []string
+		for _, s := range sessions {
+			if strings.HasPrefix(s.ID, id) {
+				matches = append(matches, s.ID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("session %q not found", id)
+		case 1:
+			id = matches[0]
+		default:
+			return nil, fmt.Errorf("session %q is ambiguous: %s", id, strings.Join(matches, ", "))
+		}
+	}
+
+	data, err := os.ReadFile(st.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid session %s: %w", id, err)
+	}
+	if s.Summarized > len(s.Turns) {
+		s.Summarized = len(s.Turns)
+	}
+	return &s, nil
+}
+
+// List returns all sessions, most recently updated first. Unreadable files are skipped.
+func (st *SessionStore) List() ([]*Session, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(st.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(data, &s); err != nil || s.ID == "" {
+			continue
+		}
+		sessions = append(sessions, &s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
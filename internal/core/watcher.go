@@ -0,0 +1,200 @@
+package core
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+type WatchEventType string
+
+const (
+	WatchStarted WatchEventType = "started"
+	WatchIndexed WatchEventType = "indexed"
+	WatchRemoved WatchEventType = "removed"
+	WatchError   WatchEventType = "error"
+)
+
+// WatchEvent reports what the watcher did. Err is set for WatchError events.
+type WatchEvent struct {
+	Type WatchEventType
+	Path string
+	Err  error
+	Time time.Time
+}
+
+type WatchOptions struct {
+	// Debounce is how long a file must stay quiet before it is re-indexed, so a
+	// burst of saves (editors, formatters, git checkouts) is indexed once.
+	Debounce time.Duration
+	// OnEvent is called from the watcher goroutine for every watch event.
+	OnEvent func(WatchEvent)
+}
+
+// WatchDirectory keeps the index in sync with dir until ctx is cancelled:
+// changed files are re-indexed and deleted or renamed files are purged.
+func (o *Orchestrator) WatchDirectory(ctx context.Context, dir string, opts WatchOptions) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultWatchDebounce
+	}
+	emit := func(t WatchEventType, path string, err error) {
+		if opts.OnEvent != nil {
+			opts.OnEvent(WatchEvent{Type: t, Path: path, Err: err, Time: time.Now()})
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	addTree := func(root string) {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if utils.IsIgnored(path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if err := watcher.Add(path); err != nil {
+					emit(WatchError, path, err)
+				}
+			}
+			return nil
+		})
+	}
+	addTree(dir)
+
+	// Every event (re)starts a per-file timer; when it fires the file's current
+	// state on disk decides whether it is indexed or purged.
+	var mu sync.Mutex
+	timers := make(map[string]*time.Timer)
+	ready := make(chan string)
+	defer func() {
+		mu.Lock()
+		for _, t := range timers {
+			t.Stop()
+		}
+		mu.Unlock()
+	}()
+
+	schedule := func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if t, ok := timers[path]; ok {
+			t.Stop()
+		}
+		timers[path] = time.AfterFunc(opts.Debounce, func() {
+			select {
+			case ready <- path:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	emit(WatchStarted, dir, nil)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if utils.IsIgnoreFile(event.Name) {
+				// New ignore rules apply to the next events; `archon index` purges newly ignored files
+				utils.ResetIgnoreCache()
+			}
+			if utils.IsIgnored(event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// New or moved-in directory: watch it and index what it already contains
+					addTree(event.Name)
+					files, _ := o.GetFilesForIndexing(event.Name)
+					for _, f := range files {
+						schedule(f)
+					}
+					continue
+				}
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				schedule(event.Name)
+			}
+
+		case path := <-ready:
+			mu.Lock()
+			delete(timers, path)
+			mu.Unlock()
+			o.syncPath(ctx, path, emit)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			emit(WatchError, "", err)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// syncPath brings the index in line with path's current state on disk.
+func (o *Orchestrator) syncPath(ctx context.Context, path string, emit func(WatchEventType, string, error)) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			emit(WatchError, path, err)
+			return
+		}
+		// Deleted or renamed away. A vanished directory takes all its files with it.
+		m, err := o.loadManifest(ctx)
+		if err != nil {
+			emit(WatchError, path, err)
+			return
+		}
+		for _, indexed := range m.Paths() {
+			if manifestKey(indexed) == manifestKey(path) || isWithin(path, indexed) {
+				if err := o.RemoveFile(ctx, indexed); err != nil {
+					emit(WatchError, indexed, err)
+				} else {
+					emit(WatchRemoved, indexed, nil)
+				}
+			}
+		}
+		return
+	}
+
+	if info.IsDir() || parser.DetectLanguage(path) == parser.Unknown {
+		return
+	}
+	m, err := o.loadManifest(ctx)
+	if err != nil {
+		emit(WatchError, path, err)
+		return
+	}
+	skipped, err := o.indexFile(ctx, m, path)
+	if saveErr := o.save(m); err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		emit(WatchError, path, err)
+		return
+	}
+	if !skipped {
+		emit(WatchIndexed, path, nil)
+	}
+}
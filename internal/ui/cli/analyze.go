@@ -1,13 +1,11 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -20,38 +18,44 @@ var analyzeCmd = &cobra.Command{
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			newReport("analyze", nil).fail("Error", err)
 		}
+		result := newReport("analyze", cfg)
 
 		ctx := context.Background()
-		store, _ := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, _ := provider.NewStore(ctx, cfg)
 		var contextText string
 		if store != nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
-			contextText, _ = orchestrator.SearchContext(ctx, "architectural overview and anomalies")
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			if res, err := searchContext(ctx, orchestrator, cfg, "analyze", "architectural overview and anomalies", core.SearchOptions{}); err == nil {
+				result.addContext(res)
+				contextText = res.Text
+			}
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			result.fail("Error", err)
 		}
 		defer client.Close()
 
 		prompt := fmt.Sprintf("%s\n\nTask: Perform a deep architectural analysis on this project. Detect anomalies, code smells, or design pattern violations. Depth: %s", 
 			contextText, depth)
 
-		fmt.Println("Analyzing architecture...")
-		resp, err := client.Ask(ctx, prompt)
+		logf("Analyzing architecture...\n")
+		resp, err := client.Generate(ctx, prompt)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			result.fail("Error", err)
 		}
+		result.addResponse(resp)
 
-		fmt.Printf("\nArchitectural Analysis:\n%s\n", resp.Text)
-		fmt.Printf("\n(Tokens used: %d)\n", resp.TotalTokens)
+		if !structuredOutput() {
+			fmt.Printf("\nArchitectural Analysis:\n%s\n", resp.Text)
+			fmt.Printf("\n(Tokens used: %d)\n", resp.TotalTokens)
+		}
+		result.emit()
 	},
 }
 
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"archon/internal/utils"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// previewChanges prints the unified diff of every change and reports whether there is any.
+func previewChanges(changes []core.FileChange) bool {
+	changed := false
+	for _, change := range changes {
+		oldName := "a/" + filepath.ToSlash(change.Path)
+		if !change.Existed {
+			oldName = "/dev/null"
+		}
+		diff := utils.UnifiedDiff(oldName, "b/"+filepath.ToSlash(change.Path), string(change.Old), string(change.New))
+		if diff != "" {
+			fmt.Print(diff)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// confirmApply asks whether to apply the previewed changes, unless yes is set.
+func confirmApply(yes bool) bool {
+	if yes {
+		return true
+	}
+	fmt.Print("\nApply these changes? (y/n): ")
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// checkUnchanged fails if a file was changed on disk since its Old contents were read.
+func checkUnchanged(changes []core.FileChange) error {
+	for _, change := range changes {
+		current, err := os.ReadFile(change.Path)
+		switch {
+		case err == nil && !change.Existed:
+			return fmt.Errorf("%s was created since the prompt was built, run the command again", change.Path)
+		case err != nil && change.Existed:
+			return fmt.Errorf("%s is no longer readable: %w", change.Path, err)
+		case err == nil && !bytes.Equal(current, change.Old):
+			return fmt.Errorf("%s changed on disk since the prompt was built, run the command again", change.Path)
+		}
+	}
+	return nil
+}
+
+// applyChanges writes changes after recording the originals in the backup
+// journal, so that `archon undo` can revert them. If a write fails, the files
+// already written are restored.
+func applyChanges(command string, changes []core.FileChange) (*core.BackupEntry, error) {
+	if err := checkUnchanged(changes); err != nil {
+		return nil, err
+	}
+
+	journal := core.NewBackupJournal(core.DefaultBackupDir)
+	entry, err := journal.Record(command, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		perm := os.FileMode(0644)
+		if info, err := os.Stat(change.Path); err == nil {
+			perm = info.Mode().Perm()
+		} else if err := os.MkdirAll(filepath.Dir(change.Path), 0755); err != nil {
+			return nil, rollback(journal, entry, fmt.Errorf("failed to write %s: %w", change.Path, err))
+		}
+		if err := os.WriteFile(change.Path, change.New, perm); err != nil {
+			return nil, rollback(journal, entry, fmt.Errorf("failed to write %s: %w", change.Path, err))
+		}
+	}
+	return entry, nil
+}
+
+// rollback restores the files of a partly applied entry and returns err, noting
+// if the rollback failed too.
+func rollback(journal *core.BackupJournal, entry *core.BackupEntry, err error) error {
+	if rerr := journal.Restore(entry); rerr != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+	}
+	return err
+}
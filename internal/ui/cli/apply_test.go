@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inTempDir runs the test in an empty directory, where the backup journal is kept.
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Chdir(root)
+	return root
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCheckUnchanged(t *testing.T) {
+	inTempDir(t)
+	write(t, "a.go", "a")
+	write(t, "b.go", "b")
+
+	tests := []struct {
+		name   string
+		change core.FileChange
+		err    string
+	}{
+		{"unchanged", core.FileChange{Path: "a.go", Existed: true, Old: []byte("a")}, ""},
+		{"new file still missing", core.FileChange{Path: "new.go"}, ""},
+		{"changed on disk", core.FileChange{Path: "b.go", Existed: true, Old: []byte("old b")}, "changed on disk"},
+		{"created since", core.FileChange{Path: "a.go"}, "was created"},
+		{"deleted since", core.FileChange{Path: "gone.go", Existed: true, Old: []byte("x")}, "no longer readable"},
+	}
+	for _, tt := range tests {
+		err := checkUnchanged([]core.FileChange{tt.change})
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestApplyChangesAndUndo(t *testing.T) {
+	inTempDir(t)
+	write(t, "a.go", "old a\n")
+	if err := os.Chmod("a.go", 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := applyChanges("refactor", []core.FileChange{
+		{Path: "a.go", Existed: true, Old: []byte("old a\n"), New: []byte("new a\n")},
+		{Path: filepath.Join("pkg", "b.go"), New: []byte("new b\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read(t, "a.go") != "new a\n" || read(t, filepath.Join("pkg", "b.go")) != "new b\n" {
+		t.Fatal("changes were not written")
+	}
+	if info, _ := os.Stat("a.go"); info.Mode().Perm() != 0o600 {
+		t.Errorf("a.go mode = %v, want its permissions kept", info.Mode().Perm())
+	}
+
+	journal := core.NewBackupJournal(core.DefaultBackupDir)
+	entries, _ := journal.List()
+	if len(entries) != 1 || entries[0].ID != entry.ID {
+		t.Fatalf("journal = %+v, want the applied edit", entries)
+	}
+	if err := journal.Restore(entries[0]); err != nil {
+		t.Fatal(err)
+	}
+	if read(t, "a.go") != "old a\n" {
+		t.Error("undo did not restore a.go")
+	}
+	if _, err := os.Stat(filepath.Join("pkg", "b.go")); !os.IsNotExist(err) {
+		t.Error("undo did not remove the created pkg/b.go")
+	}
+}
+
+func TestApplyChangesRollsBack(t *testing.T) {
+	inTempDir(t)
+	write(t, "a.go", "old a\n")
+
+	// a.go is a file, so a.go/c.go cannot be written
+	_, err := applyChanges("refactor", []core.FileChange{
+		{Path: "a.go", Existed: true, Old: []byte("old a\n"), New: []byte("new a\n")},
+		{Path: "b.go", New: []byte("new b\n")},
+		{Path: filepath.Join("a.go", "c.go"), New: []byte("new c\n")},
+	})
+	if err == nil {
+		t.Fatal("applyChanges succeeded")
+	}
+	if got := read(t, "a.go"); got != "old a\n" {
+		t.Errorf("a.go = %q after the rollback, want the original", got)
+	}
+	if _, err := os.Stat("b.go"); !os.IsNotExist(err) {
+		t.Error("b.go was left behind by the rollback")
+	}
+	if entries, _ := core.NewBackupJournal(core.DefaultBackupDir).List(); len(entries) != 0 {
+		t.Errorf("journal = %+v after the rollback, want it empty", entries)
+	}
+}
+
+func TestApplyChangesRefusesStaleChanges(t *testing.T) {
+	inTempDir(t)
+	write(t, "a.go", "edited meanwhile\n")
+
+	_, err := applyChanges("refactor", []core.FileChange{{Path: "a.go", Existed: true, Old: []byte("old a\n"), New: []byte("new a\n")}})
+	if err == nil {
+		t.Fatal("applyChanges overwrote a file changed since the prompt")
+	}
+	if got := read(t, "a.go"); got != "edited meanwhile\n" {
+		t.Errorf("a.go = %q, want it left alone", got)
+	}
+	if _, err := os.Stat(core.DefaultBackupDir); !os.IsNotExist(err) {
+		t.Error("a backup was recorded for a refused edit")
+	}
+}
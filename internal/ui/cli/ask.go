@@ -2,12 +2,11 @@ package cli
 
 import (
 	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,19 +22,25 @@ var askCmd = &cobra.Command{
 		ctx := context.Background()
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			fmt.Printf("Error loading config: %v\n", err)
-			os.Exit(1)
+			newReport("ask", nil).fail("Error loading config", err)
 		}
+		result := newReport("ask", cfg)
 
-		if cfg.GeminiKey == "" {
-			fmt.Println("Error: Gemini API key not found. Use 'archon auth' or set ARCHON_GEMINI_KEY environment variable.")
-			os.Exit(1)
+		opts, err := searchOptions(cmd)
+		if err != nil {
+			result.fail("Error", err)
 		}
 
+		client, err := provider.NewLLM(ctx, cfg)
+		if err != nil {
+			result.fail("Error", err)
+		}
+		defer client.Close()
+
 		// Initialize Vector DB and Orchestrator for RAG
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Warning: Vector DB not initialized. Asking without context. (%v)\n", err)
+			result.warn("Vector DB not initialized. Asking without context. (%v)", err)
 		} else {
 			defer store.Close()
 		}
@@ -43,39 +48,38 @@ var askCmd = &cobra.Command{
 		var prompt string
 		if store != nil {
 			orchestrator := core.NewOrchestrator(store)
-			fmt.Printf("Searching context...\n")
-			contextText, err := orchestrator.SearchContext(ctx, query)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			logf("Searching context...\n")
+			res, err := searchContext(ctx, orchestrator, cfg, "ask", query, opts)
 			if err != nil {
-				fmt.Printf("Error searching context: %v\n", err)
+				logf("Error searching context: %v\n", err)
+				result.Errors = append(result.Errors, "searching context: "+err.Error())
 				prompt = query
 			} else {
-				prompt = fmt.Sprintf("%s\n\nUser Question: %s", contextText, query)
+				result.addContext(res)
+				prompt = fmt.Sprintf("%s\n\nUser Question: %s", res.Text, query)
 			}
 		} else {
 			prompt = query
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
-		if err != nil {
-			fmt.Printf("Error creating Gemini client: %v\n", err)
-			os.Exit(1)
-		}
-		defer client.Close()
-
-		// Sync and Use Context Cache
+		// Sync and Use Context Cache (Gemini only)
+		geminiClient, isGemini := client.(*gemini.Client)
 		hash, err := gemini.CalculateProjectHash(".")
-		if err == nil {
+		if err == nil && isGemini {
 			if cfg.CacheName != "" && cfg.ProjectHash == hash {
-				client.SetCachedContent(cfg.CacheName)
+				geminiClient.SetCachedContent(cfg.CacheName)
+				result.CacheUsed = true
 			} else {
 				// Try to create new cache if possible
 				orchestrator := core.NewOrchestrator(store)
 				files, err := orchestrator.GetFilesForIndexing(".")
 				if err == nil && len(files) > 0 {
-					cm := gemini.NewCacheManager(client.Client())
+					cm := gemini.NewCacheManager(geminiClient.Client())
 					cacheName, err := cm.CreateContextCache(ctx, cfg.ModelID, files)
 					if err == nil {
-						client.SetCachedContent(cacheName)
+						geminiClient.SetCachedContent(cacheName)
+						result.CacheUsed = true
 						// Save to config
 						viper.Set("project_hash", hash)
 						viper.Set("cache_name", cacheName)
@@ -90,18 +94,18 @@ var askCmd = &cobra.Command{
 			}
 		}
 
-		fmt.Printf("Thinking...\n")
-		resp, err := client.Ask(ctx, prompt)
+		logf("Thinking...\n")
+		resp, err := result.answer(ctx, client, prompt, "\nResponse:\n")
 		if err != nil {
-			fmt.Printf("Error asking Gemini: %v\n", err)
-			os.Exit(1)
+			result.fail("Error asking "+cfg.Provider, err)
 		}
 
-		fmt.Printf("\nResponse:\n%s\n", resp.Text)
-		fmt.Printf("\n(Tokens used: %d)\n", resp.TotalTokens)
+		logf("\n(Tokens used: %d)\n", resp.TotalTokens)
+		result.emit()
 	},
 }
 
 func init() {
+	addSearchFlags(askCmd)
 	rootCmd.AddCommand(askCmd)
 }
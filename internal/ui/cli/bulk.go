@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/adapters/provider"
+	"archon/internal/config"
+	"archon/internal/core"
+	"archon/internal/utils"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// bulkMode reports whether the arguments of test/doc name anything but a single file.
+func bulkMode(all bool, args []string) bool {
+	if all || len(args) != 1 {
+		return true
+	}
+	info, err := os.Stat(args[0])
+	return err != nil || !info.Mode().IsRegular()
+}
+
+// resolveTargets expands the arguments of a bulk command into source files:
+// --all is the whole project, a directory is one package, "dir/..." is a
+// directory tree and anything else is a file or glob pattern. Ignored files
+// and test files are left out.
+func resolveTargets(args []string, all bool) ([]string, error) {
+	if all {
+		args = []string{"./..."}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if seen[path] || parser.DetectLanguage(path) == parser.Unknown || utils.IsTestFile(path) || utils.IsIgnored(path) {
+			return
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+
+	for _, arg := range args {
+		if dir, ok := strings.CutSuffix(filepath.ToSlash(arg), "/..."); ok {
+			if dir == "" || dir == "." {
+				dir = "."
+			}
+			err := filepath.WalkDir(filepath.FromSlash(dir), func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != "." && utils.IsIgnored(path) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !d.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if info, err := os.Stat(arg); err == nil {
+			if !info.IsDir() {
+				add(arg)
+				continue
+			}
+			entries, err := os.ReadDir(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					add(filepath.Join(arg, entry.Name()))
+				}
+			}
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		for _, match := range matches {
+			if utils.IsDir(match) {
+				continue
+			}
+			add(match)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+const (
+	bulkGenerated = "generated"
+	bulkSkipped   = "skipped"
+	bulkFailed    = "failed"
+)
+
+type bulkRow struct {
+	File   string
+	Status string
+	// Detail is the file written, the reason for skipping or the error.
+	Detail string
+	Tokens int
+}
+
+// bulkTask describes how a bulk command handles each file.
+type bulkTask struct {
+	// skip returns why file needs no generation, or "" to generate it.
+	skip func(file string) string
+	// generate produces the output for file, writes it and returns where it went.
+	generate func(ctx context.Context, file string) (string, *core.Response, error)
+}
+
+// runBulk generates output for every file that is not skipped, within the
+// batch_* limits of the configuration, and returns one row per file.
+func runBulk(ctx context.Context, cfg *config.Config, files []string, task bulkTask) []bulkRow {
+	var rows []bulkRow
+	var todo []string
+	for _, file := range files {
+		if reason := task.skip(file); reason != "" {
+			rows = append(rows, bulkRow{File: file, Status: bulkSkipped, Detail: reason})
+		} else {
+			todo = append(todo, file)
+		}
+	}
+
+	fmt.Printf("Generating for %d files (%d skipped)...\n", len(todo), len(rows))
+
+	var mu sync.Mutex
+	outputs := make(map[string]string)
+	results := provider.NewBatchProcessor(cfg).ProcessFiles(ctx, todo, func(ctx context.Context, file string) (*core.Response, error) {
+		output, resp, err := task.generate(ctx, file)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", file, err)
+			return resp, err
+		}
+		fmt.Printf("  ✓ %s -> %s\n", file, output)
+		mu.Lock()
+		outputs[file] = output
+		mu.Unlock()
+		return resp, nil
+	})
+
+	for _, file := range todo {
+		res := results[file]
+		row := bulkRow{File: file, Status: bulkGenerated, Detail: outputs[file]}
+		if res.Response != nil {
+			row.Tokens = res.Response.TotalTokens
+		}
+		if res.Err != nil {
+			row.Status, row.Detail = bulkFailed, res.Err.Error()
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].File < rows[j].File })
+	return rows
+}
+
+// printBulkSummary prints the table of rows and reports whether any file failed.
+func printBulkSummary(rows []bulkRow) bool {
+	counts := make(map[string]int)
+	tokens := 0
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSTATUS\tTOKENS\tDETAIL")
+	for _, row := range rows {
+		counts[row.Status]++
+		tokens += row.Tokens
+		detail := strings.ReplaceAll(row.Detail, "\n", " ")
+		if r := []rune(detail); len(r) > 80 {
+			detail = string(r[:77]) + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", row.File, row.Status, row.Tokens, detail)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d generated, %d skipped, %d failed (tokens used: %d)\n",
+		counts[bulkGenerated], counts[bulkSkipped], counts[bulkFailed], tokens)
+	return counts[bulkFailed] > 0
+}
+
+// fileContext retrieves the code context for a file quietly, for use in bulk runs.
+func fileContext(ctx context.Context, orchestrator *core.Orchestrator, cfg *config.Config, command, query string) string {
+	if orchestrator == nil {
+		return ""
+	}
+	res, err := orchestrator.SearchContext(ctx, query, core.ContextBudget(command, cfg.ContextBudget), core.SearchOptions{})
+	if err != nil {
+		return ""
+	}
+	return res.Text
+}
+
+// writeGenerated writes generated content to path, creating its directory.
+func writeGenerated(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
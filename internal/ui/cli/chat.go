@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"archon/internal/adapters/provider"
+	"archon/internal/config"
+	"archon/internal/core"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Have a multi-turn conversation about the codebase",
+	Long: `Starts an interactive conversation. Every answer takes the previous turns into account,
+and older turns are summarized once the history exceeds chat_window tokens.
+Sessions are saved under .archon/sessions and can be continued with --resume.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sessions := core.NewSessionStore(core.DefaultSessionDir)
+
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			printSessions(sessions)
+			return
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		opts, err := searchOptions(cmd)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		session := core.NewSession()
+		if id, _ := cmd.Flags().GetString("resume"); id != "" {
+			session, err = sessions.Load(id)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, turn := range session.Turns {
+				if turn.Role == core.RoleUser {
+					fmt.Printf("\nYou: %s\n", turn.Content)
+				} else {
+					fmt.Printf("\nArchon: %s\n", turn.Content)
+				}
+			}
+		}
+
+		ctx := context.Background()
+		client, err := provider.NewLLM(ctx, cfg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		var orchestrator *core.Orchestrator
+		store, err := provider.NewStore(ctx, cfg)
+		if err != nil {
+			fmt.Printf("Warning: Vector DB not initialized. Chatting without context. (%v)\n", err)
+		} else {
+			defer store.Close()
+			orchestrator = core.NewOrchestrator(store)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+		}
+
+		fmt.Printf("\nSession %s (resume with: archon chat --resume %s). Type 'exit' to quit.\n", session.ID, session.ID)
+
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for {
+			fmt.Print("\nYou> ")
+			if !scanner.Scan() {
+				fmt.Println()
+				break
+			}
+			question := strings.TrimSpace(scanner.Text())
+			if question == "" {
+				continue
+			}
+			if question == "exit" || question == "quit" {
+				break
+			}
+
+			prompt := question
+			if orchestrator != nil {
+				contextText, err := gatherContext(ctx, orchestrator, cfg, "ask", question, opts)
+				if err != nil {
+					fmt.Printf("Error searching context: %v\n", err)
+				} else {
+					prompt = fmt.Sprintf("%s\n\nUser Question: %s", contextText, question)
+				}
+			}
+
+			resp, err := printStream(session.Ask(ctx, client, question, prompt, cfg.ChatWindow), "\nArchon: ")
+			if err != nil {
+				fmt.Printf("Error: %s\n", describeError(err))
+				continue
+			}
+			if err := sessions.Save(session); err != nil {
+				fmt.Printf("Warning: session not saved: %v\n", err)
+			}
+			fmt.Printf("\n(Tokens used: %d, session total: %d)\n", resp.TotalTokens, session.Tokens)
+		}
+	},
+}
+
+func printSessions(sessions *core.SessionStore) {
+	list, err := sessions.List()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(list) == 0 {
+		fmt.Println("No chat sessions yet. Start one with 'archon chat'.")
+		return
+	}
+	fmt.Printf("%-24s %-17s %6s  %s\n", "ID", "UPDATED", "TURNS", "TITLE")
+	for _, s := range list {
+		fmt.Printf("%-24s %-17s %6d  %s\n", s.ID, s.Updated.Format("2006-01-02 15:04"), len(s.Turns), s.Title)
+	}
+}
+
+func init() {
+	chatCmd.Flags().String("resume", "", "Continue the session with this ID (or a unique prefix of it)")
+	chatCmd.Flags().Bool("list", false, "List saved sessions")
+	addSearchFlags(chatCmd)
+	rootCmd.AddCommand(chatCmd)
+}
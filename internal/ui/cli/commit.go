@@ -1,8 +1,9 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
+	"archon/internal/core"
 	"archon/internal/utils"
 	"context"
 	"fmt"
@@ -16,71 +17,258 @@ import (
 var commitCmd = &cobra.Command{
 	Use:   "commit",
 	Short: "Generate a smart commit message",
-	Long:  `Analyzes staged changes and generates a commit message following conventions (such as Conventional Commits).`,
+	Long: `Analyzes staged changes and generates a commit message in the configured style: conventional
+(Conventional Commits, the default), gitmoji or a custom subject template (commit_style,
+commit_template). The scope is inferred from the changed packages, and a message that breaks
+the style (commit_max_subject, commit_body, commit_footer) is sent back to the model once to be
+fixed. Before committing, the message can be edited in your editor.
+
+With --files, only the changes to those paths are described and committed (git commit -- <paths>). With
+--base, --range or --unstaged the message is only printed, e.g. to squash a branch.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Only the message goes to stdout, for git hooks and scripts
+		messageOnly, _ := cmd.Flags().GetBool("message-only")
+		logToStderr = messageOnly
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			logf("Error: %v\n", err)
 			os.Exit(1)
 		}
 
 		ctx := context.Background()
 
-		// Ambil diff staged
-		diffOutput, err := utils.GetStagedDiff()
+		styleName := cfg.CommitStyle
+		if cmd.Flags().Changed("style") {
+			styleName, _ = cmd.Flags().GetString("style")
+		}
+		style, err := core.NewCommitStyle(styleName, cfg.CommitTemplate, cfg.CommitMaxSubject, cfg.CommitBody, cfg.CommitFooter)
+		if err != nil {
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		diffOpts, err := diffOptions(cmd)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		diffOutput, err := utils.GetDiff(diffOpts)
+		if err != nil {
+			logf("Error: %v\n", err)
 			os.Exit(1)
 		}
 
 		if strings.TrimSpace(diffOutput) == "" {
-			fmt.Println("No staged changes to generate a commit message for.")
+			if diffOpts.Staged() {
+				logf("No staged changes to generate a commit message for.\n")
+			} else {
+				logf("No %s to generate a commit message for.\n", diffOpts)
+			}
 			return
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		// The code around the changed symbols helps to say why they changed
+		files := utils.ParseDiff(diffOutput)
+		var contextText, symbolsText string
+		var symbols []core.ChangedSymbol
+		if store, err := provider.NewStore(ctx, cfg); err == nil {
+			defer store.Close()
+			orchestrator := core.NewOrchestrator(store)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			var res *core.ContextResult
+			if res, symbols, err = changeContext(ctx, orchestrator, cfg, "commit", diffOutput, diffOpts, core.SearchOptions{}); err == nil {
+				contextText = res.Text
+			}
+		} else {
+			// Without an index, symbols still tell which packages belong together
+			symbols = core.NewOrchestrator(nil).ChangedSymbols(ctx, files, diffOpts.ReadNew)
+		}
+		if len(symbols) > 0 {
+			symbolsText = "Changed symbols: " + core.FormatChangedSymbols(symbols) + "\n\n"
+		}
+
+		if groups := core.SplitGroups(files, symbols); len(groups) > 1 {
+			logf("💡 These changes touch unrelated packages; consider splitting them into %d commits:\n", len(groups))
+			for i, group := range groups {
+				logf("   %d. %s\n", i+1, strings.Join(group, ", "))
+			}
+			if diffOpts.Staged() {
+				logf("   e.g. unstage a group with git restore --staged <files> and commit it afterwards.\n")
+			}
+		}
+
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			logf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		defer client.Close()
 
-		prompt := fmt.Sprintf(`Task: Create a descriptive commit message based on the following code changes (diff).
-Use Conventional Commits format (type(scope): description).
-Provide a brief explanation of WHAT changed and WHY (if it can be inferred).
+		// A diff too large for one request is described by summaries of its parts
+		changes := "Diff:\n" + diffOutput
+		if chunks := diffChunks(cfg, diffOutput); len(chunks) > 1 {
+			logf("The diff is large, summarizing it in %d parts...\n", len(chunks))
+			summaries, err := summarizeDiff(ctx, client, chunks)
+			if err != nil {
+				logf("Error: %s\n", describeError(err))
+				os.Exit(1)
+			}
+			changes = "Summary of the changes, by part of the diff:\n" + summaries
+		}
+
+		prompt := strings.TrimSpace(fmt.Sprintf(`%s
+
+Task: Create a descriptive commit message based on the following code changes (diff).
+%s
 Only return the commit message itself, no other additional text.
 
-Diff:
-%s`, string(diffOutput))
+%s%s`, contextText, style.Instructions(core.InferScopes(files)), symbolsText, changes))
 
-		fmt.Println("🤖 Generating commit message...")
-		resp, err := client.Ask(ctx, prompt)
+		logf("🤖 Generating commit message...\n")
+		commitMsg, problems, err := generateCommitMessage(ctx, client, style, prompt)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			logf("Error: %s\n", describeError(err))
 			os.Exit(1)
 		}
+		for _, p := range problems {
+			logf("Warning: the message does not follow the %s style: %s\n", style.Name, p)
+		}
+
+		if messageOnly {
+			fmt.Println(commitMsg)
+			return
+		}
 
-		commitMsg := strings.TrimSpace(resp.Text)
 		fmt.Printf("\nSuggested Commit Message:\n---\n%s\n---\n", commitMsg)
-		
-		fmt.Print("\nDo you want to commit now? (y/n): ")
+
+		if !diffOpts.Staged() {
+			fmt.Printf("\n(Not committing: the message describes the %s, not the staged changes.)\n", diffOpts)
+			return
+		}
+
+		fmt.Print("\nDo you want to commit now? (y = yes, e = edit first, n = no): ")
 		var confirm string
 		fmt.Scanln(&confirm)
 
-		if strings.ToLower(confirm) == "y" {
-			commitExec := exec.Command("git", "commit", "-m", commitMsg)
-			commitExec.Stdout = os.Stdout
-			commitExec.Stderr = os.Stderr
-			err := commitExec.Run()
+		switch strings.ToLower(confirm) {
+		case "e":
+			edited, err := utils.EditText(commitMsg+"\n\n# Edit the commit message. Lines starting with '#' are ignored;\n# an empty message aborts the commit.\n", "COMMIT_EDITMSG-*")
 			if err != nil {
-				fmt.Printf("Failed to commit: %v\n", err)
-			} else {
-				fmt.Println("✅ Successfully committed!")
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if edited == "" {
+				fmt.Println("Empty commit message, not committing.")
+				return
 			}
+			for _, p := range style.Validate(edited) {
+				fmt.Printf("Warning: the message does not follow the %s style: %s\n", style.Name, p)
+			}
+			commitMsg = edited
+		case "y":
+		default:
+			return
+		}
+
+		if err := gitCommit(commitMsg, diffOpts.Files); err != nil {
+			fmt.Printf("Failed to commit: %v\n", err)
+		} else {
+			fmt.Println("✅ Successfully committed!")
 		}
 	},
 }
 
+// generateCommitMessage asks for a commit message and, if it breaks the style,
+// once more with the problems listed. It returns the problems left.
+func generateCommitMessage(ctx context.Context, client core.LLM, style *core.CommitStyle, prompt string) (string, []string, error) {
+	resp, err := client.Generate(ctx, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	msg := core.CleanCommitMessage(resp.Text)
+	problems := style.Validate(msg)
+	if len(problems) == 0 {
+		return msg, nil, nil
+	}
+
+	logf("The message does not follow the %s style, asking for a fix...\n", style.Name)
+	repair := fmt.Sprintf(`%s
+
+Your previous answer was:
+%s
+
+It breaks these rules:
+- %s
+
+Return the corrected commit message only.`, prompt, msg, strings.Join(problems, "\n- "))
+	resp, err = client.Generate(ctx, repair)
+	if err != nil {
+		return "", nil, err
+	}
+	fixed := core.CleanCommitMessage(resp.Text)
+	if fixed == "" {
+		return msg, problems, nil
+	}
+	return fixed, style.Validate(fixed), nil
+}
+
+// gitCommit commits the staged changes (to files, if given) with msg. The
+// message goes through a file, so that git keeps its paragraphs as written.
+func gitCommit(msg string, files []string) error {
+	if len(files) > 0 {
+		// git commit -- <files> takes the files from the working tree, so their
+		// unstaged changes would be committed under a message that ignores them
+		out, err := exec.Command("git", append([]string{"diff", "--name-only", "--"}, files...)...).Output()
+		if err != nil {
+			return fmt.Errorf("failed to check for unstaged changes: %w", err)
+		}
+		if unstaged := strings.TrimSpace(string(out)); unstaged != "" {
+			return fmt.Errorf("%s also has unstaged changes, stage or stash them first", strings.ReplaceAll(unstaged, "\n", ", "))
+		}
+	}
+
+	f, err := os.CreateTemp("", "archon-commit-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(msg + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	commitArgs := []string{"commit", "-F", f.Name()}
+	if len(files) > 0 {
+		commitArgs = append(append(commitArgs, "--"), files...)
+	}
+	commitExec := exec.Command("git", commitArgs...)
+	commitExec.Stdout = os.Stdout
+	commitExec.Stderr = os.Stderr
+	return commitExec.Run()
+}
+
+// summarizeDiff describes each chunk of a diff in a few lines, for diffs too
+// large to send at once.
+func summarizeDiff(ctx context.Context, client core.LLM, chunks []string) (string, error) {
+	var b strings.Builder
+	for i, chunk := range chunks {
+		prompt := fmt.Sprintf("Summarize the following part of a diff in a few bullet points: what changed, in which files, and why if it can be inferred. Only return the bullet points.\n\nDiff:\n%s", chunk)
+		resp, err := client.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "Part %d:\n%s\n\n", i+1, strings.TrimSpace(resp.Text))
+	}
+	return b.String(), nil
+}
+
 func init() {
+	addDiffFlags(commitCmd)
+	commitCmd.Flags().Bool("message-only", false, "Only print the commit message, without asking to commit (for git hooks and scripts)")
+	commitCmd.Flags().String("style", "", "Commit message style: conventional, gitmoji or custom (default commit_style)")
 	rootCmd.AddCommand(commitCmd)
 }
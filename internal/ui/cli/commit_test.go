@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository with one commit and runs the test in it. The
+// returned function runs git there and returns its output.
+func gitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "test")
+	write(t, filepath.Join(root, "a.go"), "package a\n")
+	write(t, filepath.Join(root, "b.go"), "package b\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	t.Chdir(root)
+	return root, git
+}
+
+func TestGitCommitFiles(t *testing.T) {
+	_, git := gitRepo(t)
+	write(t, "a.go", "package a\n\nvar staged = 1\n")
+	git("add", "a.go")
+	write(t, "a.go", "package a\n\nvar staged = 1\nvar unstaged = 2\n")
+
+	err := gitCommit("feat: add staged", []string{"a.go"})
+	if err == nil || !strings.Contains(err.Error(), "a.go also has unstaged changes") {
+		t.Fatalf("gitCommit = %v, want it refused for the unstaged change", err)
+	}
+	if log := git("log", "--oneline"); strings.Count(log, "\n") != 1 {
+		t.Fatalf("a commit was made:\n%s", log)
+	}
+
+	// Without unstaged changes only the files given are committed
+	write(t, "a.go", "package a\n\nvar staged = 1\n")
+	write(t, "b.go", "package b\n\nvar other = 1\n")
+	git("add", "b.go")
+	if err := gitCommit("feat: add staged", []string{"a.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("show", "--name-only", "--format=%s", "HEAD"); got != "feat: add staged\n\na.go\n" {
+		t.Errorf("HEAD = %q, want the message and only a.go", got)
+	}
+	if got := git("diff", "--cached", "--name-only"); got != "b.go\n" {
+		t.Errorf("staged after the commit = %q, want b.go left staged", got)
+	}
+}
@@ -17,7 +17,11 @@ var configListCmd = &cobra.Command{
 	Short: "List all configuration",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg, _ := config.LoadConfig()
+		fmt.Printf("Provider: %s\n", cfg.Provider)
 		fmt.Printf("Model ID: %s\n", cfg.ModelID)
+		if cfg.Provider == config.ProviderOpenAI {
+			fmt.Printf("OpenAI Base URL: %s\n", cfg.OpenAIBaseURL)
+		}
 		fmt.Printf("Gemini Key: %s\n", "********")
 	},
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/config"
+	"archon/internal/core"
+	"archon/internal/utils"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// gatherContext retrieves the code context for query within the token budget of
+// command and prints a summary of what was included and dropped.
+func gatherContext(ctx context.Context, orchestrator *core.Orchestrator, cfg *config.Config, command, query string, opts core.SearchOptions) (string, error) {
+	res, err := searchContext(ctx, orchestrator, cfg, command, query, opts)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// searchContext is gatherContext for commands that also report what the context holds.
+func searchContext(ctx context.Context, orchestrator *core.Orchestrator, cfg *config.Config, command, query string, opts core.SearchOptions) (*core.ContextResult, error) {
+	res, err := orchestrator.SearchContext(ctx, query, core.ContextBudget(command, cfg.ContextBudget), opts)
+	if err != nil {
+		return nil, err
+	}
+	logf("Context: %s\n", res.Summary())
+	return res, nil
+}
+
+// changeContext retrieves the code context of a diff: the symbols enclosing its
+// changed lines and the code that refers to them. It prints which symbols changed.
+func changeContext(ctx context.Context, orchestrator *core.Orchestrator, cfg *config.Config, command, diff string, diffOpts utils.DiffOptions, opts core.SearchOptions) (*core.ContextResult, []core.ChangedSymbol, error) {
+	files := utils.ParseDiff(diff)
+	symbols := orchestrator.ChangedSymbols(ctx, files, diffOpts.ReadNew)
+	if len(symbols) > 0 {
+		logf("Changed symbols: %s\n", core.FormatChangedSymbols(symbols))
+	}
+	res, err := orchestrator.ChangeContext(ctx, files, symbols, core.ContextBudget(command, cfg.ContextBudget), opts)
+	if err != nil {
+		return nil, symbols, err
+	}
+	logf("Context: %s\n", res.Summary())
+	return res, symbols, nil
+}
+
+// addSearchFlags registers the flags that scope which code is used as context.
+func addSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("path", "", "Only use code under this path as context (e.g. internal/adapters)")
+	cmd.Flags().String("lang", "", "Only use code in this language as context (go, python, typescript, ...)")
+	cmd.Flags().String("type", "", "Only use symbols of this type as context (function, method, class, type, interface)")
+	cmd.Flags().Bool("exclude-tests", false, "Do not use test files as context")
+	cmd.Flags().Float32("min-similarity", 0, "Drop semantic matches below this similarity (0-1)")
+}
+
+// searchOptions reads the flags registered by addSearchFlags.
+func searchOptions(cmd *cobra.Command) (core.SearchOptions, error) {
+	var opts core.SearchOptions
+	opts.PathPrefix, _ = cmd.Flags().GetString("path")
+	opts.SymbolType, _ = cmd.Flags().GetString("type")
+	opts.ExcludeTests, _ = cmd.Flags().GetBool("exclude-tests")
+	opts.MinSimilarity, _ = cmd.Flags().GetFloat32("min-similarity")
+	opts.SymbolType = strings.ToLower(opts.SymbolType)
+
+	if opts.PathPrefix != "" {
+		if _, err := os.Stat(opts.PathPrefix); err != nil {
+			return opts, fmt.Errorf("--path %q: %w", opts.PathPrefix, err)
+		}
+	}
+	if lang, _ := cmd.Flags().GetString("lang"); lang != "" {
+		l, ok := parser.ParseLanguage(lang)
+		if !ok {
+			return opts, fmt.Errorf("--lang %q is not a supported language", lang)
+		}
+		opts.Language = string(l)
+	}
+	return opts, nil
+}
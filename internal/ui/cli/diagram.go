@@ -1,8 +1,7 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"context"
@@ -26,16 +25,17 @@ var diagramCmd = &cobra.Command{
 		}
 
 		ctx := context.Background()
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		var contextText string
 		if err == nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
 			fmt.Println("Gathering context...")
-			contextText, _ = orchestrator.SearchContext(ctx, "structure and relationships for "+focus)
+			contextText, _ = gatherContext(ctx, orchestrator, cfg, "diagram", "structure and relationships for "+focus, core.SearchOptions{})
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
@@ -46,9 +46,9 @@ var diagramCmd = &cobra.Command{
 			contextText, diagType, focus)
 
 		fmt.Println("Generating diagram...")
-		resp, err := client.Ask(ctx, prompt)
+		resp, err := client.Generate(ctx, prompt)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", describeError(err))
 			os.Exit(1)
 		}
 
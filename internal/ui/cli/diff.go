@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"archon/internal/config"
+	"archon/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultDiffChunkTokens is used when diff_chunk_tokens is not set.
+const defaultDiffChunkTokens = 30000
+
+// addDiffFlags registers the flags that select which changes review and commit look at.
+func addDiffFlags(cmd *cobra.Command) {
+	cmd.Flags().String("base", "", "Use the changes of the current branch since it left this ref (e.g. main)")
+	cmd.Flags().String("range", "", "Use the changes in a revision range (e.g. main..feature, HEAD~3..HEAD)")
+	cmd.Flags().Bool("unstaged", false, "Use the unstaged changes in the working tree")
+	cmd.Flags().StringSlice("files", nil, "Only use the changes to these paths")
+}
+
+// diffOptions reads the flags registered by addDiffFlags.
+func diffOptions(cmd *cobra.Command) (utils.DiffOptions, error) {
+	var opts utils.DiffOptions
+	opts.Base, _ = cmd.Flags().GetString("base")
+	opts.Range, _ = cmd.Flags().GetString("range")
+	opts.Unstaged, _ = cmd.Flags().GetBool("unstaged")
+	opts.Files, _ = cmd.Flags().GetStringSlice("files")
+	return opts, opts.Validate()
+}
+
+// diffChunks splits a diff into the parts sent to the model one at a time.
+func diffChunks(cfg *config.Config, diff string) []string {
+	tokens := cfg.DiffChunkTokens
+	if tokens <= 0 {
+		tokens = defaultDiffChunkTokens
+	}
+	// About 4 characters per token, as core.EstimateTokens
+	return utils.ChunkDiff(diff, tokens*4)
+}
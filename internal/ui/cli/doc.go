@@ -1,23 +1,37 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/parser"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 )
 
 var docCmd = &cobra.Command{
-	Use:   "doc [file]",
+	Use:   "doc [file|dir|dir/...|glob]...",
 	Short: "Generate documentation for a file",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Generates documentation for a file and prints it.
+
+With --all, several files, a directory (one package), "dir/..." (a directory tree) or a
+glob pattern, documentation is generated for every source file. It is written as Markdown
+to a mirrored tree under --out, or with --in-place added as docstrings to the source files
+themselves. Files that are already documented are skipped unless --overwrite is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		filePath := args[0]
+		all, _ := cmd.Flags().GetBool("all")
+		if len(args) == 0 && !all {
+			fmt.Println("Error: specify a file, directory or pattern, or use --all")
+			os.Exit(1)
+		}
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
@@ -25,16 +39,23 @@ var docCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if bulkMode(all, args) {
+			runDocBulk(cmd, cfg, args, all)
+			return
+		}
+
+		filePath := args[0]
 		ctx := context.Background()
-		store, _ := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, _ := provider.NewStore(ctx, cfg)
 		var contextText string
 		if store != nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
-			contextText, _ = orchestrator.SearchContext(ctx, "documentation for "+filePath)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			contextText, _ = gatherContext(ctx, orchestrator, cfg, "doc", "documentation for "+filePath, core.SearchOptions{})
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
@@ -46,9 +67,9 @@ var docCmd = &cobra.Command{
 			contextText, filePath, string(content))
 
 		fmt.Println("Generating documentation...")
-		resp, err := client.Ask(ctx, prompt)
+		resp, err := client.Generate(ctx, prompt)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", describeError(err))
 			os.Exit(1)
 		}
 
@@ -56,6 +77,193 @@ var docCmd = &cobra.Command{
 	},
 }
 
+// runDocBulk documents every source file matched by args, either as Markdown
+// under --out or as docstrings in the files themselves.
+func runDocBulk(cmd *cobra.Command, cfg *config.Config, args []string, all bool) {
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	outDir, _ := cmd.Flags().GetString("out")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	files, err := resolveTargets(args, all)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("No source files found.")
+		return
+	}
+
+	client, err := provider.NewLLM(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	var orchestrator *core.Orchestrator
+	if store, err := provider.NewStore(ctx, cfg); err == nil {
+		defer store.Close()
+		orchestrator = core.NewOrchestrator(store)
+		orchestrator.SetSearchBlend(cfg.SearchBlend)
+	}
+
+	// In-place edits are applied together once every file was generated
+	var mu sync.Mutex
+	var changes []core.FileChange
+
+	docPath := func(file string) string {
+		return filepath.Join(outDir, filepath.Clean(file)+".md")
+	}
+	p := parser.NewTreeSitterParser()
+
+	task := bulkTask{
+		skip: func(file string) string {
+			if overwrite {
+				return ""
+			}
+			if inPlace {
+				if symbols, ok := parseFile(ctx, p, file); ok && undocumented(symbols) == 0 {
+					return "all symbols documented"
+				}
+				return ""
+			}
+			if _, err := os.Stat(docPath(file)); err == nil {
+				return "documented in " + docPath(file)
+			}
+			return ""
+		},
+		generate: func(ctx context.Context, file string) (string, *core.Response, error) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return "", nil, err
+			}
+			contextText := fileContext(ctx, orchestrator, cfg, "doc", "documentation for "+file)
+
+			if !inPlace {
+				prompt := fmt.Sprintf("%s\n\nTask: Write Markdown reference documentation for the following file: %s. Describe its purpose and every exported type and function with its parameters, return values and errors.\n\nCode:\n```\n%s\n```", 
+					contextText, file, string(content))
+				resp, err := client.Generate(ctx, prompt)
+				if err != nil {
+					return "", nil, err
+				}
+				return docPath(file), resp, writeGenerated(docPath(file), resp.Text)
+			}
+
+			prompt := fmt.Sprintf("%s\n\nTask: Add documentation comments (docstrings) in the idiomatic style of the language to every undocumented type, function and method of the following file: %s. Keep existing comments and do not change any code.\n\nCode:\n```\n%s\n```", 
+				contextText, file, string(content))
+			prompt += "\n\nRETURN ONLY THE COMPLETE FILE in a single Markdown code block (```). Do not provide any explanation outside that code block because your output will be written directly to the file."
+			resp, err := client.Generate(ctx, prompt)
+			if err != nil {
+				return "", nil, err
+			}
+			code := extractCode(resp.Text)
+			if code == "" {
+				return "", resp, fmt.Errorf("no code block in the response")
+			}
+
+			if !strings.HasSuffix(code, "\n") {
+				code += "\n"
+			}
+
+			// Refuse output that changed or lost code, e.g. a truncated answer
+			if err := onlyCommentsChanged(ctx, p, file, content, []byte(code)); err != nil {
+				return "", resp, fmt.Errorf("%w, not written", err)
+			}
+			mu.Lock()
+			changes = append(changes, core.FileChange{Path: file, Existed: true, Old: content, New: []byte(code)})
+			mu.Unlock()
+			return file, resp, nil
+		},
+	}
+
+	rows := runBulk(ctx, cfg, files, task)
+	if len(changes) > 0 {
+		// One journal entry for the whole run, so `archon undo` reverts it at once
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+		entry, err := applyChanges("doc", changes)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nWrote %d files (undo with 'archon undo', backup %s)\n", len(changes), entry.ID)
+	}
+	if printBulkSummary(rows) {
+		os.Exit(1)
+	}
+}
+
+// onlyCommentsChanged fails unless after is before with comments added or
+// changed, i.e. both are the same code once comments are stripped. The error
+// names the first symbol whose code differs.
+func onlyCommentsChanged(ctx context.Context, p parser.Parser, file string, before, after []byte) error {
+	lang := parser.DetectLanguage(file)
+	if parser.StripComments(lang, string(after)) == parser.StripComments(lang, string(before)) {
+		return nil
+	}
+
+	old, err := p.Parse(ctx, file, before)
+	if err != nil {
+		return fmt.Errorf("documented file changed code")
+	}
+	updated, err := p.Parse(ctx, file, after)
+	if err != nil {
+		return fmt.Errorf("documented file does not parse: %w", err)
+	}
+	// Symbols are matched by name and, for repeated names, by their order
+	key := func(seen map[string]int, sym parser.Symbol) string {
+		name := sym.QualifiedName()
+		seen[name]++
+		return fmt.Sprintf("%s#%d", name, seen[name])
+	}
+	codeOf := make(map[string]string)
+	seen := make(map[string]int)
+	for _, sym := range updated {
+		codeOf[key(seen, sym)] = parser.StripComments(lang, sym.Code)
+	}
+	keys := make([]string, len(old))
+	seen = make(map[string]int)
+	for i, sym := range old {
+		keys[i] = key(seen, sym)
+		if _, ok := codeOf[keys[i]]; !ok {
+			return fmt.Errorf("documented file lost %s", sym.QualifiedName())
+		}
+	}
+	for i, sym := range old {
+		if codeOf[keys[i]] != parser.StripComments(lang, sym.Code) {
+			return fmt.Errorf("documented file changed the code of %s", sym.QualifiedName())
+		}
+	}
+	return fmt.Errorf("documented file changed code outside of its symbols")
+}
+
+func parseFile(ctx context.Context, p parser.Parser, file string) ([]parser.Symbol, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	symbols, err := p.Parse(ctx, file, content)
+	return symbols, err == nil
+}
+
+// undocumented counts the symbols without a doc comment.
+func undocumented(symbols []parser.Symbol) int {
+	n := 0
+	for _, sym := range symbols {
+		if sym.Type != "file" && sym.Doc == "" {
+			n++
+		}
+	}
+	return n
+}
+
 func init() {
+	docCmd.Flags().Bool("all", false, "Document every source file in the project")
+	docCmd.Flags().Bool("overwrite", false, "Regenerate documentation for files that are already documented")
+	docCmd.Flags().Bool("in-place", false, "Add docstrings to the source files instead of writing Markdown")
+	docCmd.Flags().String("out", filepath.Join("docs", "api"), "Directory of the generated Markdown documentation")
 	rootCmd.AddCommand(docCmd)
 }
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"archon/internal/adapters/parser"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOnlyCommentsChanged(t *testing.T) {
+	before := "package a\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() {}\n"
+	tests := []struct {
+		name  string
+		after string
+		err   string
+	}{
+		{"docs added", "package a\n\n// A returns one.\nfunc A() int {\n\treturn 1\n}\n\n// B does nothing.\nfunc B() {}\n", ""},
+		{"body changed", "package a\n\n// A returns one.\nfunc A() int {\n\treturn 2\n}\n\nfunc B() {}\n", "changed the code of A"},
+		{"symbol dropped", "package a\n\n// A returns one.\nfunc A() int {\n\treturn 1\n}\n", "lost B"},
+		{"import added", "package a\n\nimport \"fmt\"\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() {}\n", "outside of its symbols"},
+	}
+	p := parser.NewTreeSitterParser()
+	for _, tt := range tests {
+		err := onlyCommentsChanged(context.Background(), p, "a.go", []byte(before), []byte(tt.after))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"fmt"
+)
+
+// describeError renders a provider error followed by a hint on how to fix it, if there is one.
+func describeError(err error) string {
+	if hint := core.ErrorHint(err); hint != "" {
+		return fmt.Sprintf("%v\n%s", err, hint)
+	}
+	return err.Error()
+}
@@ -1,13 +1,14 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
+	"archon/internal/utils"
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -15,30 +16,58 @@ import (
 var explainCmd = &cobra.Command{
 	Use:   "explain [file/symbol]",
 	Short: "Explain a file or symbol",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Explains a file or symbol using the related code of the index.
+
+With --changes it explains a diff instead: the staged changes, or those selected with --base,
+--range, --unstaged and --files. The symbols enclosing the changed lines and the code that
+calls them are used as context.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if changes, _ := cmd.Flags().GetBool("changes"); changes {
+			return cobra.NoArgs(cmd, args)
+		}
+		for _, name := range []string{"base", "range", "unstaged", "files"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s needs --changes", name)
+			}
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		target := args[0]
-		
 		cfg, _ := config.LoadConfig()
 		ctx := context.Background()
+		result := newReport("explain", cfg)
 
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		opts, err := searchOptions(cmd)
+		if err != nil {
+			result.fail("Error", err)
+		}
+
+		if changes, _ := cmd.Flags().GetBool("changes"); changes {
+			explainChanges(ctx, cmd, cfg, result, opts)
+			return
+		}
+		target := args[0]
+
+		store, err := provider.NewStore(ctx, cfg)
 		var contextText string
 		if err == nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
-			fmt.Println("Gathering context...")
-			contextText, _ = orchestrator.SearchContext(ctx, "Explain "+target)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			logf("Gathering context...\n")
+			if res, err := searchContext(ctx, orchestrator, cfg, "explain", "Explain "+target, opts); err == nil {
+				result.addContext(res)
+				contextText = res.Text
+			}
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			result.fail("Error", err)
 		}
 		defer client.Close()
 
-		fmt.Printf("Analyzing %s...\n", target)
+		logf("Analyzing %s...\n", target)
 		
 		var prompt string
 		if contextText != "" {
@@ -53,15 +82,81 @@ var explainCmd = &cobra.Command{
 			}
 		}
 
-		resp, err := client.Ask(ctx, prompt)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+		if _, err := result.answer(ctx, client, prompt, ""); err != nil {
+			if structuredOutput() {
+				result.fail("Error", err)
+			}
+			fmt.Printf("Error: %s\n", describeError(err))
 			return
 		}
-		fmt.Println(resp.Text)
+		result.emit()
 	},
 }
 
+// explainChanges explains a diff, with the changed symbols and their callers as context.
+func explainChanges(ctx context.Context, cmd *cobra.Command, cfg *config.Config, result *report, opts core.SearchOptions) {
+	diffOpts, err := diffOptions(cmd)
+	if err != nil {
+		result.fail("Error", err)
+	}
+	diffOutput, err := utils.GetDiff(diffOpts)
+	if err != nil {
+		result.fail("Error", err)
+	}
+	if strings.TrimSpace(diffOutput) == "" {
+		logf("No %s to explain.\n", diffOpts)
+		result.emit()
+		return
+	}
+
+	var contextText, symbolsText string
+	if store, err := provider.NewStore(ctx, cfg); err == nil {
+		defer store.Close()
+		orchestrator := core.NewOrchestrator(store)
+		orchestrator.SetSearchBlend(cfg.SearchBlend)
+		logf("Gathering context...\n")
+		if res, symbols, err := changeContext(ctx, orchestrator, cfg, "explain", diffOutput, diffOpts, opts); err == nil {
+			result.addContext(res)
+			contextText = res.Text
+			if len(symbols) > 0 {
+				symbolsText = "Changed symbols: " + core.FormatChangedSymbols(symbols) + "\n\n"
+			}
+		}
+	}
+
+	client, err := provider.NewLLM(ctx, cfg)
+	if err != nil {
+		result.fail("Error", err)
+	}
+	defer client.Close()
+
+	// Only the first part of a large diff fits in the prompt
+	diffText := diffChunks(cfg, diffOutput)[0]
+	if len(diffText) < len(diffOutput) {
+		result.warn("the diff is large, only its first part is explained")
+	}
+
+	logf("Analyzing the %s...\n", diffOpts)
+	prompt := strings.TrimSpace(fmt.Sprintf(`%s
+
+Task: Explain the following changes (diff): what they do, why they were likely made, and how they affect the code that uses the changed symbols.
+
+%sDiff:
+%s`, contextText, symbolsText, diffText))
+
+	if _, err := result.answer(ctx, client, prompt, ""); err != nil {
+		if structuredOutput() {
+			result.fail("Error", err)
+		}
+		fmt.Printf("Error: %s\n", describeError(err))
+		return
+	}
+	result.emit()
+}
+
 func init() {
+	addSearchFlags(explainCmd)
+	addDiffFlags(explainCmd)
+	explainCmd.Flags().Bool("changes", false, "Explain the staged changes, or those selected with --base, --range, --unstaged or --files")
 	rootCmd.AddCommand(explainCmd)
 }
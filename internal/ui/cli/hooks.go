@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"archon/internal/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies the hooks written by archon.
+const hookMarker = "# Installed by archon hooks install."
+
+// chainedSuffix is appended to a hook that existed before archon's, which then runs it first.
+const chainedSuffix = ".pre-archon"
+
+const prepareCommitMsgHook = `#!/bin/sh
+%s
+# Fills in the commit message with archon commit --message-only when git has no
+# message yet. Set ARCHON_SKIP_HOOKS=1 to skip it.
+hook="$(dirname "$0")/prepare-commit-msg%s"
+if [ -x "$hook" ]; then
+	"$hook" "$@" || exit $?
+fi
+
+# Not for -m/-F, templates, merges, squashes or amends
+if [ -n "$2" ] || [ -n "$ARCHON_SKIP_HOOKS" ]; then
+	exit 0
+fi
+
+archon=%s
+[ -x "$archon" ] || archon=archon
+msg=$("$archon" commit --message-only) || exit 0
+if [ -n "$msg" ]; then
+	{ printf '%%s\n' "$msg"; cat "$1"; } > "$1.archon" && mv "$1.archon" "$1"
+fi
+exit 0
+`
+
+const preCommitHook = `#!/bin/sh
+%s
+# Reviews the staged changes with archon review and blocks the commit on findings
+# of severity %s or higher. Skip it with git commit --no-verify or ARCHON_SKIP_HOOKS=1.
+hook="$(dirname "$0")/pre-commit%s"
+if [ -x "$hook" ]; then
+	"$hook" "$@" || exit $?
+fi
+
+if [ -n "$ARCHON_SKIP_HOOKS" ]; then
+	exit 0
+fi
+
+archon=%s
+[ -x "$archon" ] || archon=archon
+"$archon" review --fail-on %s
+if [ $? -eq 2 ]; then
+	echo "archon: commit blocked by review findings (git commit --no-verify skips the review)" >&2
+	exit 1
+fi
+exit 0
+`
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage the git hooks of Archon",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install git hooks that write commit messages and review commits",
+	Long: `Installs a prepare-commit-msg hook that fills in the message of 'git commit' from the staged
+changes, like 'archon commit'. With --pre-commit, a pre-commit hook also reviews the staged
+changes and blocks the commit when a finding has the --fail-on severity or higher.
+
+Hooks that already exist are kept: they are renamed with the suffix .pre-archon and run
+before Archon's. 'archon hooks uninstall' puts them back.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := utils.HooksDir()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		severity, ok := core.ParseSeverity(failOn)
+		if !ok {
+			fmt.Printf("Error: --fail-on must be critical, high, medium, low or info, not %q\n", failOn)
+			os.Exit(1)
+		}
+
+		// The hooks call this binary, or archon from PATH if it moved
+		archon := "archon"
+		if exe, err := os.Executable(); err == nil {
+			archon = exe
+		}
+
+		hooks := map[string]string{
+			"prepare-commit-msg": fmt.Sprintf(prepareCommitMsgHook, hookMarker, chainedSuffix, shellQuote(archon)),
+		}
+		if preCommit, _ := cmd.Flags().GetBool("pre-commit"); preCommit {
+			hooks["pre-commit"] = fmt.Sprintf(preCommitHook, hookMarker, severity, chainedSuffix, shellQuote(archon), severity)
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, name := range []string{"prepare-commit-msg", "pre-commit"} {
+			script, ok := hooks[name]
+			if !ok {
+				continue
+			}
+			chained, err := installHook(filepath.Join(dir, name), script)
+			if err != nil {
+				fmt.Printf("Error installing %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Installed %s\n", filepath.Join(dir, name))
+			if chained {
+				fmt.Printf("   The existing hook was moved to %s%s and still runs first.\n", name, chainedSuffix)
+			}
+		}
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the git hooks of Archon",
+	Long:  `Removes the hooks written by 'archon hooks install' and restores the hooks they chained to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := utils.HooksDir()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		removed := 0
+		for _, name := range []string{"prepare-commit-msg", "pre-commit"} {
+			ok, err := uninstallHook(filepath.Join(dir, name))
+			if err != nil {
+				fmt.Printf("Error removing %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			if ok {
+				removed++
+				fmt.Printf("Removed %s\n", filepath.Join(dir, name))
+			}
+		}
+		if removed == 0 {
+			fmt.Println("No Archon hooks are installed.")
+		}
+	},
+}
+
+// isArchonHook reports whether the hook at path was written by archon.
+func isArchonHook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), hookMarker)
+}
+
+// installHook writes script as the hook at path. A hook that is not archon's
+// is moved aside first, to be run by script. Reinstalling replaces archon's hook.
+func installHook(path, script string) (chained bool, err error) {
+	if _, err := os.Stat(path); err == nil && !isArchonHook(path) {
+		if _, err := os.Stat(path + chainedSuffix); err == nil {
+			return false, fmt.Errorf("both %s and %s exist, merge them first", path, path+chainedSuffix)
+		}
+		if err := os.Rename(path, path+chainedSuffix); err != nil {
+			return false, err
+		}
+		chained = true
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return chained, err
+	}
+	// WriteFile keeps the mode of an existing file
+	return chained, os.Chmod(path, 0755)
+}
+
+// uninstallHook removes archon's hook at path and restores the hook it chained
+// to. It reports whether there was a hook of archon.
+func uninstallHook(path string) (bool, error) {
+	if !isArchonHook(path) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path + chainedSuffix); err == nil {
+		if err := os.Rename(path+chainedSuffix, path); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	hooksInstallCmd.Flags().Bool("pre-commit", false, "Also install a pre-commit hook that reviews the staged changes")
+	hooksInstallCmd.Flags().String("fail-on", core.SeverityHigh, "Severity from which the pre-commit review blocks the commit (critical, high, medium, low, info)")
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
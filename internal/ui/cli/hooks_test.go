@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const userHook = "#!/bin/sh\necho user hook\n"
+
+func TestInstallHook(t *testing.T) {
+	script := fmt.Sprintf(preCommitHook, hookMarker, "high", chainedSuffix, shellQuote("archon"), "high")
+
+	t.Run("fresh", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pre-commit")
+		chained, err := installHook(path, script)
+		if err != nil || chained {
+			t.Fatalf("installHook = %v, %v, want a plain install", chained, err)
+		}
+		if read(t, path) != script || !isArchonHook(path) {
+			t.Error("the hook was not written")
+		}
+		if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+			t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+		}
+	})
+
+	t.Run("chains an existing hook", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pre-commit")
+		write(t, path, userHook)
+		chained, err := installHook(path, script)
+		if err != nil || !chained {
+			t.Fatalf("installHook = %v, %v, want the user's hook chained", chained, err)
+		}
+		if read(t, path+chainedSuffix) != userHook || read(t, path) != script {
+			t.Error("the user's hook was not moved aside")
+		}
+	})
+
+	t.Run("reinstall", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pre-commit")
+		write(t, path, userHook)
+		if _, err := installHook(path, "#!/bin/sh\n"+hookMarker+"\n# old version\n"); err != nil {
+			t.Fatal(err)
+		}
+		chained, err := installHook(path, script)
+		if err != nil || chained {
+			t.Fatalf("installHook = %v, %v, want archon's hook replaced", chained, err)
+		}
+		if read(t, path) != script || read(t, path+chainedSuffix) != userHook {
+			t.Error("reinstalling did not keep the chained hook")
+		}
+	})
+
+	t.Run("leftover chained hook", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pre-commit")
+		write(t, path, userHook)
+		write(t, path+chainedSuffix, "#!/bin/sh\necho older hook\n")
+		if _, err := installHook(path, script); err == nil {
+			t.Fatal("installHook overwrote a leftover chained hook")
+		}
+		if read(t, path) != userHook || read(t, path+chainedSuffix) != "#!/bin/sh\necho older hook\n" {
+			t.Error("a refused install changed the hooks")
+		}
+	})
+}
+
+func TestUninstallHook(t *testing.T) {
+	script := fmt.Sprintf(prepareCommitMsgHook, hookMarker, chainedSuffix, shellQuote("archon"))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prepare-commit-msg")
+	write(t, path, userHook)
+	if _, err := installHook(path, script); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := uninstallHook(path); err != nil || !ok {
+		t.Fatalf("uninstallHook = %v, %v", ok, err)
+	}
+	if read(t, path) != userHook {
+		t.Error("the original hook was not restored")
+	}
+	if _, err := os.Stat(path + chainedSuffix); !os.IsNotExist(err) {
+		t.Error("the chained copy was left behind")
+	}
+
+	// Hooks that are not archon's are left alone
+	if ok, err := uninstallHook(path); err != nil || ok {
+		t.Errorf("uninstallHook of the user's hook = %v, %v, want nothing done", ok, err)
+	}
+	if read(t, path) != userHook {
+		t.Error("the user's hook was changed")
+	}
+	if ok, err := uninstallHook(filepath.Join(dir, "pre-commit")); err != nil || ok {
+		t.Errorf("uninstallHook without a hook = %v, %v", ok, err)
+	}
+}
+
+func TestPreCommitHookRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+
+	// A fake archon whose review exits with the given status
+	run := func(t *testing.T, reviewStatus int, chained string) error {
+		t.Helper()
+		hooks := t.TempDir()
+		archon := filepath.Join(hooks, "archon")
+		write(t, archon, fmt.Sprintf("#!/bin/sh\necho \"archon $*\" >> %s\nexit %d\n", shellQuote(log), reviewStatus))
+		os.Chmod(archon, 0o755)
+		path := filepath.Join(hooks, "pre-commit")
+		if chained != "" {
+			write(t, path, chained)
+			os.Chmod(path, 0o755)
+		}
+		if _, err := installHook(path, fmt.Sprintf(preCommitHook, hookMarker, "high", chainedSuffix, shellQuote(archon), "high")); err != nil {
+			t.Fatal(err)
+		}
+		os.Remove(log)
+		cmd := exec.Command(path)
+		cmd.Env = append(os.Environ(), "ARCHON_SKIP_HOOKS=")
+		return cmd.Run()
+	}
+	exitCode := func(err error) int {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return 0
+	}
+
+	if code := exitCode(run(t, 0, "")); code != 0 {
+		t.Errorf("clean review: hook exited %d, want 0", code)
+	}
+	if got := read(t, log); got != "archon review --fail-on high\n" {
+		t.Errorf("archon ran as %q", got)
+	}
+	if code := exitCode(run(t, 2, "")); code != 1 {
+		t.Errorf("review with findings: hook exited %d, want 1 to block the commit", code)
+	}
+	// A chained hook runs first and its failure stops the commit before the review
+	chained := fmt.Sprintf("#!/bin/sh\necho chained >> %s\nexit 3\n", shellQuote(log))
+	if code := exitCode(run(t, 0, chained)); code != 3 {
+		t.Errorf("failing chained hook: hook exited %d, want 3", code)
+	}
+	if got := read(t, log); strings.Contains(got, "archon") || got != "chained\n" {
+		t.Errorf("log = %q, want only the chained hook run", got)
+	}
+}
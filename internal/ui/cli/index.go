@@ -2,75 +2,160 @@ package cli
 
 import (
 	"archon/internal/config"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/core"
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	watch bool
-	force bool
+	watch    bool
+	force    bool
+	debounce time.Duration
 )
 
 var indexCmd = &cobra.Command{
 	Use:   "index",
 	Short: "Scan and index the codebase",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Indexing codebase...")
-		ctx := context.Background()
+		logf("Indexing codebase...\n")
+		// Ctrl+C stops indexing cleanly, keeping whatever was stored so far
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			fmt.Printf("Error loading config: %v\n", err)
-			return
-		}
-
-		if cfg.GeminiKey == "" {
-			fmt.Println("Error: Gemini API key not found. Use 'archon auth' to set it.")
-			return
+			newReport("index", nil).fail("Error loading config", err)
 		}
+		result := newReport("index", cfg)
 
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Error creating store: %v\n", err)
-			return
+			result.fail("Error creating store", err)
 		}
 		defer store.Close()
 
 		if force {
-			fmt.Println("Force flag set, clearing existing index...")
+			logf("Force flag set, clearing existing index...\n")
 			err = store.Clear(ctx)
 			if err != nil {
-				fmt.Printf("Error clearing store: %v\n", err)
-				return
+				result.fail("Error clearing store", err)
 			}
+		} else if err := store.Compatible(); err != nil {
+			result.fail("Error", err)
 		}
 
 		orchestrator := core.NewOrchestrator(store)
-		err = orchestrator.IndexDirectory(ctx, ".", func(current, total int, file string) {
-			fmt.Printf("[%d/%d] Indexing %s...\n", current, total, file)
+		report, err := orchestrator.IndexDirectory(ctx, ".", func(current, total int, file string) {
+			logf("[%d/%d] %s\n", current, total, file)
 		})
+		if structuredOutput() {
+			if report != nil {
+				result.Data = newIndexInfo(report)
+				result.markdown = func() string { return "```\n" + strings.TrimSpace(indexReportText(report)) + "\n```" }
+			}
+		} else {
+			printIndexReport(report)
+		}
 		if err != nil {
-			fmt.Printf("Error indexing: %v\n", err)
-			return
+			result.fail("Error indexing", err)
+		}
+		if structuredOutput() {
+			result.emit()
 		}
-		fmt.Println("Indexing complete.")
 
 		if watch {
-			err = orchestrator.WatchDirectory(ctx, ".")
-			if err != nil {
-				fmt.Printf("Error watching directory: %v\n", err)
-				return
+			if debounce == 0 {
+				debounce = time.Duration(cfg.WatchDebounceMs) * time.Millisecond
+			}
+			logf("Watching for changes...\n")
+			err = orchestrator.WatchDirectory(ctx, ".", core.WatchOptions{
+				Debounce: debounce,
+				OnEvent: func(ev core.WatchEvent) {
+					switch ev.Type {
+					case core.WatchIndexed:
+						logf("File changed: %s, re-indexed\n", ev.Path)
+					case core.WatchRemoved:
+						logf("File removed: %s, purged from index\n", ev.Path)
+					case core.WatchError:
+						logf("Watcher error: %s %v\n", ev.Path, ev.Err)
+					}
+				},
+			})
+			if err != nil && ctx.Err() == nil {
+				logf("Error watching directory: %v\n", err)
+				os.Exit(1)
 			}
+			return
+		}
+		if report.Failed > 0 {
+			os.Exit(1)
 		}
 	},
 }
 
+// indexInfo is the data of `archon index --output json`.
+type indexInfo struct {
+	Total      int              `json:"total"`
+	Indexed    int              `json:"indexed"`
+	Skipped    int              `json:"skipped"`
+	Removed    int              `json:"removed"`
+	Failed     int              `json:"failed"`
+	Errors     []indexFileError `json:"errors"`
+	DurationMs int64            `json:"duration_ms"`
+}
+
+type indexFileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+func newIndexInfo(report *core.IndexReport) *indexInfo {
+	info := &indexInfo{
+		Total:      report.Total,
+		Indexed:    report.Indexed,
+		Skipped:    report.Skipped,
+		Removed:    report.Removed,
+		Failed:     report.Failed,
+		Errors:     []indexFileError{},
+		DurationMs: report.Duration.Milliseconds(),
+	}
+	for _, fe := range report.Errors {
+		info.Errors = append(info.Errors, indexFileError{Path: fe.Path, Error: fe.Err.Error()})
+	}
+	return info
+}
+
+func printIndexReport(report *core.IndexReport) {
+	fmt.Print(indexReportText(report))
+}
+
+func indexReportText(report *core.IndexReport) string {
+	if report == nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nIndexing summary (%s):\n", report.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "  Files:   %d\n", report.Total)
+	fmt.Fprintf(&b, "  Indexed: %d\n", report.Indexed)
+	fmt.Fprintf(&b, "  Skipped: %d (unchanged)\n", report.Skipped)
+	fmt.Fprintf(&b, "  Removed: %d\n", report.Removed)
+	fmt.Fprintf(&b, "  Failed:  %d\n", report.Failed)
+	for _, fe := range report.Errors {
+		fmt.Fprintf(&b, "    - %s: %v\n", fe.Path, fe.Err)
+	}
+	return b.String()
+}
+
 func init() {
 	indexCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for file changes")
 	indexCmd.Flags().BoolVarP(&force, "force", "f", false, "Force re-indexing of all files")
+	indexCmd.Flags().DurationVar(&debounce, "debounce", 0, "Quiet period before a changed file is re-indexed in watch mode (default 500ms or watch_debounce_ms)")
 	rootCmd.AddCommand(indexCmd)
 }
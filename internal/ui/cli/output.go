@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"archon/internal/config"
+	"archon/internal/core"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputMarkdown = "markdown"
+)
+
+// reportVersion is bumped whenever a field of report changes meaning or is removed.
+const reportVersion = 1
+
+// outputFormat is the value of the global --output flag.
+var outputFormat = outputText
+
+// structuredOutput reports whether the result is printed as one JSON or
+// Markdown document at the end, instead of as text while the command runs.
+func structuredOutput() bool {
+	return outputFormat != outputText
+}
+
+// logToStderr sends progress to stderr for commands whose stdout is read by
+// another program, like commit --message-only in a git hook.
+var logToStderr bool
+
+// logf prints progress. It goes to stderr with --output json or markdown, so
+// that stdout holds only the document.
+func logf(format string, args ...any) {
+	if structuredOutput() || logToStderr {
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// report is the result of a command as printed by --output json. Its fields
+// are a stable interface for scripts; new fields may be added.
+type report struct {
+	Version   int        `json:"version"`
+	Command   string     `json:"command"`
+	Provider  string     `json:"provider,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	Answer    string     `json:"answer,omitempty"`
+	Citations []citation `json:"citations"`
+	Usage     *usage     `json:"usage,omitempty"`
+	CacheUsed bool       `json:"cache_used"`
+	// Data holds the result of commands that do not answer with text, like status and index.
+	Data     any      `json:"data,omitempty"`
+	Warnings []string `json:"warnings"`
+	Errors   []string `json:"errors"`
+
+	// markdown renders Data for --output markdown.
+	markdown func() string
+}
+
+// citation is a piece of code that was sent to the model as context.
+type citation struct {
+	File      string  `json:"file"`
+	Name      string  `json:"name,omitempty"`
+	Type      string  `json:"type,omitempty"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Score     float32 `json:"score"`
+}
+
+type usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	AnswerTokens int `json:"answer_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func newReport(command string, cfg *config.Config) *report {
+	r := &report{
+		Version:   reportVersion,
+		Command:   command,
+		Citations: []citation{},
+		Warnings:  []string{},
+		Errors:    []string{},
+	}
+	if cfg != nil {
+		r.Provider, r.Model = cfg.Provider, cfg.ModelID
+	}
+	return r
+}
+
+// addContext records the snippets of a retrieved context as citations.
+func (r *report) addContext(res *core.ContextResult) {
+	if res == nil {
+		return
+	}
+	for _, snip := range res.Snippets {
+		r.Citations = append(r.Citations, citation{
+			File:      snip.File,
+			Name:      snip.Name,
+			Type:      snip.Type,
+			StartLine: snip.StartLine,
+			EndLine:   snip.EndLine,
+			Score:     snip.Score,
+		})
+	}
+}
+
+// addResponse adds the text of resp to the answer and counts its tokens.
+func (r *report) addResponse(resp *core.Response) {
+	if r.Answer != "" && !strings.HasSuffix(r.Answer, "\n") {
+		r.Answer += "\n\n"
+	}
+	r.Answer += resp.Text
+	r.addUsage(resp)
+}
+
+// addUsage counts the tokens of resp.
+func (r *report) addUsage(resp *core.Response) {
+	if r.Usage == nil {
+		r.Usage = &usage{}
+	}
+	r.Usage.PromptTokens += resp.PromptTokens
+	r.Usage.AnswerTokens += resp.AnswerTokens
+	r.Usage.TotalTokens += resp.TotalTokens
+}
+
+// warn prints a warning, and keeps it in the report.
+func (r *report) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	r.Warnings = append(r.Warnings, msg)
+	logf("Warning: %s\n", msg)
+}
+
+// fail ends the command with err: as a report with --output json or markdown,
+// otherwise as "prefix: err" and its hint.
+func (r *report) fail(prefix string, err error) {
+	r.Errors = append(r.Errors, err.Error())
+	if structuredOutput() {
+		r.emit()
+	} else {
+		fmt.Printf("%s: %s\n", prefix, describeError(err))
+	}
+	os.Exit(1)
+}
+
+// answer generates the answer to prompt. As text it is printed while it
+// arrives, after header; otherwise it is only recorded in the report.
+func (r *report) answer(ctx context.Context, client core.LLM, prompt, header string) (*core.Response, error) {
+	var resp *core.Response
+	var err error
+	if structuredOutput() {
+		resp, err = core.CollectStream(client.GenerateStream(ctx, prompt), nil)
+	} else {
+		resp, err = streamAnswer(ctx, client, prompt, header)
+	}
+	if err == nil {
+		r.addResponse(resp)
+	}
+	return resp, err
+}
+
+// emit prints the report in the --output format. Text output is printed by
+// the command itself, so there is nothing to do for it.
+func (r *report) emit() {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	case outputMarkdown:
+		fmt.Print(r.renderMarkdown())
+	}
+}
+
+func (r *report) renderMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# archon %s\n\n", r.Command)
+	if r.Answer != "" {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(r.Answer))
+	}
+	if r.markdown != nil {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(r.markdown()))
+	}
+	if len(r.Citations) > 0 {
+		b.WriteString("## Sources\n\n")
+		for _, c := range r.Citations {
+			fmt.Fprintf(&b, "- `%s:%d-%d`", c.File, c.StartLine, c.EndLine)
+			if c.Name != "" {
+				fmt.Fprintf(&b, " %s", c.Name)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "> **Warning:** %s\n\n", w)
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(&b, "> **Error:** %s\n\n", e)
+	}
+	if r.Usage != nil {
+		fmt.Fprintf(&b, "_Model: %s, tokens used: %d", r.Model, r.Usage.TotalTokens)
+		if r.CacheUsed {
+			b.WriteString(", context cache used")
+		}
+		b.WriteString("_\n")
+	}
+	return b.String()
+}
+
+// checkOutputFormat validates the --output flag for cmd. Only the commands
+// that print a report accept a format other than text.
+func checkOutputFormat(cmd *cobra.Command) error {
+	switch outputFormat {
+	case outputText:
+		return nil
+	case outputJSON, outputMarkdown:
+		for _, c := range []*cobra.Command{askCmd, reviewCmd, explainCmd, analyzeCmd, statusCmd, indexCmd} {
+			if cmd == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s does not support --output %s", cmd.CommandPath(), outputFormat)
+	}
+	return fmt.Errorf("--output must be text, json or markdown, not %q", outputFormat)
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"archon/internal/adapters/provider"
+	"archon/internal/config"
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runRefactorPlan asks the model for an edit plan spanning files, shows it as a
+// diff and applies it as a whole: if formatting or the build fails afterwards,
+// every file of the plan is restored.
+func runRefactorPlan(cfg *config.Config, files []string, goal string, yes, build bool) {
+	ctx := context.Background()
+
+	// The plan is resolved against what the model was shown, so checkUnchanged
+	// catches files edited while it was thinking
+	var b strings.Builder
+	snapshot := make(map[string][]byte)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		snapshot[filepath.Clean(file)] = content
+		fmt.Fprintf(&b, "File: %s\n```\n%s\n```\n\n", filepath.ToSlash(file), string(content))
+	}
+
+	store, err := provider.NewStore(ctx, cfg)
+	var contextText string
+	if err == nil {
+		defer store.Close()
+		orchestrator := core.NewOrchestrator(store)
+		orchestrator.SetSearchBlend(cfg.SearchBlend)
+		fmt.Println("Gathering context...")
+		contextText, _ = gatherContext(ctx, orchestrator, cfg, "refactor", "refactor "+strings.Join(files, " ")+" with goal "+goal, core.SearchOptions{})
+	}
+
+	client, err := provider.NewLLM(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	prompt := fmt.Sprintf("%s\n\nTask: Perform refactoring across the following files. You may change any of them and create new files.\nGoal: %s\n\n%s%s",
+		contextText, goal, b.String(), core.EditPlanFormat)
+
+	fmt.Println("Planning the refactoring...")
+	resp, err := client.Generate(ctx, prompt)
+	if err != nil {
+		fmt.Printf("Error: %s\n", describeError(err))
+		os.Exit(1)
+	}
+
+	plan, err := core.ParseEditPlan(resp.Text)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println(resp.Text)
+		os.Exit(1)
+	}
+	changes, err := plan.Resolve(func(path string) ([]byte, error) {
+		if content, ok := snapshot[filepath.Clean(path)]; ok {
+			return content, nil
+		}
+		return os.ReadFile(path)
+	})
+	if err != nil {
+		fmt.Printf("Error: the edit plan does not fit the current files: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%s\n\n", plan.Summary)
+	if !previewChanges(changes) {
+		fmt.Println("The plan leaves every file unchanged.")
+		return
+	}
+	if !confirmApply(yes) {
+		fmt.Println("Refactoring not applied.")
+		return
+	}
+
+	entry, err := applyChanges("refactor", changes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	journal := core.NewBackupJournal(core.DefaultBackupDir)
+	rollback := func(reason, output string) {
+		if output != "" {
+			fmt.Println(tail(output, maxFailureOutput))
+		}
+		if err := journal.Restore(entry); err != nil {
+			fmt.Printf("Error: %s, and restoring the files failed: %v (backup %s)\n", reason, err, entry.ID)
+			os.Exit(1)
+		}
+		fmt.Printf("Error: %s, the plan was rolled back\n", reason)
+		os.Exit(1)
+	}
+
+	for _, change := range changes {
+		if out, err := formatFile(ctx, cfg.FormatCommand, change.Path); err != nil {
+			rollback(fmt.Sprintf("formatting %s failed: %v", change.Path, err), out)
+		}
+	}
+	if build {
+		if command := buildCommand(cfg.BuildCommand); command != nil {
+			fmt.Printf("Running %s...\n", strings.Join(command, " "))
+			if out, err := runCommand(ctx, command); err != nil {
+				reason := "the build fails with the plan applied (use --no-build to skip this check)"
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) {
+					reason = err.Error()
+				}
+				rollback(reason, out)
+			}
+		}
+	}
+	if err := journal.Refresh(entry); err != nil {
+		fmt.Printf("Warning: backup not updated after formatting: %v\n", err)
+	}
+
+	fmt.Printf("✅ Applied the plan to %d files (undo with 'archon undo', backup %s)\n", len(changes), entry.ID)
+	fmt.Printf("(Tokens used: %d)\n", resp.TotalTokens)
+}
+
+// formatFile runs format_command on file, or gofmt for Go files when it is not
+// configured. A missing gofmt is not an error.
+func formatFile(ctx context.Context, template, file string) (string, error) {
+	if template == "" {
+		if filepath.Ext(file) != ".go" {
+			return "", nil
+		}
+		if _, err := exec.LookPath("gofmt"); err != nil {
+			return "", nil
+		}
+		template = "gofmt -w {file}"
+	}
+	if !strings.Contains(template, "{file}") {
+		template += " {file}"
+	}
+
+	out, err := runCommand(ctx, expandCommand(template, "{file}", file))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, fmt.Errorf("exit status %d", exitErr.ExitCode())
+	}
+	return out, err
+}
+
+// buildCommand returns build_command, or "go build ./..." in a Go module when
+// it is not configured. nil means there is nothing to check.
+func buildCommand(template string) []string {
+	if template == "" {
+		if _, err := os.Stat("go.mod"); err != nil {
+			return nil
+		}
+		template = "go build ./..."
+	}
+	return expandCommand(template)
+}
@@ -1,10 +1,10 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -14,9 +14,26 @@ import (
 )
 
 var refactorCmd = &cobra.Command{
-	Use:   "refactor [file]",
+	Use:   "refactor [file]...",
 	Short: "Analyze and suggest refactorings for a file",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Suggests refactorings for a file, or applies them with --apply. Several files
+can only be refactored together with --plan.
+
+With --plan, the model may change all the given files and create new ones. It answers
+with an edit plan that is checked against the current files, shown as a diff and applied
+as a whole; the changed files are then formatted (format_command, gofmt by default) and
+the project is built (build_command, "go build ./..." in Go modules). If either fails,
+every file is restored.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		// Only an edit plan can span several files
+		if plan, _ := cmd.Flags().GetBool("plan"); plan {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		if len(args) > 1 {
+			return fmt.Errorf("refactoring several files needs --plan, got %d files", len(args))
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		filePath := args[0]
 		goal, _ := cmd.Flags().GetString("goal")
@@ -28,24 +45,36 @@ var refactorCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if plan, _ := cmd.Flags().GetBool("plan"); plan {
+			yes, _ := cmd.Flags().GetBool("yes")
+			noBuild, _ := cmd.Flags().GetBool("no-build")
+			runRefactorPlan(cfg, args, goal, yes, !noBuild)
+			return
+		}
+
 		ctx := context.Background()
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		var contextText string
 		if err == nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
 			fmt.Println("Gathering context...")
-			contextText, _ = orchestrator.SearchContext(ctx, "refactor "+filePath+" with goal "+goal)
+			contextText, _ = gatherContext(ctx, orchestrator, cfg, "refactor", "refactor "+filePath+" with goal "+goal, core.SearchOptions{})
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		defer client.Close()
 
-		content, _ := os.ReadFile(filePath)
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 		
 		prompt := fmt.Sprintf("%s\n\nTask: Perform refactoring on the following file: %s\nGoal: %s\n\nCode:\n```\n%s\n```", 
 			contextText, filePath, goal, string(content))
@@ -55,9 +84,9 @@ var refactorCmd = &cobra.Command{
 		}
 
 		fmt.Println("Analyzing and refactoring...")
-		resp, err := client.Ask(ctx, prompt)
+		resp, err := client.Generate(ctx, prompt)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", describeError(err))
 			os.Exit(1)
 		}
 
@@ -69,13 +98,29 @@ var refactorCmd = &cobra.Command{
 				fmt.Println(resp.Text)
 				return
 			}
+			if bytes.HasSuffix(content, []byte("\n")) && !strings.HasSuffix(newCode, "\n") {
+				newCode += "\n"
+			}
 
-			err = os.WriteFile(filePath, []byte(newCode), 0644)
-			if err != nil {
-				fmt.Printf("Failed to write to file: %v\n", err)
+			changes := []core.FileChange{{Path: filePath, Existed: true, Old: content, New: []byte(newCode)}}
+			fmt.Println()
+			if !previewChanges(changes) {
+				fmt.Println("The refactoring leaves the file unchanged.")
 				return
 			}
-			fmt.Printf("✅ Successfully applied refactoring to %s\n", filePath)
+			yes, _ := cmd.Flags().GetBool("yes")
+			if !confirmApply(yes) {
+				fmt.Println("Refactoring not applied.")
+				return
+			}
+
+			entry, err := applyChanges("refactor", changes)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Successfully applied refactoring to %s (undo with 'archon undo', backup %s)\n", filePath, entry.ID)
+			fmt.Printf("(Tokens used: %d)\n", resp.TotalTokens)
 		} else {
 			fmt.Printf("\nRefactoring Suggestions:\n%s\n", resp.Text)
 			fmt.Printf("\n(Tokens used: %d)\n", resp.TotalTokens)
@@ -109,6 +154,9 @@ func extractCode(resp string) string {
 
 func init() {
 	refactorCmd.Flags().String("goal", "improve code quality and performance", "Specific goal for refactoring")
-	refactorCmd.Flags().Bool("apply", false, "Apply refactoring directly to the file")
+	refactorCmd.Flags().Bool("apply", false, "Apply refactoring directly to the file, after showing the diff")
+	refactorCmd.Flags().BoolP("yes", "y", false, "Apply without asking for confirmation")
+	refactorCmd.Flags().Bool("plan", false, "Refactor across all given files with an edit plan, applied atomically")
+	refactorCmd.Flags().Bool("no-build", false, "Do not build the project after applying an edit plan")
 	rootCmd.AddCommand(refactorCmd)
 }
@@ -2,11 +2,12 @@ package cli
 
 import (
 	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"archon/internal/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -17,52 +18,99 @@ import (
 var reviewCmd = &cobra.Command{
 	Use:   "review",
 	Short: "Review staged changes using AI",
-	Long:  `Analyzes staged code changes (git add) and provides feedback regarding quality, potential bugs, and architectural compliance.`,
+	Long: `Analyzes staged code changes (git add) and provides feedback regarding quality, potential bugs, and architectural compliance.
+
+--base reviews a whole branch against the ref it started from (e.g. main), --range a revision
+range and --unstaged the changes not staged yet; --files limits the review to some paths.
+Diffs larger than diff_chunk_tokens are reviewed in parts, split between files.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			newReport("review", nil).fail("Error", err)
 		}
+		result := newReport("review", cfg)
 
 		ctx := context.Background()
 
-		// Ambil diff staged
-		diffOutput, err := utils.GetStagedDiff()
+		diffOpts, err := diffOptions(cmd)
+		if err != nil {
+			result.fail("Error", err)
+		}
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		if failOn != "" {
+			severity, ok := core.ParseSeverity(failOn)
+			if !ok {
+				result.fail("Error", fmt.Errorf("--fail-on must be critical, high, medium, low or info, not %q", failOn))
+			}
+			failOn = severity
+		}
+		diffOutput, err := utils.GetDiff(diffOpts)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			result.fail("Error", err)
 		}
 
 		if strings.TrimSpace(diffOutput) == "" {
-			fmt.Println("No staged changes (git add) to review.")
+			result.Data = reviewData{Findings: []core.Finding{}}
+			if diffOpts.Staged() {
+				logf("No staged changes (git add) to review.\n")
+			} else {
+				logf("No %s to review.\n", diffOpts)
+			}
+			result.emit()
 			return
 		}
 
 		// Inisialisasi store untuk RAG (opsional tapi bagus untuk konteks)
-		store, _ := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, _ := provider.NewStore(ctx, cfg)
 		var contextText string
 		if store != nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
-			// Cari konteks berdasarkan file yang berubah
-			contextText, _ = orchestrator.SearchContext(ctx, "Review changes in these files")
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			// Cari konteks berdasarkan simbol yang berubah dan pemanggilnya
+			if res, _, err := changeContext(ctx, orchestrator, cfg, "review", diffOutput, diffOpts, core.SearchOptions{}); err == nil {
+				result.addContext(res)
+				contextText = res.Text
+			}
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			result.fail("Error", err)
 		}
 		defer client.Close()
 
 		// Gunakan cache jika tersedia
-		hash, err := gemini.CalculateProjectHash(".")
-		if err == nil && cfg.CacheName != "" && cfg.ProjectHash == hash {
-			client.SetCachedContent(cfg.CacheName)
+		if geminiClient, ok := client.(*gemini.Client); ok {
+			hash, err := gemini.CalculateProjectHash(".")
+			if err == nil && cfg.CacheName != "" && cfg.ProjectHash == hash {
+				geminiClient.SetCachedContent(cfg.CacheName)
+				result.CacheUsed = true
+			}
 		}
 
-		prompt := fmt.Sprintf(`%s
+		chunks := diffChunks(cfg, diffOutput)
+		if len(chunks) > 1 {
+			logf("The diff is large, reviewing it in %d parts.\n", len(chunks))
+		}
+
+		logf("🚀 Analyzing your changes...\n")
+		var all []core.Finding
+		var summaries []string
+		// unread counts the parts whose answer could not be parsed
+		unread := 0
+		for i, chunk := range chunks {
+			part := ""
+			if len(chunks) > 1 {
+				var files []string
+				for _, f := range utils.SplitDiff(chunk) {
+					files = append(files, f.Path)
+				}
+				logf("Reviewing part %d/%d (%s)...\n", i+1, len(chunks), strings.Join(files, ", "))
+				part = fmt.Sprintf("This is part %d of %d of the diff; review only this part.\n", i+1, len(chunks))
+			}
+
+			prompt := fmt.Sprintf(`%s
 
 Task: Perform a deep code review on the following changes (diff). 
 Focus on:
@@ -70,22 +118,159 @@ Focus on:
 2. Compliance with best practices (Clean Code, SOLID).
 3. Code smells or unnecessary complexity.
 4. Concrete improvement suggestions.
+%s
+Diff (each line of the new version is prefixed with its line number):
+%s
 
-Diff:
-%s`, contextText, string(diffOutput))
+%s`, contextText, part, utils.NumberDiff(chunk), core.ReviewFormat)
 
-		fmt.Println("🚀 Analyzing your changes...")
-		resp, err := client.Ask(ctx, prompt)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			// The answer is JSON, so it is parsed once complete rather than printed
+			label := "Reviewing"
+			if len(chunks) > 1 {
+				label = fmt.Sprintf("Reviewing part %d/%d", i+1, len(chunks))
+			}
+			resp, err := collectStream(client.GenerateStream(ctx, prompt), label)
+			if err != nil {
+				result.fail("Error", err)
+			}
+			result.addUsage(resp)
+
+			review, err := core.ParseReview(resp.Text)
+			if err != nil {
+				result.warn("part %d of the review could not be read: %v", i+1, err)
+				unread++
+				continue
+			}
+			if summary := strings.TrimSpace(review.Summary); summary != "" {
+				summaries = append(summaries, summary)
+			}
+			all = append(all, review.Findings...)
+		}
+		if len(summaries) == 0 && len(all) == 0 {
+			result.fail("Error", fmt.Errorf("the model did not answer with a structured review"))
+		}
+
+		findings, dropped := core.ValidateFindings(all, utils.ChangedRanges(diffOutput))
+		if len(dropped) > 0 {
+			result.warn("%d findings dropped: they do not point into the changed lines", len(dropped))
+		}
+		if findings == nil {
+			findings = []core.Finding{}
+		}
+		data := reviewData{Summary: strings.Join(summaries, "\n\n"), Findings: findings, Dropped: len(dropped)}
+		result.Answer = data.Summary
+		result.Data = data
+		result.markdown = func() string { return renderFindings(findings, true) }
+
+		if path, _ := cmd.Flags().GetString("sarif"); path != "" {
+			out, err := core.SARIF(findings, version)
+			if err == nil {
+				err = writeGenerated(path, string(out))
+			}
+			if err != nil {
+				result.fail("Error writing SARIF", err)
+			}
+			logf("SARIF report written to %s\n", path)
+		}
+		if path, _ := cmd.Flags().GetString("findings-json"); path != "" {
+			out, err := json.MarshalIndent(data, "", "  ")
+			if err == nil {
+				err = writeGenerated(path, string(out))
+			}
+			if err != nil {
+				result.fail("Error writing findings", err)
+			}
+			logf("Findings written to %s\n", path)
 		}
 
-		fmt.Printf("\nAI Code Review:\n%s\n", resp.Text)
-		fmt.Printf("\n(Tokens used: %d)\n", resp.TotalTokens)
+		if !structuredOutput() {
+			fmt.Printf("\nAI Code Review:\n%s\n\n%s", data.Summary, renderFindings(findings, false))
+		}
+		logf("\n(Tokens used: %d)\n", result.Usage.TotalTokens)
+		result.emit()
+
+		if failOn != "" {
+			for _, f := range findings {
+				if core.SeverityRank(f.Severity) >= core.SeverityRank(failOn) {
+					logf("Failing: findings of severity %s or higher.\n", failOn)
+					os.Exit(2)
+				}
+			}
+			// Findings of the parts that could not be read are unknown
+			if unread > 0 {
+				logf("Failing: %d part(s) of the review could not be read.\n", unread)
+				os.Exit(1)
+			}
+		}
 	},
 }
 
+// reviewData is the data of `archon review --output json` and --findings-json.
+type reviewData struct {
+	Summary  string         `json:"summary"`
+	Findings []core.Finding `json:"findings"`
+	// Dropped counts the findings left out because they did not point into the diff.
+	Dropped int `json:"dropped"`
+}
+
+var severityIcons = map[string]string{
+	core.SeverityCritical: "🛑",
+	core.SeverityHigh:     "🔴",
+	core.SeverityMedium:   "🟠",
+	core.SeverityLow:      "🟡",
+	core.SeverityInfo:     "🔵",
+}
+
+// renderFindings lists findings grouped by file, as Markdown or as plain text.
+func renderFindings(findings []core.Finding, markdown bool) string {
+	var b strings.Builder
+	counts := make(map[string]int)
+	file := ""
+	for _, f := range findings {
+		counts[f.Severity]++
+		if f.File != file {
+			file = f.File
+			if markdown {
+				fmt.Fprintf(&b, "### %s\n\n", file)
+			} else {
+				fmt.Fprintf(&b, "%s\n", file)
+			}
+		}
+		lines := fmt.Sprintf("L%d", f.StartLine)
+		if f.EndLine > f.StartLine {
+			lines += fmt.Sprintf("-%d", f.EndLine)
+		}
+		if markdown {
+			fmt.Fprintf(&b, "- %s **%s** %s (%s): %s\n", severityIcons[f.Severity], strings.ToUpper(f.Severity), lines, f.Category, f.Message)
+			if f.Suggestion != "" {
+				fmt.Fprintf(&b, "\n  Suggested fix:\n\n  ```\n  %s\n  ```\n", strings.ReplaceAll(strings.TrimSpace(f.Suggestion), "\n", "\n  "))
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "  %s %-8s %-9s [%s] %s\n", severityIcons[f.Severity], strings.ToUpper(f.Severity), lines, f.Category, f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(&b, "      Fix: %s\n", strings.ReplaceAll(strings.TrimSpace(f.Suggestion), "\n", "\n           "))
+		}
+	}
+	if len(findings) == 0 {
+		b.WriteString("No issues found in the changed lines.\n")
+		return b.String()
+	}
+
+	var parts []string
+	for _, s := range []string{core.SeverityCritical, core.SeverityHigh, core.SeverityMedium, core.SeverityLow, core.SeverityInfo} {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	fmt.Fprintf(&b, "\n%d findings (%s)\n", len(findings), strings.Join(parts, ", "))
+	return b.String()
+}
+
 func init() {
+	addDiffFlags(reviewCmd)
+	reviewCmd.Flags().String("sarif", "", "Write the findings to this file as SARIF 2.1.0")
+	reviewCmd.Flags().String("findings-json", "", "Write the summary and findings to this file as JSON")
+	reviewCmd.Flags().String("fail-on", "", "Exit with status 2 if a finding has this severity or higher (critical, high, medium, low, info)")
 	rootCmd.AddCommand(reviewCmd)
 }
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"archon/internal/config"
 	"archon/internal/ui/tui"
+	"archon/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +15,17 @@ var rootCmd = &cobra.Command{
 	Short: "ArchonCLI - AI Architect Assistant for your codebase",
 	Long: `ArchonCLI is a revolutionary CLI & TUI tool designed to interact with complex codebases 
 using semantic syntax-aware indexing and Google Gemini 3.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := checkOutputFormat(cmd); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		// The ignore list is process-wide, so it is set once here rather than by
+		// every LoadConfig. A broken config is reported by the command itself.
+		if cfg, err := config.LoadConfig(); err == nil {
+			utils.SetIgnorePatterns(cfg.Ignore)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no arguments, start TUI mode
 		if len(args) == 0 {
@@ -28,3 +41,7 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format of ask, review, explain, analyze, status and index: text, json or markdown")
+}
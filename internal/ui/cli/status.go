@@ -1,49 +1,119 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"archon/internal/config"
 	"archon/internal/adapters/gemini"
+	"archon/internal/adapters/parser"
+	"archon/internal/adapters/provider"
 	"github.com/spf13/cobra"
 )
 
+// statusInfo is the data of `archon status --output json`.
+type statusInfo struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Endpoint         string `json:"endpoint,omitempty"`
+	APIKeyConfigured bool   `json:"api_key_configured"`
+	VectorDB         bool   `json:"vector_db"`
+	Embedder         string `json:"embedder,omitempty"`
+	EmbedderError    string `json:"embedder_error,omitempty"`
+	KeywordDocuments int    `json:"keyword_documents"`
+	// Parser is the symbol extractor: "tree-sitter", or the regex fallback of builds without cgo.
+	Parser           string `json:"parser"`
+	ProjectHash      string `json:"project_hash,omitempty"`
+	CacheName        string `json:"cache_name,omitempty"`
+	// CacheStatus is "active", "stale" (the project changed) or "none".
+	CacheStatus string `json:"cache_status"`
+}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show Archon status",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg, _ := config.LoadConfig()
-		fmt.Printf("Archon Status:\n")
-		fmt.Printf("- Model: %s\n", cfg.ModelID)
-		if cfg.GeminiKey != "" {
-			fmt.Printf("- API Key: Configured\n")
+		info := statusInfo{Provider: cfg.Provider, Model: cfg.ModelID, APIKeyConfigured: cfg.GeminiKey != ""}
+		var lines []string
+		add := func(format string, args ...any) {
+			lines = append(lines, fmt.Sprintf("- "+format, args...))
+		}
+
+		add("Provider: %s", cfg.Provider)
+		add("Model: %s", cfg.ModelID)
+		if cfg.Provider == config.ProviderOpenAI {
+			info.Endpoint = cfg.OpenAIBaseURL
+			info.APIKeyConfigured = cfg.OpenAIKey != ""
+			add("Endpoint: %s", cfg.OpenAIBaseURL)
+		} else if cfg.GeminiKey != "" {
+			add("API Key: Configured")
 		} else {
-			fmt.Printf("- API Key: NOT Configured\n")
+			add("API Key: NOT Configured")
 		}
 		
 		if _, err := os.Stat("./chromem_db"); err == nil {
-			fmt.Printf("- Vector DB: Ready (chromem_db)\n")
+			info.VectorDB = true
+			add("Vector DB: Ready (chromem_db)")
+			store, err := provider.NewStore(context.Background(), cfg)
+			if err == nil {
+				info.Embedder = store.EmbedderName()
+				if err := store.Compatible(); err != nil {
+					info.EmbedderError = err.Error()
+					add("Embedder: %s (%v)", store.EmbedderName(), err)
+				} else if !store.HasEmbedder() {
+					info.Embedder = config.EmbedderNone
+					add("Embedder: none (keyword search only)")
+				} else {
+					add("Embedder: %s", store.EmbedderName())
+				}
+				info.KeywordDocuments = store.KeywordCount()
+				add("Keyword Index: %d documents", store.KeywordCount())
+				store.Close()
+			} else {
+				info.EmbedderError = err.Error()
+				add("Embedder: %s (%v)", cfg.Embedder, err)
+			}
 		} else {
-			fmt.Printf("- Vector DB: Not initialized (Use 'archon index')\n")
+			add("Vector DB: Not initialized (Use 'archon index')")
 		}
 
+		info.Parser = parser.Backend
+		add("Parser: %s", parser.Backend)
+
 		// Caching status
 		hash, _ := gemini.CalculateProjectHash(".")
 		if hash != "" {
-			fmt.Printf("- Project Hash: %s\n", hash[:8]+"...")
+			info.ProjectHash = hash
+			add("Project Hash: %s", hash[:8]+"...")
 		}
 		
 		cacheStatus := "Inactive"
+		info.CacheStatus = "none"
 		if cfg.CacheName != "" {
+			info.CacheName = cfg.CacheName
 			if cfg.ProjectHash == hash {
+				info.CacheStatus = "active"
 				cacheStatus = fmt.Sprintf("Active (%s)", cfg.CacheName)
 			} else {
+				info.CacheStatus = "stale"
 				cacheStatus = "Inactive (Hash Mismatch)"
 			}
 		} else {
 			cacheStatus = "Inactive (Not Created)"
 		}
-		fmt.Printf("- Context Cache: %s\n", cacheStatus)
+		add("Context Cache: %s", cacheStatus)
+
+		if !structuredOutput() {
+			fmt.Printf("Archon Status:\n%s\n", strings.Join(lines, "\n"))
+			return
+		}
+		result := newReport("status", cfg)
+		result.Data = info
+		result.CacheUsed = info.CacheStatus == "active"
+		result.markdown = func() string { return strings.Join(lines, "\n") }
+		result.emit()
 	},
 }
 
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamAnswer generates the answer to prompt and prints it while it arrives.
+// header is printed once, right before the first piece of text.
+func streamAnswer(ctx context.Context, client core.LLM, prompt, header string) (*core.Response, error) {
+	return printStream(client.GenerateStream(ctx, prompt), header)
+}
+
+// printStream prints the text of events while it arrives, preceded by header.
+func printStream(events <-chan core.StreamEvent, header string) (*core.Response, error) {
+	started := false
+	resp, err := core.CollectStream(events, func(delta string) {
+		if !started {
+			fmt.Print(header)
+			started = true
+		}
+		fmt.Print(delta)
+	})
+	if started {
+		fmt.Println()
+	}
+	return resp, err
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// collectStream collects an answer that is not printed as it arrives, like the
+// JSON of a review. On a terminal, a spinner with label and the size of the
+// answer so far shows that it is progressing; it is removed once done.
+func collectStream(events <-chan core.StreamEvent, label string) (*core.Response, error) {
+	out := os.Stdout
+	if structuredOutput() || logToStderr {
+		out = os.Stderr
+	}
+	if info, err := out.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return core.CollectStream(events, nil)
+	}
+
+	var mu sync.Mutex
+	received := 0
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			mu.Lock()
+			n := received
+			mu.Unlock()
+			status := "waiting for the model"
+			if n > 0 {
+				status = fmt.Sprintf("%d characters received", n)
+			}
+			fmt.Fprintf(out, "\r\033[K%s %s (%s)", spinnerFrames[frame%len(spinnerFrames)], label, status)
+			select {
+			case <-done:
+				fmt.Fprint(out, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	resp, err := core.CollectStream(events, func(delta string) {
+		mu.Lock()
+		received += len(delta)
+		mu.Unlock()
+	})
+	close(done)
+	wg.Wait()
+	return resp, err
+}
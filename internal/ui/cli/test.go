@@ -1,23 +1,38 @@
 package cli
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
+	"archon/internal/utils"
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
 
 var testCmd = &cobra.Command{
-	Use:   "test [file]",
+	Use:   "test [file|dir|dir/...|glob]...",
 	Short: "Generate unit tests for a file",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Generates unit tests for a file and prints them.
+
+With --all, several files, a directory (one package), "dir/..." (a directory tree) or a
+glob pattern, tests are generated for every source file and written next to it
+(foo_test.go, test_foo.py, foo.test.ts, ...). Files that already have a test file are
+skipped unless --overwrite is given.
+
+For a single file, --write writes the test file instead of printing it, and --verify also
+runs the project's test command (test_command, default "go test {pkg}/..." for Go). Compiler
+and test failures are sent back to the model for up to --max-fixes repairs; the test file
+is only kept if the tests pass.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		filePath := args[0]
+		all, _ := cmd.Flags().GetBool("all")
+		if len(args) == 0 && !all {
+			fmt.Println("Error: specify a file, directory or pattern, or use --all")
+			os.Exit(1)
+		}
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
@@ -25,17 +40,52 @@ var testCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		ctx := context.Background()
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		write, _ := cmd.Flags().GetBool("write")
+		verify, _ := cmd.Flags().GetBool("verify")
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+		if bulkMode(all, args) {
+			if verify {
+				fmt.Println("Error: --verify works on a single file")
+				os.Exit(1)
+			}
+			runTestBulk(cfg, args, all, overwrite)
+			return
+		}
+
+		filePath := args[0]
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		target := utils.TestFilePath(filePath)
+		previous, readErr := os.ReadFile(target)
+		existed := readErr == nil
+		var command []string
+		if write || verify {
+			if existed && !overwrite {
+				fmt.Printf("Error: %s already exists, use --overwrite to replace it\n", target)
+				os.Exit(1)
+			}
+			if verify {
+				command, err = testCommand(cfg.TestCommand, filePath, target)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		store, err := provider.NewStore(ctx, cfg)
 		var contextText string
 		if err == nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
 			fmt.Println("Gathering context...")
-			contextText, _ = orchestrator.SearchContext(ctx, "unit test for "+filePath)
+			contextText, _ = gatherContext(ctx, orchestrator, cfg, "test", "unit test for "+filePath, core.SearchOptions{})
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+		client, err := provider.NewLLM(ctx, cfg)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
@@ -43,20 +93,140 @@ var testCmd = &cobra.Command{
 		defer client.Close()
 
 		content, _ := os.ReadFile(filePath)
-		prompt := fmt.Sprintf("%s\n\nTask: Create comprehensive unit tests for the following file: %s\n\nCode:\n```\n%s\n```", 
-			contextText, filePath, string(content))
+		if !write && !verify {
+			prompt := fmt.Sprintf("%s\n\nTask: Create comprehensive unit tests for the following file: %s\n\nCode:\n```\n%s\n```", 
+				contextText, filePath, string(content))
+
+			fmt.Println("Generating tests...")
+			resp, err := client.Generate(ctx, prompt)
+			if err != nil {
+				fmt.Printf("Error: %s\n", describeError(err))
+				os.Exit(1)
+			}
+
+			fmt.Printf("\nGenerated Unit Tests for %s:\n%s\n", filePath, resp.Text)
+			return
+		}
 
 		fmt.Println("Generating tests...")
-		resp, err := client.Ask(ctx, prompt)
+		resp, err := client.Generate(ctx, testFilePrompt(contextText, filePath, target, string(content)))
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Printf("Error: %s\n", describeError(err))
 			os.Exit(1)
 		}
+		code := extractCode(resp.Text)
+		if code == "" {
+			fmt.Println("Failed to extract code from AI response:")
+			fmt.Println(resp.Text)
+			os.Exit(1)
+		}
+		if err := writeGenerated(target, code); err != nil {
+			fmt.Printf("Failed to write to file: %v\n", err)
+			os.Exit(1)
+		}
+		if !verify {
+			fmt.Printf("✅ Wrote tests to %s\n", target)
+			fmt.Printf("(Tokens used: %d)\n", resp.TotalTokens)
+			return
+		}
 
-		fmt.Printf("\nGenerated Unit Tests for %s:\n%s\n", filePath, resp.Text)
+		maxFixes, _ := cmd.Flags().GetInt("max-fixes")
+		tokens, passed, err := verifyTests(ctx, client, command, filePath, target, maxFixes)
+		tokens += resp.TotalTokens
+		if err != nil || !passed {
+			// Leave the tree as it was, the failing tests are of no use
+			if existed {
+				os.WriteFile(target, previous, 0644)
+				fmt.Printf("Restored the previous %s\n", target)
+			} else {
+				os.Remove(target)
+				fmt.Printf("Removed %s\n", target)
+			}
+			if err != nil {
+				fmt.Printf("Error: %s\n", describeError(err))
+			}
+			fmt.Printf("(Tokens used: %d)\n", tokens)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Tests pass, kept %s\n", target)
+		fmt.Printf("(Tokens used: %d)\n", tokens)
 	},
 }
 
+// testFilePrompt asks for a complete test file for file, to be saved as target.
+func testFilePrompt(contextText, file, target, content string) string {
+	prompt := fmt.Sprintf("%s\n\nTask: Create comprehensive unit tests for the following file: %s\nThe tests will be saved as %s.\n\nCode:\n```\n%s\n```", 
+		contextText, file, target, content)
+	return prompt + "\n\nRETURN ONLY THE COMPLETE TEST FILE in a single Markdown code block (```). Do not provide any explanation outside that code block because your output will be written directly to the file."
+}
+
+// runTestBulk writes a test file for every source file matched by args.
+func runTestBulk(cfg *config.Config, args []string, all, overwrite bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	files, err := resolveTargets(args, all)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("No source files found.")
+		return
+	}
+
+	client, err := provider.NewLLM(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	var orchestrator *core.Orchestrator
+	if store, err := provider.NewStore(ctx, cfg); err == nil {
+		defer store.Close()
+		orchestrator = core.NewOrchestrator(store)
+		orchestrator.SetSearchBlend(cfg.SearchBlend)
+	}
+
+	rows := runBulk(ctx, cfg, files, bulkTask{
+		skip: func(file string) string {
+			target := utils.TestFilePath(file)
+			if _, err := os.Stat(target); err == nil && !overwrite {
+				return "has tests in " + target
+			}
+			return ""
+		},
+		generate: func(ctx context.Context, file string) (string, *core.Response, error) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return "", nil, err
+			}
+			target := utils.TestFilePath(file)
+			contextText := fileContext(ctx, orchestrator, cfg, "test", "unit test for "+file)
+
+			resp, err := client.Generate(ctx, testFilePrompt(contextText, file, target, string(content)))
+			if err != nil {
+				return "", nil, err
+			}
+			code := extractCode(resp.Text)
+			if code == "" {
+				return "", resp, fmt.Errorf("no code block in the response")
+			}
+			return target, resp, writeGenerated(target, code)
+		},
+	})
+
+	if printBulkSummary(rows) {
+		os.Exit(1)
+	}
+}
+
 func init() {
+	testCmd.Flags().Bool("all", false, "Generate tests for every source file in the project")
+	testCmd.Flags().Bool("overwrite", false, "Regenerate tests for files that already have a test file")
+	testCmd.Flags().Bool("write", false, "Write the tests of a single file next to it instead of printing them")
+	testCmd.Flags().Bool("verify", false, "Write the tests, run them and let the model fix failures; keep the file only if it passes")
+	testCmd.Flags().Int("max-fixes", 3, "Repair attempts for failing tests with --verify")
 	rootCmd.AddCommand(testCmd)
 }
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"archon/internal/core"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [n]",
+	Short: "Revert the last edits applied by Archon",
+	Long: `Restores the files changed by the last n edits applied by Archon (default 1), such as
+'archon refactor --apply', from the backups in .archon/backups. Files created by an edit
+are removed. An edit whose files were changed again afterwards is not reverted unless
+--force is given.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		journal := core.NewBackupJournal(core.DefaultBackupDir)
+		entries, err := journal.List()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			if len(entries) == 0 {
+				fmt.Println("No edits to undo.")
+				return
+			}
+			fmt.Printf("%-24s %-17s %-10s %s\n", "ID", "APPLIED", "COMMAND", "FILES")
+			for _, e := range entries {
+				var files []string
+				for _, f := range e.Files {
+					files = append(files, f.Path)
+				}
+				fmt.Printf("%-24s %-17s %-10s %s\n", e.ID, e.Time.Format("2006-01-02 15:04"), e.Command, strings.Join(files, ", "))
+			}
+			return
+		}
+
+		n := 1
+		if len(args) == 1 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				fmt.Printf("Error: invalid number of edits %q\n", args[0])
+				os.Exit(1)
+			}
+		}
+		if len(entries) == 0 {
+			fmt.Println("No edits to undo.")
+			return
+		}
+		if n > len(entries) {
+			fmt.Printf("Only %d edits recorded, undoing all of them.\n", len(entries))
+			n = len(entries)
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		for _, entry := range entries[:n] {
+			if modified := journal.Modified(entry); len(modified) > 0 && !force {
+				fmt.Printf("Error: %s changed since edit %s was applied, use --force to revert it anyway\n", strings.Join(modified, ", "), entry.ID)
+				os.Exit(1)
+			}
+			if err := journal.Restore(entry); err != nil {
+				fmt.Printf("Error: failed to undo %s: %v\n", entry.ID, err)
+				os.Exit(1)
+			}
+			for _, f := range entry.Files {
+				if f.Existed {
+					fmt.Printf("↩ Restored %s\n", f.Path)
+				} else {
+					fmt.Printf("↩ Removed %s\n", f.Path)
+				}
+			}
+		}
+		fmt.Printf("✅ Undid %d edit(s)\n", n)
+	},
+}
+
+func init() {
+	undoCmd.Flags().Bool("list", false, "List the edits that can be undone")
+	undoCmd.Flags().Bool("force", false, "Revert even if the files were changed after the edit")
+	rootCmd.AddCommand(undoCmd)
+}
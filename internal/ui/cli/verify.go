@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"archon/internal/adapters/parser"
+	"archon/internal/core"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultTestCommands are used when test_command is not configured. Rust has
+// none: cargo never compiles a foo_test.rs written next to the source, so a
+// default would pass without running the generated tests.
+var defaultTestCommands = map[parser.Language]string{
+	parser.Go:         "go test {pkg}/...",
+	parser.Python:     "python -m pytest {test}",
+	parser.TypeScript: "npx jest {test}",
+	parser.JavaScript: "npx jest {test}",
+}
+
+// maxFailureOutput is how much of the test output is sent back to the model.
+const maxFailureOutput = 8000
+
+// testCommand expands the configured (or the language's default) test command
+// for a source file and its test file. Placeholders: {pkg} is the package
+// directory as a path pattern (./internal/core), {dir} the plain directory,
+// {file} the source file and {test} the test file.
+func testCommand(template, file, testFile string) ([]string, error) {
+	if template == "" {
+		lang := parser.DetectLanguage(file)
+		template = defaultTestCommands[lang]
+		if template == "" {
+			return nil, fmt.Errorf("no default test command for %s files, set test_command in .archon.yaml", lang)
+		}
+	}
+
+	dir := filepath.Dir(testFile)
+	pkg := filepath.ToSlash(dir)
+	if pkg != "." && !filepath.IsAbs(dir) && !strings.HasPrefix(pkg, "../") {
+		pkg = "./" + pkg
+	}
+
+	command := expandCommand(template, "{pkg}", pkg, "{dir}", dir, "{file}", file, "{test}", testFile)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("test_command is empty")
+	}
+	return command, nil
+}
+
+// expandCommand splits a configured command into its arguments and fills in the
+// placeholders, given as old, new pairs like strings.NewReplacer. Arguments
+// are split on whitespace; quoting is not supported.
+func expandCommand(template string, placeholders ...string) []string {
+	replacer := strings.NewReplacer(placeholders...)
+	fields := strings.Fields(template)
+	for i, field := range fields {
+		fields[i] = replacer.Replace(field)
+	}
+	return fields
+}
+
+// runCommand runs a test, format or build command and returns its combined
+// output. A non-zero exit is reported as an *exec.ExitError; failing to start
+// the command is a different error, since no repair by the model can fix it.
+func runCommand(ctx context.Context, command []string) (string, error) {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return string(out), fmt.Errorf("failed to run %s: %w", strings.Join(command, " "), err)
+	}
+	return string(out), err
+}
+
+// verifyTests runs the tests in testFile and, while they fail, asks the model to
+// fix the test file, at most maxFixes times. It returns the tokens used and
+// whether the tests passed; the last failure output is printed when they do not.
+func verifyTests(ctx context.Context, client core.LLM, command []string, file, testFile string, maxFixes int) (int, bool, error) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false, err
+	}
+
+	tokens := 0
+	for attempt := 0; ; attempt++ {
+		fmt.Printf("Running %s...\n", strings.Join(command, " "))
+		out, err := runCommand(ctx, command)
+		if err == nil {
+			return tokens, true, nil
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return tokens, false, err
+		}
+		if attempt == maxFixes {
+			fmt.Printf("\nTests still fail after %d repair attempts:\n%s\n", maxFixes, tail(out, maxFailureOutput))
+			return tokens, false, nil
+		}
+
+		current, err := os.ReadFile(testFile)
+		if err != nil {
+			return tokens, false, err
+		}
+		fmt.Printf("Tests fail, asking for a fix (%d/%d)...\n", attempt+1, maxFixes)
+
+		prompt := fmt.Sprintf("The test file %s written for %s does not pass. Fix the TEST FILE so that it compiles and passes; do not change the code under test, and remove tests whose expectations cannot be met by the current code.\n\nCode under test (%s):\n```\n%s\n```\n\nTest file (%s):\n```\n%s\n```\n\nOutput of `%s`:\n```\n%s\n```",
+			testFile, file, file, string(source), testFile, string(current), strings.Join(command, " "), tail(out, maxFailureOutput))
+		prompt += "\n\nRETURN ONLY THE COMPLETE TEST FILE in a single Markdown code block (```). Do not provide any explanation outside that code block because your output will be written directly to the file."
+
+		resp, err := client.Generate(ctx, prompt)
+		if err != nil {
+			return tokens, false, err
+		}
+		tokens += resp.TotalTokens
+		code := extractCode(resp.Text)
+		if code == "" {
+			return tokens, false, fmt.Errorf("no code block in the response")
+		}
+		if err := writeGenerated(testFile, code); err != nil {
+			return tokens, false, err
+		}
+	}
+}
+
+// tail returns the last n bytes of s, where test runners put the failures that matter.
+func tail(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return "...\n" + s[len(s)-n:]
+}
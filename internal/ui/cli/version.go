@@ -5,11 +5,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// version is the release of Archon, also reported in SARIF logs.
+const version = "1.0.0"
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version info",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("ArchonCLI v1.0.0")
+		fmt.Println("ArchonCLI v" + version)
 	},
 }
 
@@ -1,19 +1,24 @@
 package lsp
 
 import (
-	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type ExecuteCommandParams struct {
 	Command   string            `json:"command"`
 	Arguments []json.RawMessage `json:"arguments"`
+	// WorkDoneToken is the client's token for $/progress reports, if any.
+	WorkDoneToken interface{} `json:"workDoneToken,omitempty"`
 }
 
 func (s *Server) handleMessage(req Request) {
@@ -35,7 +40,20 @@ func (s *Server) handleMessage(req Request) {
 	}
 }
 
+type InitializeParams struct {
+	Capabilities struct {
+		Window struct {
+			WorkDoneProgress bool `json:"workDoneProgress"`
+		} `json:"window"`
+	} `json:"capabilities"`
+}
+
 func (s *Server) handleInitialize(req Request) {
+	var params InitializeParams
+	if err := json.Unmarshal(req.Params, &params); err == nil {
+		s.workDoneProgress.Store(params.Capabilities.Window.WorkDoneProgress)
+	}
+
 	result := map[string]interface{}{
 		"capabilities": map[string]interface{}{
 			"executeCommandProvider": map[string]interface{}{
@@ -43,6 +61,8 @@ func (s *Server) handleInitialize(req Request) {
 					"archon/ask",
 					"archon/index",
 					"archon/explain",
+					"archon/watch",
+					"archon/unwatch",
 				},
 			},
 		},
@@ -71,9 +91,12 @@ func (s *Server) handleExecuteCommand(req Request) {
 			return
 		}
 		var query string
-		json.Unmarshal(params.Arguments[0], &query)
-		
-		response, err := s.executeAsk(ctx, cfg, query)
+		if err := json.Unmarshal(params.Arguments[0], &query); err != nil {
+			s.sendError(req.ID, -32602, "Query argument must be a string")
+			return
+		}
+
+		response, err := s.executeAsk(ctx, cfg, query, params.WorkDoneToken)
 		if err != nil {
 			s.sendError(req.ID, -32000, err.Error())
 		} else {
@@ -94,36 +117,121 @@ func (s *Server) handleExecuteCommand(req Request) {
 			return
 		}
 		var target string
-		json.Unmarshal(params.Arguments[0], &target)
-		
-		response, err := s.executeExplain(ctx, cfg, target)
+		if err := json.Unmarshal(params.Arguments[0], &target); err != nil {
+			s.sendError(req.ID, -32602, "Target argument must be a string")
+			return
+		}
+
+		response, err := s.executeExplain(ctx, cfg, target, params.WorkDoneToken)
 		if err != nil {
 			s.sendError(req.ID, -32000, err.Error())
 		} else {
 			s.sendResponse(req.ID, response)
 		}
 
+	case "archon/watch":
+		err := s.startWatch(cfg)
+		if err != nil {
+			s.sendError(req.ID, -32000, err.Error())
+		} else {
+			s.sendResponse(req.ID, "Watching for changes")
+		}
+
+	case "archon/unwatch":
+		s.stopWatch()
+		s.sendResponse(req.ID, "Watcher stopped")
+
 	default:
 		s.sendError(req.ID, -32601, fmt.Sprintf("Command '%s' not found", params.Command))
 	}
 }
 
-func (s *Server) executeAsk(ctx context.Context, cfg *config.Config, query string) (string, error) {
-	if cfg.GeminiKey == "" {
-		return "", fmt.Errorf("Gemini API key not found")
+// AnswerResult is returned by archon/ask and archon/explain. Citations point
+// at the code snippets the answer was grounded on.
+type AnswerResult struct {
+	Answer    string     `json:"answer"`
+	Citations []Location `json:"citations"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is zero-based, as mandated by the LSP specification.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func toLocations(snippets []core.Snippet) []Location {
+	locations := []Location{}
+	for _, snip := range snippets {
+		locations = append(locations, Location{
+			URI: pathToURI(snip.File),
+			Range: Range{
+				Start: Position{Line: max(0, snip.StartLine-1), Character: max(0, snip.StartColumn-1)},
+				End:   Position{Line: max(0, snip.EndLine-1), Character: max(0, snip.EndColumn-1)},
+			},
+		})
 	}
+	return locations
+}
 
-	store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
-	var contextText string
-	if err == nil {
-		defer store.Close()
-		orchestrator := core.NewOrchestrator(store)
-		contextText, _ = orchestrator.SearchContext(ctx, query)
+func pathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		// Windows drive letter paths: C:/foo -> /C:/foo
+		abs = "/" + abs
+	}
+	return (&url.URL{Scheme: "file", Path: abs}).String()
+}
+
+// searchSnippets returns the context snippets for query within the token budget of command.
+func (s *Server) searchSnippets(ctx context.Context, cfg *config.Config, command, query string) []core.Snippet {
+	store, err := provider.NewStore(ctx, cfg)
+	if err != nil {
+		return nil
 	}
+	defer store.Close()
 
-	client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+	orchestrator := core.NewOrchestrator(store)
+	orchestrator.SetSearchBlend(cfg.SearchBlend)
+	res, err := orchestrator.SearchContext(ctx, query, core.ContextBudget(command, cfg.ContextBudget), core.SearchOptions{})
 	if err != nil {
-		return "", err
+		return nil
+	}
+	return res.Snippets
+}
+
+// generate streams the answer to prompt, reporting it through $/progress.
+func (s *Server) generate(ctx context.Context, client core.LLM, prompt string, token interface{}, title string) (*core.Response, error) {
+	p := s.startProgress(ctx, token, title)
+	resp, err := core.CollectStream(client.GenerateStream(ctx, prompt), p.delta)
+	if err != nil {
+		p.end("Failed")
+		return nil, err
+	}
+	p.end("Done")
+	return resp, nil
+}
+
+func (s *Server) executeAsk(ctx context.Context, cfg *config.Config, query string, token interface{}) (*AnswerResult, error) {
+	snippets := s.searchSnippets(ctx, cfg, "ask", query)
+	contextText := core.FormatContext(snippets)
+
+	client, err := provider.NewLLM(ctx, cfg)
+	if err != nil {
+		return nil, err
 	}
 	defer client.Close()
 
@@ -132,45 +240,106 @@ func (s *Server) executeAsk(ctx context.Context, cfg *config.Config, query strin
 		prompt = fmt.Sprintf("%s\n\nQuestion: %s", contextText, query)
 	}
 
-	resp, err := client.Ask(ctx, prompt)
+	resp, err := s.generate(ctx, client, prompt, token, "Archon: answering")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return resp.Text, nil
+	return &AnswerResult{Answer: resp.Text, Citations: toLocations(snippets)}, nil
 }
 
 func (s *Server) executeIndex(ctx context.Context, cfg *config.Config) error {
-	if cfg.GeminiKey == "" {
-		return fmt.Errorf("Gemini API key not found")
-	}
-
-	store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+	store, err := provider.NewStore(ctx, cfg)
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
+	if err := store.Compatible(); err != nil {
+		return err
+	}
+
 	orchestrator := core.NewOrchestrator(store)
-	return orchestrator.IndexDirectory(ctx, ".", nil)
+	report, err := orchestrator.IndexDirectory(ctx, ".", nil)
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		s.sendNotification("window/logMessage", map[string]interface{}{
+			"type":    2,
+			"message": fmt.Sprintf("Archon: %d of %d files failed to index, first error: %s: %v", report.Failed, report.Total, report.Errors[0].Path, report.Errors[0].Err),
+		})
+	}
+	return nil
 }
 
-func (s *Server) executeExplain(ctx context.Context, cfg *config.Config, target string) (string, error) {
-	if cfg.GeminiKey == "" {
-		return "", fmt.Errorf("Gemini API key not found")
+// startWatch keeps the index in sync in the background and reports activity
+// to the client through window/logMessage notifications.
+func (s *Server) startWatch(cfg *config.Config) error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watch != nil {
+		return fmt.Errorf("already watching")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store, err := provider.NewStore(ctx, cfg)
+	if err != nil {
+		cancel()
+		return err
 	}
+	handle := &watchHandle{cancel: cancel}
+	s.watch = handle
 
-	store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
-	var contextText string
-	if err == nil {
+	go func() {
 		defer store.Close()
 		orchestrator := core.NewOrchestrator(store)
-		contextText, _ = orchestrator.SearchContext(ctx, "Explain "+target)
+		err := orchestrator.WatchDirectory(ctx, ".", core.WatchOptions{
+			Debounce: time.Duration(cfg.WatchDebounceMs) * time.Millisecond,
+			OnEvent: func(ev core.WatchEvent) {
+				msgType, message := 3, fmt.Sprintf("archon watch: %s %s", ev.Type, ev.Path)
+				if ev.Err != nil {
+					msgType, message = 1, fmt.Sprintf("%s: %v", message, ev.Err)
+				}
+				s.sendNotification("window/logMessage", map[string]interface{}{
+					"type":    msgType,
+					"message": message,
+				})
+			},
+		})
+		if err != nil && ctx.Err() == nil {
+			s.sendNotification("window/logMessage", map[string]interface{}{
+				"type":    1,
+				"message": fmt.Sprintf("archon watch stopped: %v", err),
+			})
+		}
+
+		s.watchMu.Lock()
+		if s.watch == handle {
+			s.watch = nil
+		}
+		s.watchMu.Unlock()
+		cancel()
+	}()
+	return nil
+}
+
+func (s *Server) stopWatch() {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watch != nil {
+		s.watch.cancel()
+		s.watch = nil
 	}
+}
+
+func (s *Server) executeExplain(ctx context.Context, cfg *config.Config, target string, token interface{}) (*AnswerResult, error) {
+	snippets := s.searchSnippets(ctx, cfg, "explain", "Explain "+target)
+	contextText := core.FormatContext(snippets)
 
-	client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
+	client, err := provider.NewLLM(ctx, cfg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer client.Close()
 
@@ -186,10 +355,10 @@ func (s *Server) executeExplain(ctx context.Context, cfg *config.Config, target
 		}
 	}
 
-	resp, err := client.Ask(ctx, prompt)
+	resp, err := s.generate(ctx, client, prompt, token, "Archon: explaining "+target)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return resp.Text, nil
+	return &AnswerResult{Answer: resp.Text, Citations: toLocations(snippets)}, nil
 }
@@ -0,0 +1,75 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// progressInterval limits how often $/progress reports are sent while an answer streams.
+const progressInterval = 200 * time.Millisecond
+
+// progress reports a long-running command through $/progress notifications.
+// A nil *progress reports nothing, so callers do not have to check.
+type progress struct {
+	s     *Server
+	token interface{}
+	last  time.Time
+	text  strings.Builder
+}
+
+// startProgress begins progress reporting under the token the client sent with
+// the command. Without one, a token is created through
+// window/workDoneProgress/create if the client supports it.
+func (s *Server) startProgress(ctx context.Context, token interface{}, title string) *progress {
+	if token == nil {
+		if !s.workDoneProgress.Load() {
+			return nil
+		}
+		token = fmt.Sprintf("archon-%d", time.Now().UnixNano())
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if _, err := s.sendRequest(ctx, "window/workDoneProgress/create", map[string]interface{}{"token": token}); err != nil {
+			return nil
+		}
+	}
+
+	p := &progress{s: s, token: token}
+	p.send(map[string]interface{}{"kind": "begin", "title": title, "cancellable": false})
+	return p
+}
+
+// delta records a piece of the streamed answer and reports the line being
+// written, at most once per progressInterval.
+func (p *progress) delta(text string) {
+	if p == nil {
+		return
+	}
+	p.text.WriteString(text)
+	if time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+
+	answer := strings.TrimSpace(p.text.String())
+	line := answer[strings.LastIndex(answer, "\n")+1:]
+	if r := []rune(line); len(r) > 80 {
+		line = "…" + string(r[len(r)-80:])
+	}
+	p.send(map[string]interface{}{"kind": "report", "message": line})
+}
+
+func (p *progress) end(message string) {
+	if p == nil {
+		return
+	}
+	p.send(map[string]interface{}{"kind": "end", "message": message})
+}
+
+func (p *progress) send(value map[string]interface{}) {
+	p.s.sendNotification("$/progress", map[string]interface{}{
+		"token": p.token,
+		"value": value,
+	})
+}
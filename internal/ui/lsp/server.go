@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Request mewakili pesan JSON-RPC 2.0 Request
@@ -17,6 +19,11 @@ type Request struct {
 	ID      interface{}     `json:"id,omitempty"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params,omitempty"`
+
+	// Result dan Error hanya terisi jika pesan adalah response dari client
+	// atas request yang dikirim server.
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ResponseError  `json:"error,omitempty"`
 }
 
 // Response mewakili pesan JSON-RPC 2.0 Response
@@ -44,12 +51,27 @@ type Server struct {
 	reader *bufio.Reader
 	writer io.Writer
 	mu     sync.Mutex
+
+	watchMu sync.Mutex
+	watch   *watchHandle
+
+	// workDoneProgress is set when the client supports server-initiated progress.
+	workDoneProgress atomic.Bool
+
+	pendingMu sync.Mutex
+	pending   map[string]chan Request
+	nextID    int
+}
+
+type watchHandle struct {
+	cancel context.CancelFunc
 }
 
 func NewServer() *Server {
 	return &Server{
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
+		reader:  bufio.NewReader(os.Stdin),
+		writer:  os.Stdout,
+		pending: make(map[string]chan Request),
 	}
 }
 
@@ -74,6 +96,10 @@ func (s *Server) Start() error {
 			continue
 		}
 
+		if req.Method == "" && req.ID != nil {
+			s.handleClientResponse(req)
+			continue
+		}
 		go s.handleMessage(req)
 	}
 }
@@ -121,6 +147,54 @@ func (s *Server) sendError(id interface{}, code int, message string) {
 	s.write(res)
 }
 
+func (s *Server) sendNotification(method string, params interface{}) {
+	data, _ := json.Marshal(params)
+	s.write(Notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  data,
+	})
+}
+
+// sendRequest sends a request to the client and waits for its response.
+func (s *Server) sendRequest(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	data, _ := json.Marshal(params)
+	ch := make(chan Request, 1)
+
+	s.pendingMu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.pending[fmt.Sprint(id)] = ch
+	s.pendingMu.Unlock()
+
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, fmt.Sprint(id))
+		s.pendingMu.Unlock()
+	}()
+
+	s.write(Request{JSONRPC: "2.0", ID: id, Method: method, Params: data})
+
+	select {
+	case res := <-ch:
+		if res.Error != nil {
+			return nil, fmt.Errorf("%s: %s", method, res.Error.Message)
+		}
+		return res.Result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Server) handleClientResponse(res Request) {
+	s.pendingMu.Lock()
+	ch, ok := s.pending[fmt.Sprint(res.ID)]
+	s.pendingMu.Unlock()
+	if ok {
+		ch <- res
+	}
+}
+
 func (s *Server) write(msg interface{}) {
 	data, _ := json.Marshal(msg)
 	s.mu.Lock()
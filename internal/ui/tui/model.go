@@ -2,7 +2,7 @@ package tui
 
 import (
 	"archon/internal/adapters/gemini"
-	"archon/internal/adapters/vectordb"
+	"archon/internal/adapters/provider"
 	"archon/internal/config"
 	"archon/internal/core"
 	"archon/internal/utils"
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -40,6 +41,8 @@ const (
 	stateStatus
 	stateContext
 	stateInputPath
+	stateWatch
+	stateSessions
 )
 
 type model struct {
@@ -66,6 +69,13 @@ type model struct {
 	lastPromptTokens int
 	lastAnswerTokens int
 	totalCost      float64
+	watching       bool
+	watchLog       []string
+	watchEvents    chan core.WatchEvent
+	watchCancel    context.CancelFunc
+	session        *core.Session
+	sessions       []*core.Session
+	sessionCursor  int
 }
 
 func initialModel() model {
@@ -99,7 +109,9 @@ func initialModel() model {
 		state:           stateMenu,
 		choices:         []string{
 			"Chat Mode", 
+			"Chat Sessions",
 			"Index Codebase", 
+			"Watch for Changes",
 			"AI Code Review",
 			"Smart Commit",
 			"Explain File/Symbol",
@@ -150,6 +162,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.err = nil
 				return m, nil
 			}
+		case "s":
+			if m.state == stateWatch && m.watching {
+				m.watchCancel()
+				return m, nil
+			}
 		case "tab":
 			if m.state == stateChat {
 				m.state = stateContext
@@ -159,12 +176,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "up", "k":
 			if m.state == stateMenu && m.cursor > 0 {
 				m.cursor--
+			} else if m.state == stateSessions && m.sessionCursor > 0 {
+				m.sessionCursor--
 			} else if m.state == stateStatus {
 				m.table.MoveUp(1)
 			}
 		case "down", "j":
 			if m.state == stateMenu && m.cursor < len(m.choices)-1 {
 				m.cursor++
+			} else if m.state == stateSessions && m.sessionCursor < len(m.sessions) {
+				m.sessionCursor++
 			} else if m.state == stateStatus {
 				m.table.MoveDown(1)
 			}
@@ -174,10 +195,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				switch choice {
 				case "Chat Mode":
 					m.state = stateChat
+				case "Chat Sessions":
+					m.state = stateSessions
+					m.sessionCursor = 0
+					return m, loadSessions()
 				case "Index Codebase":
 					m.state = stateIndex
 					m.indexing = true
 					return m, m.startIndexing()
+				case "Watch for Changes":
+					m.state = stateWatch
+					if m.watching {
+						return m, nil
+					}
+					m.watching = true
+					m.watchLog = nil
+					m.watchEvents = make(chan core.WatchEvent)
+					ctx, cancel := context.WithCancel(context.Background())
+					m.watchCancel = cancel
+					return m, tea.Batch(startWatching(ctx, m.watchEvents), waitForWatchEvent(m.watchEvents))
 				case "AI Code Review":
 					m.state = stateChat
 					m.thinking = true
@@ -199,7 +235,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 					
 					prompt := fmt.Sprintf("Perform a code review on the following git diff:\n\n```diff\n%s\n```\n\nIdentify potential bugs, best practice violations, and provide improvement suggestions.", diff)
-					return m, m.askGemini(prompt)
+					return m, m.askGemini(prompt, nil)
 					
 				case "Smart Commit":
 					m.state = stateChat
@@ -222,7 +258,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 					
 					prompt := fmt.Sprintf("Analyze the following changes and generate a commit message following Conventional Commits standards:\n\n```diff\n%s\n```\n\nProvide 3 commit message options.", diff)
-					return m, m.askGemini(prompt)
+					return m, m.askGemini(prompt, nil)
 
 				case "Explain File/Symbol", "Refactor Code", "Generate Unit Tests", "Generate Diagram":
 					m.state = stateInputPath
@@ -240,7 +276,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.chatHistory += fmt.Sprintf("\n%s You: Running Architectural Analysis\n", UserMsgStyle.Render("●"))
 					m.viewport.SetContent(m.chatHistory)
 					m.viewport.GotoBottom()
-					return m, m.askGemini("Perform a deep architectural analysis on this project. Detect anomalies, code smells, or design pattern violations.")
+					return m, m.askGemini("Perform a deep architectural analysis on this project. Detect anomalies, code smells, or design pattern violations.", nil)
 				case "System Status":
 					m.state = stateStatus
 					return m, m.loadStatus()
@@ -274,8 +310,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.viewport.GotoBottom()
 					m.textInput.SetValue("")
 					m.textInput.Placeholder = "Type your question here..."
-					return m, m.askGemini(prompt)
+					return m, m.askGemini(prompt, nil)
+				}
+			} else if m.state == stateSessions {
+				// The first entry starts a new session
+				m.session = nil
+				m.chatHistory = ""
+				if m.sessionCursor > 0 && m.sessionCursor <= len(m.sessions) {
+					m.session = m.sessions[m.sessionCursor-1]
+					for _, turn := range m.session.Turns {
+						if turn.Role == core.RoleUser {
+							m.chatHistory += fmt.Sprintf("\n%s You: %s\n", UserMsgStyle.Render("●"), turn.Content)
+						} else {
+							m.chatHistory += fmt.Sprintf("\n%s Archon: %s\n", BotMsgStyle.Render("◆"), turn.Content)
+						}
+					}
 				}
+				m.viewport.SetContent(m.chatHistory)
+				m.viewport.GotoBottom()
+				m.state = stateChat
 			} else if m.state == stateChat {
 				query := m.textInput.Value()
 				if query != "" {
@@ -284,21 +337,50 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.viewport.SetContent(m.chatHistory)
 					m.viewport.GotoBottom()
 					m.textInput.SetValue("")
-					return m, m.askGemini(query)
+					// Chat Mode keeps a session, so follow-up questions see the earlier turns
+					if m.session == nil {
+						m.session = core.NewSession()
+					}
+					return m, m.askGemini(query, m.session)
 				}
 			}
 		}
+	case watchEventMsg:
+		ev := core.WatchEvent(msg)
+		line := fmt.Sprintf("%s %-8s %s", ev.Time.Format("15:04:05"), ev.Type, ev.Path)
+		if ev.Err != nil {
+			line += fmt.Sprintf(" (%v)", ev.Err)
+		}
+		m.watchLog = append(m.watchLog, line)
+		if len(m.watchLog) > 100 {
+			m.watchLog = m.watchLog[len(m.watchLog)-100:]
+		}
+		return m, waitForWatchEvent(m.watchEvents)
+	case watchStoppedMsg:
+		m.watching = false
+		m.watchLog = append(m.watchLog, "Watcher stopped.")
+		return m, nil
 	case indexCompleteMsg:
 		m.indexing = false
 		m.indexStats = string(msg)
 		return m, nil
+	case answerStreamMsg:
+		m.lastContext = msg.context
+		m.viewportContext.SetContent(m.lastContext)
+		m.chatHistory += fmt.Sprintf("\n%s Archon: ", BotMsgStyle.Render("◆"))
+		m.viewport.SetContent(m.chatHistory)
+		m.viewport.GotoBottom()
+		return m, waitForAnswer(msg.events)
+	case answerDeltaMsg:
+		m.chatHistory += msg.delta
+		m.viewport.SetContent(m.chatHistory)
+		m.viewport.GotoBottom()
+		return m, waitForAnswer(msg.events)
 	case geminiResponseMsg:
 		m.thinking = false
-		m.chatHistory += fmt.Sprintf("\n%s Archon: %s\n", BotMsgStyle.Render("◆"), msg.answer)
+		m.chatHistory += "\n"
 		m.viewport.SetContent(m.chatHistory)
 		m.viewport.GotoBottom()
-		m.lastContext = msg.context
-		m.viewportContext.SetContent(m.lastContext)
 		m.lastPromptTokens = msg.promptTokens
 		m.lastAnswerTokens = msg.answerTokens
 		m.totalTokens += msg.totalTokens
@@ -307,6 +389,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cost := m.calculateCost(msg.promptTokens, msg.answerTokens)
 		m.totalCost += cost
 		
+		return m, nil
+	case sessionsMsg:
+		m.sessions = msg
 		return m, nil
 	case statusTableMsg:
 		m.table.SetRows(msg)
@@ -351,10 +436,11 @@ func (m model) loadStatus() tea.Cmd {
 		cfg, _ := config.LoadConfig()
 		
 		var rows []table.Row
+		rows = append(rows, table.Row{"Provider", cfg.Provider})
 		rows = append(rows, table.Row{"Model", cfg.ModelID})
 		
 		apiKeyStatus := "NOT Configured"
-		if cfg.GeminiKey != "" {
+		if cfg.GeminiKey != "" || cfg.Provider == config.ProviderOpenAI {
 			apiKeyStatus = "Configured"
 		}
 		rows = append(rows, table.Row{"API Key", apiKeyStatus})
@@ -364,6 +450,7 @@ func (m model) loadStatus() tea.Cmd {
 			vectorDBStatus = "Ready (chromem_db)"
 		}
 		rows = append(rows, table.Row{"Vector DB", vectorDBStatus})
+		rows = append(rows, table.Row{"Embedder", cfg.Embedder})
 
 		// Caching status
 		hash, _ := gemini.CalculateProjectHash(".")
@@ -386,7 +473,7 @@ func (m model) loadStatus() tea.Cmd {
 		rows = append(rows, table.Row{"Context Cache", cacheStatus})
 
 		// List all caches if client is available
-		if cfg.GeminiKey != "" {
+		if cfg.Provider == config.ProviderGemini && cfg.GeminiKey != "" {
 			client, err := gemini.NewClient(context.Background(), cfg.GeminiKey, cfg.ModelID)
 			if err == nil {
 				defer client.Close()
@@ -417,9 +504,19 @@ func (m model) clearIndex() tea.Cmd {
 	}
 }
 
+// answerStreamMsg starts an answer, which then arrives as answerDeltaMsg
+// pieces followed by a geminiResponseMsg (or an errMsg).
+type answerStreamMsg struct {
+	context string
+	events  <-chan core.StreamEvent
+}
+
+type answerDeltaMsg struct {
+	delta  string
+	events <-chan core.StreamEvent
+}
+
 type geminiResponseMsg struct {
-	answer       string
-	context      string
 	promptTokens int
 	answerTokens int
 	totalTokens  int
@@ -427,6 +524,51 @@ type geminiResponseMsg struct {
 type indexCompleteMsg string
 type errMsg error
 
+type watchEventMsg core.WatchEvent
+type watchStoppedMsg struct{}
+
+// startWatching runs the watcher until ctx is cancelled, forwarding its events
+// to ch. The channel is closed when the watcher stops.
+func startWatching(ctx context.Context, ch chan core.WatchEvent) tea.Cmd {
+	return func() tea.Msg {
+		defer close(ch)
+		send := func(ev core.WatchEvent) {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+			}
+		}
+
+		cfg, _ := config.LoadConfig()
+		store, err := provider.NewStore(ctx, cfg)
+		if err != nil {
+			send(core.WatchEvent{Type: core.WatchError, Err: err, Time: time.Now()})
+			return nil
+		}
+		defer store.Close()
+
+		orchestrator := core.NewOrchestrator(store)
+		err = orchestrator.WatchDirectory(ctx, ".", core.WatchOptions{
+			Debounce: time.Duration(cfg.WatchDebounceMs) * time.Millisecond,
+			OnEvent:  send,
+		})
+		if err != nil && ctx.Err() == nil {
+			send(core.WatchEvent{Type: core.WatchError, Err: err, Time: time.Now()})
+		}
+		return nil
+	}
+}
+
+func waitForWatchEvent(ch chan core.WatchEvent) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-ch
+		if !ok {
+			return watchStoppedMsg{}
+		}
+		return watchEventMsg(ev)
+	}
+}
+
 type indexProgressMsg struct {
 	current int
 	total   int
@@ -437,43 +579,89 @@ func (m model) startIndexing() tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
 		cfg, _ := config.LoadConfig()
-		if cfg.GeminiKey == "" {
-			return errMsg(fmt.Errorf("Gemini API key not found. Please use 'archon auth --key [key]' in CLI first."))
-		}
-		
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		if err != nil {
 			return errMsg(err)
 		}
 		defer store.Close()
 
+		if err := store.Compatible(); err != nil {
+			return errMsg(err)
+		}
+
 		orchestrator := core.NewOrchestrator(store)
 		
-		err = orchestrator.IndexDirectory(ctx, ".", nil)
+		report, err := orchestrator.IndexDirectory(ctx, ".", nil)
 
 		if err != nil {
 			return errMsg(err)
 		}
-		return indexCompleteMsg("Indexing complete. The codebase is now ready for semantic search.")
+		msg := fmt.Sprintf("Indexing complete in %s: %d indexed, %d unchanged, %d removed, %d failed.",
+			report.Duration.Round(time.Millisecond), report.Indexed, report.Skipped, report.Removed, report.Failed)
+		for _, fe := range report.Errors {
+			msg += fmt.Sprintf("\n  %s: %v", fe.Path, fe.Err)
+		}
+		return indexCompleteMsg(msg)
 	}
 }
 
-func (m model) askGemini(query string) tea.Cmd {
+func waitForAnswer(events <-chan core.StreamEvent) tea.Cmd {
 	return func() tea.Msg {
-		cfg, _ := config.LoadConfig()
-		if cfg.GeminiKey == "" {
-			return errMsg(fmt.Errorf("Gemini API key not found. Please use 'archon auth' in CLI."))
+		ev, ok := <-events
+		switch {
+		case !ok:
+			return errMsg(fmt.Errorf("answer stream ended unexpectedly"))
+		case ev.Err != nil:
+			return errMsg(ev.Err)
+		case ev.Response != nil:
+			return geminiResponseMsg{
+				promptTokens: ev.Response.PromptTokens,
+				answerTokens: ev.Response.AnswerTokens,
+				totalTokens:  ev.Response.TotalTokens,
+			}
+		default:
+			return answerDeltaMsg{delta: ev.Delta, events: events}
+		}
+	}
+}
+
+type sessionsMsg []*core.Session
+
+func loadSessions() tea.Cmd {
+	return func() tea.Msg {
+		sessions, err := core.NewSessionStore(core.DefaultSessionDir).List()
+		if err != nil {
+			return errMsg(err)
 		}
+		return sessionsMsg(sessions)
+	}
+}
+
+// askGemini answers query, within session if it is not nil. The session is
+// saved once the answer is complete.
+func (m model) askGemini(query string, session *core.Session) tea.Cmd {
+	return func() tea.Msg {
+		cfg, _ := config.LoadConfig()
 		ctx := context.Background()
 
+		client, err := provider.NewLLM(ctx, cfg)
+		if err != nil {
+			return errMsg(err)
+		}
+
 		// RAG Context
-		store, err := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+		store, err := provider.NewStore(ctx, cfg)
 		var prompt string
 		var contextText string
 		if err == nil {
 			defer store.Close()
 			orchestrator := core.NewOrchestrator(store)
-			contextText, err = orchestrator.SearchContext(ctx, query)
+			orchestrator.SetSearchBlend(cfg.SearchBlend)
+			var res *core.ContextResult
+			res, err = orchestrator.SearchContext(ctx, query, core.ContextBudget("ask", cfg.ContextBudget), core.SearchOptions{})
+			if err == nil {
+				contextText = res.Text
+			}
 			if err == nil {
 				prompt = fmt.Sprintf("%s\n\nUser Question: %s", contextText, query)
 			} else {
@@ -483,29 +671,36 @@ func (m model) askGemini(query string) tea.Cmd {
 			prompt = query
 		}
 
-		client, err := gemini.NewClient(ctx, cfg.GeminiKey, cfg.ModelID)
-		if err != nil {
-			return errMsg(err)
+		// Sync and Use Context Cache (Gemini only)
+		if geminiClient, ok := client.(*gemini.Client); ok {
+			cacheName, err := m.syncCache(ctx, geminiClient)
+			if err == nil && cacheName != "" {
+				geminiClient.SetCachedContent(cacheName)
+			}
 		}
-		defer client.Close()
 
-		// Sync and Use Context Cache
-		cacheName, err := m.syncCache(ctx, client)
-		if err == nil && cacheName != "" {
-			client.SetCachedContent(cacheName)
+		var stream <-chan core.StreamEvent
+		if session != nil {
+			stream = session.Ask(ctx, client, query, prompt, cfg.ChatWindow)
+		} else {
+			stream = client.GenerateStream(ctx, prompt)
 		}
 
-		resp, err := client.Ask(ctx, prompt)
-		if err != nil {
-			return errMsg(err)
-		}
-		return geminiResponseMsg{
-			answer:       resp.Text,
-			context:      contextText,
-			promptTokens: resp.PromptTokens,
-			answerTokens: resp.AnswerTokens,
-			totalTokens:  resp.TotalTokens,
-		}
+		// The client stays open until the whole answer has been streamed
+		events := make(chan core.StreamEvent)
+		go func() {
+			defer close(events)
+			defer client.Close()
+			for ev := range stream {
+				if ev.Response != nil && session != nil {
+					if err := core.NewSessionStore(core.DefaultSessionDir).Save(session); err != nil {
+						ev = core.StreamEvent{Err: fmt.Errorf("session not saved: %w", err)}
+					}
+				}
+				events <- ev
+			}
+		}()
+		return answerStreamMsg{context: contextText, events: events}
 	}
 }
 
@@ -530,7 +725,7 @@ func (m model) syncCache(ctx context.Context, client *gemini.Client) (string, er
 	}
 
 	// Cache invalid or not found, create new one
-	store, _ := vectordb.NewStore(ctx, "./chromem_db", cfg.GeminiKey)
+	store, _ := provider.NewStore(ctx, cfg)
 	if store != nil {
 		defer store.Close()
 		orchestrator := core.NewOrchestrator(store)
@@ -663,6 +858,9 @@ func (m model) View() string {
 		
 		if m.err != nil {
 			s += ErrorStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n"
+			if hint := core.ErrorHint(m.err); hint != "" {
+				s += hint + "\n"
+			}
 		}
 		s += FooterStyle.Render("\n(esc: back • tab: context)")
 
@@ -677,6 +875,40 @@ func (m model) View() string {
 		s += m.textInput.View() + "\n\n"
 		s += FooterStyle.Render("\n(enter: run • esc: back)")
 
+	case stateSessions:
+		s += StatusStyle.Render("CHAT SESSIONS") + "\n\n"
+		entries := []string{"+ New session"}
+		for _, session := range m.sessions {
+			title := session.Title
+			if title == "" {
+				title = "(empty)"
+			}
+			entries = append(entries, fmt.Sprintf("%s  %s  (%d turns)", session.Updated.Format("2006-01-02 15:04"), title, len(session.Turns)))
+		}
+		for i, entry := range entries {
+			if m.sessionCursor == i {
+				s += SelectedItemStyle.Render("> "+entry) + "\n"
+			} else {
+				s += ItemStyle.Render("  "+entry) + "\n"
+			}
+		}
+		if m.err != nil {
+			s += ErrorStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n"
+		}
+		s += FooterStyle.Render("\n(enter: open • esc: back to menu)")
+
+	case stateWatch:
+		s += StatusStyle.Render("WATCH MODE") + "\n\n"
+		if m.watching {
+			s += m.spinner.View() + " Watching for changes...\n\n"
+		}
+		logLines := m.watchLog
+		if m.height > 0 && len(logLines) > max(1, m.height-20) {
+			logLines = logLines[len(logLines)-max(1, m.height-20):]
+		}
+		s += strings.Join(logLines, "\n") + "\n"
+		s += FooterStyle.Render("\n(s: stop watching • esc: back to menu, watching continues)")
+
 	case stateIndex:
 		s += StatusStyle.Render("CODEBASE INDEXING") + "\n\n"
 		if m.indexing {
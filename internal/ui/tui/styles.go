@@ -114,6 +114,7 @@ var (
 func GetMenuIcon(choice string) string {
 	icons := map[string]string{
 		"Chat Mode":              "💬",
+		"Chat Sessions":          "🗂️",
 		"Index Codebase":         "📂",
 		"AI Code Review":         "🔍",
 		"Smart Commit":           "✍️",
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// lineOp is one step of an edit script. a and b are the positions in the old
+// and new lines at which the step happens.
+type lineOp struct {
+	kind opKind
+	a, b int
+}
+
+// UnifiedDiff returns the changes from oldText to newText in unified diff
+// format, or "" if they are equal.
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a, b := splitLines(oldText), splitLines(newText)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		group := ops[h[0]:h[1]]
+		oldStart, newStart := group[0].a, group[0].b
+		oldCount, newCount := 0, 0
+		for _, op := range group {
+			if op.kind != opInsert {
+				oldCount++
+			}
+			if op.kind != opDelete {
+				newCount++
+			}
+		}
+		// An empty range names the line before it, which is its start already
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range group {
+			switch op.kind {
+			case opEqual:
+				writeDiffLine(&out, ' ', a[op.a])
+			case opDelete:
+				writeDiffLine(&out, '-', a[op.a])
+			case opInsert:
+				writeDiffLine(&out, '+', b[op.b])
+			}
+		}
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func writeDiffLine(out *strings.Builder, prefix byte, line string) {
+	out.WriteByte(prefix)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// splitLines splits text into lines that keep their "\n", so that a missing
+// newline at the end of the file counts as a change.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hunks groups the changes of ops with their context into [start, end) ranges
+// of ops, merging changes whose context would overlap.
+func hunks(ops []lineOp) [][2]int {
+	var groups [][2]int
+	for i, op := range ops {
+		if op.kind == opEqual {
+			continue
+		}
+		start, end := max(i-diffContext, 0), min(i+1+diffContext, len(ops))
+		if n := len(groups); n > 0 && start <= groups[n-1][1] {
+			groups[n-1][1] = end
+			continue
+		}
+		groups = append(groups, [2]int{start, end})
+	}
+	return groups
+}
+
+// diffLines computes a shortest edit script from a to b with Myers' algorithm.
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: opEqual, a: x, b: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, lineOp{kind: opInsert, a: x, b: prevY})
+			} else {
+				ops = append(ops, lineOp{kind: opDelete, a: prevX, b: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Editor returns the editor command git would use: GIT_EDITOR, core.editor,
+// VISUAL or EDITOR, falling back to vi (notepad on Windows).
+func Editor() string {
+	if out, err := exec.Command("git", "var", "GIT_EDITOR").Output(); err == nil {
+		// "vi" is also git's built-in default, which Windows usually lacks
+		if editor := strings.TrimSpace(string(out)); editor != "" && (editor != "vi" || hasCommand("vi")) {
+			return editor
+		}
+	}
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(key); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// EditText opens text in the user's editor and returns it once the editor is
+// closed, without the lines starting with "#" (for instructions).
+func EditText(text, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// Like git, let the shell split the editor command, e.g. "code --wait".
+	// Windows has no sh, so the command is split the same way here.
+	editor := Editor()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		args := splitCommand(editor)
+		if len(args) == 0 {
+			return "", fmt.Errorf("no editor configured")
+		}
+		cmd = exec.Command(args[0], append(args[1:], f.Name())...)
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, f.Name())
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// splitCommand splits a command line into its arguments like a POSIX shell:
+// on whitespace, except in single or double quotes. A backslash only escapes
+// `"` and `\` inside double quotes and is kept otherwise, so that Windows paths
+// like C:\tools\vim.exe need no quoting.
+func splitCommand(command string) []string {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				arg.WriteRune(runes[i])
+			default:
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
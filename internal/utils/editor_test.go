@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"vim", []string{"vim"}},
+		{"code --wait", []string{"code", "--wait"}},
+		{"  subl   -n -w ", []string{"subl", "-n", "-w"}},
+		{`"C:\Program Files\Notepad++\notepad++.exe" -multiInst -nosession`, []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst", "-nosession"}},
+		{`C:\tools\vim.exe`, []string{`C:\tools\vim.exe`}},
+		{`'/opt/my editor/bin/ed' -x`, []string{"/opt/my editor/bin/ed", "-x"}},
+		{`ed --title "say \"hi\"" a\ b`, []string{"ed", "--title", `say "hi"`, `a\`, "b"}},
+		{`ed ""`, []string{"ed", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitCommand(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -6,58 +6,72 @@ import (
 	"strings"
 )
 
-// IsIgnored checking if path should be ignored by Archon (indexing, hashing, etc)
+// IsIgnored checking if path should be ignored by Archon (indexing, hashing, etc).
+// It follows the built-in defaults, .gitignore and .archonignore files and the
+// `ignore` patterns of .archon.yaml, relative to the root of the git repository
+// (the current directory outside of one).
 func IsIgnored(path string) bool {
-	name := filepath.Base(path)
-	
-	// Folders to ignore
-	ignoredDirs := []string{".git", "node_modules", "vendor", "chromem_db", "bin", "build", "obj", ".idea", ".vscode"}
-	
-	// Files to ignore specifically
-	ignoredFiles := []string{".archon.yaml", "archon.exe"}
-	
-	// Extensions to ignore (binaries, logs, etc)
-	ignoredExts := []string{".exe", ".dll", ".so", ".dylib", ".bin", ".log", ".test"}
-
-	// Check specific files
-	for _, f := range ignoredFiles {
-		if name == f {
-			return true
-		}
+	isDir := false
+	if info, err := os.Lstat(path); err == nil {
+		isDir = info.IsDir()
 	}
+	return currentIgnoreMatcher().Match(path, isDir)
+}
 
-	// Check if path contains any ignored directory
-	parts := strings.Split(filepath.ToSlash(path), "/")
-	for _, part := range parts {
-		for _, dir := range ignoredDirs {
-			if part == dir {
-				return true
-			}
-		}
+// IsDir checks if path is a directory
+func IsDir(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
 	}
+	return info.IsDir()
+}
 
-	// Check extension
-	ext := filepath.Ext(name)
-	for _, e := range ignoredExts {
-		if ext == e {
+// IsTestFile reports whether path looks like a test file or test fixture by the
+// usual conventions (foo_test.go, test_foo.py, foo.spec.ts, tests/, __tests__/, testdata/).
+func IsTestFile(path string) bool {
+	slashed := filepath.ToSlash(path)
+	for _, dir := range []string{"test", "tests", "__tests__", "testdata", "spec"} {
+		if strings.HasPrefix(slashed, dir+"/") || strings.Contains(slashed, "/"+dir+"/") {
 			return true
 		}
 	}
 
-	// Ignore common temporary files
-	if strings.HasPrefix(name, ".") && name != ".archon.yaml" && !strings.Contains(path, "github") {
-		// allow hidden files that are not common config folders
-		// but keep it simple for now
-	}
-
-	return false
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.HasSuffix(stem, "_test") ||
+		strings.HasPrefix(stem, "test_") ||
+		strings.HasSuffix(stem, ".test") ||
+		strings.HasSuffix(stem, ".spec") ||
+		strings.HasSuffix(stem, "Test") ||
+		strings.HasSuffix(stem, "Tests") ||
+		strings.HasSuffix(stem, "_spec")
 }
 
-// IsDir checks if path is a directory
-func IsDir(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
+// TestFilePath returns where the tests of source file path conventionally live:
+// foo_test.go, test_foo.py, foo.test.ts, FooTest.java (under src/test when the
+// file is under src/main), FooTests.cs and so on.
+func TestFilePath(path string) string {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	switch ext {
+	case ".py":
+		return filepath.Join(dir, "test_"+stem+ext)
+	case ".ts", ".tsx", ".js", ".jsx":
+		return filepath.Join(dir, stem+".test"+ext)
+	case ".java":
+		slashed := filepath.ToSlash(dir)
+		if strings.Contains(slashed, "src/main/") {
+			dir = filepath.FromSlash(strings.Replace(slashed, "src/main/", "src/test/", 1))
+		}
+		return filepath.Join(dir, stem+"Test"+ext)
+	case ".php":
+		return filepath.Join(dir, stem+"Test"+ext)
+	case ".cs":
+		return filepath.Join(dir, stem+"Tests"+ext)
+	default:
+		return filepath.Join(dir, stem+"_test"+ext)
 	}
-	return info.IsDir()
 }
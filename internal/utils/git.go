@@ -2,32 +2,243 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
-func GetStagedDiff() (string, error) {
+// DiffOptions selects the changes a diff covers. The zero value is the staged changes.
+type DiffOptions struct {
+	// Base compares the current branch with the point where it left Base, like a pull request.
+	Base string
+	// Range is a revision range such as "main..feature" or "HEAD~3..HEAD".
+	Range string
+	// Unstaged selects the changes in the working tree that are not staged.
+	Unstaged bool
+	// Files limits the diff to these paths.
+	Files []string
+}
+
+// Validate rejects options that select more than one kind of diff.
+func (o DiffOptions) Validate() error {
+	set := 0
+	for _, on := range []bool{o.Base != "", o.Range != "", o.Unstaged} {
+		if on {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("use only one of --base, --range and --unstaged")
+	}
+	return nil
+}
+
+// Staged reports whether the options select the staged changes.
+func (o DiffOptions) Staged() bool {
+	return o.Base == "" && o.Range == "" && !o.Unstaged
+}
+
+// String describes the selected changes, e.g. "changes since main".
+func (o DiffOptions) String() string {
+	var s string
+	switch {
+	case o.Base != "":
+		s = "changes since " + o.Base
+	case o.Range != "":
+		s = "changes in " + o.Range
+	case o.Unstaged:
+		s = "unstaged changes"
+	default:
+		s = "staged changes"
+	}
+	if len(o.Files) > 0 {
+		s += " to " + strings.Join(o.Files, ", ")
+	}
+	return s
+}
+
+// args builds the git diff command line. The output format is fixed, whatever
+// the user's configuration (diff.noprefix, diff.mnemonicPrefix, diff.relative,
+// color.diff, diff.external), since SplitDiff and ParseDiff read it.
+func (o DiffOptions) args() []string {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-relative", "--src-prefix=a/", "--dst-prefix=b/"}
+	switch {
+	case o.Base != "":
+		args = append(args, o.Base+"...HEAD")
+	case o.Range != "":
+		args = append(args, o.Range)
+	case o.Unstaged:
+	default:
+		args = append(args, "--cached")
+	}
+	if len(o.Files) > 0 {
+		args = append(args, "--")
+		args = append(args, o.Files...)
+	}
+	return args
+}
+
+// GetDiff returns the diff selected by opts.
+func GetDiff(opts DiffOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
 	if !IsGitRepo() {
 		return "", fmt.Errorf("this directory is not a git repository")
 	}
-	cmd := exec.Command("git", "diff", "--cached")
-	out, err := cmd.CombinedOutput()
+	args := opts.args()
+	cmd := exec.Command("git", args...)
+	out, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("git diff --cached failed: %s (%v)", strings.TrimSpace(string(out)), err)
+		msg := err.Error()
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			msg = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), msg)
 	}
 	return string(out), nil
 }
 
-func GetUnstagedDiff() (string, error) {
-	if !IsGitRepo() {
-		return "", fmt.Errorf("this directory is not a git repository")
+// ReadNew returns the contents of path (relative to the repository root) in the
+// new version of the diff selected by opts: the index for staged changes, the
+// end of the range or HEAD for committed ones, and the working tree otherwise.
+func (o DiffOptions) ReadNew(path string) ([]byte, error) {
+	var rev string
+	switch {
+	case o.Base != "":
+		rev = "HEAD"
+	case o.Range != "":
+		// "a..b" and "a...b" end at b, or HEAD if it is left out. A single
+		// revision is compared with the working tree.
+		i := strings.LastIndex(o.Range, "..")
+		if i < 0 {
+			return os.ReadFile(filepath.Join(RepoRoot(), filepath.FromSlash(path)))
+		}
+		if rev = o.Range[i+2:]; rev == "" {
+			rev = "HEAD"
+		}
+	case o.Unstaged:
+		return os.ReadFile(filepath.Join(RepoRoot(), filepath.FromSlash(path)))
 	}
-	cmd := exec.Command("git", "diff")
-	out, err := cmd.CombinedOutput()
+	// With no revision, ":path" is the staged version
+	out, err := exec.Command("git", "show", rev+":"+path).Output()
 	if err != nil {
-		return "", fmt.Errorf("git diff failed: %s (%v)", strings.TrimSpace(string(out)), err)
+		return nil, fmt.Errorf("git show %s:%s failed: %w", rev, path, err)
 	}
-	return string(out), nil
+	return out, nil
+}
+
+func GetStagedDiff() (string, error) {
+	return GetDiff(DiffOptions{})
+}
+
+func GetUnstagedDiff() (string, error) {
+	return GetDiff(DiffOptions{Unstaged: true})
+}
+
+// FileDiff is the part of a diff that changes one file.
+type FileDiff struct {
+	Path string
+	Text string
+}
+
+// SplitDiff splits a git diff into its files.
+func SplitDiff(diff string) []FileDiff {
+	var files []FileDiff
+	for _, part := range splitBefore(diff, "diff --git ") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		line, _, _ := strings.Cut(part, "\n")
+		files = append(files, FileDiff{Path: diffPath(line), Text: part})
+	}
+	return files
+}
+
+// splitBefore cuts text before every line that starts with prefix.
+func splitBefore(text, prefix string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(text); {
+		if i > start && strings.HasPrefix(text[i:], prefix) {
+			parts = append(parts, text[start:i])
+			start = i
+		}
+		next := strings.IndexByte(text[i:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return append(parts, text[start:])
+}
+
+// diffPath reads the new path from a "diff --git a/x b/x" line.
+func diffPath(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "diff --git "))
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return header
+}
+
+// ChunkDiff groups the files of a diff into chunks of at most maxChars, so that
+// each fits in one prompt. A file larger than that is split between its hunks,
+// repeating the file header, and a single hunk that is still too large is cut.
+func ChunkDiff(diff string, maxChars int) []string {
+	if maxChars <= 0 || len(diff) <= maxChars {
+		return []string{diff}
+	}
+
+	var pieces []string
+	for _, file := range SplitDiff(diff) {
+		if len(file.Text) <= maxChars {
+			pieces = append(pieces, file.Text)
+			continue
+		}
+		header, hunks := splitHunks(file.Text)
+		for _, hunk := range hunks {
+			piece := header + hunk
+			if len(piece) > maxChars {
+				// Keep whole lines, and at least the "@@" line
+				cut := min(max(maxChars-len(header)-32, 0), len(hunk))
+				if nl := strings.LastIndexByte(hunk[:cut], '\n'); nl >= 0 {
+					cut = nl + 1
+				} else if nl := strings.IndexByte(hunk, '\n'); nl >= 0 {
+					cut = nl + 1
+				}
+				piece = header + hunk[:cut] + "... (hunk truncated)\n"
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range pieces {
+		if current.Len() > 0 && current.Len()+len(piece) > maxChars {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(piece)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// splitHunks separates the header of a file's diff from its "@@" hunks.
+func splitHunks(text string) (string, []string) {
+	parts := splitBefore(text, "@@")
+	if !strings.HasPrefix(parts[0], "@@") {
+		if len(parts) == 1 {
+			return parts[0], []string{""}
+		}
+		return parts[0], parts[1:]
+	}
+	return "", parts
 }
 
 func IsGitRepo() bool {
@@ -47,3 +258,26 @@ func GetCommitMessage(diff string) (string, error) {
 	}
 	return "", nil
 }
+
+// HooksDir returns the directory git runs hooks from, honoring core.hooksPath.
+func HooksDir() (string, error) {
+	if !IsGitRepo() {
+		return "", fmt.Errorf("this directory is not a git repository")
+	}
+	out, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --git-path hooks failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RepoRoot returns the top-level directory of the git repository containing the
+// current directory, or the current directory outside of a repository.
+func RepoRoot() string {
+	if out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		if root := strings.TrimSpace(string(out)); root != "" {
+			return filepath.FromSlash(root)
+		}
+	}
+	return "."
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffOptionsArgs(t *testing.T) {
+	format := []string{"diff", "--no-color", "--no-ext-diff", "--no-relative", "--src-prefix=a/", "--dst-prefix=b/"}
+	tests := []struct {
+		opts DiffOptions
+		want []string
+		desc string
+	}{
+		{DiffOptions{}, []string{"--cached"}, "staged changes"},
+		{DiffOptions{Unstaged: true}, nil, "unstaged changes"},
+		{DiffOptions{Base: "main"}, []string{"main...HEAD"}, "changes since main"},
+		{DiffOptions{Range: "HEAD~2..HEAD"}, []string{"HEAD~2..HEAD"}, "changes in HEAD~2..HEAD"},
+		{DiffOptions{Files: []string{"a.go", "b.go"}}, []string{"--cached", "--", "a.go", "b.go"}, "staged changes to a.go, b.go"},
+	}
+	for _, tt := range tests {
+		if got, want := tt.opts.args(), append(slices.Clone(format), tt.want...); !slices.Equal(got, want) {
+			t.Errorf("%+v: args = %q, want %q", tt.opts, got, want)
+		}
+		if got := tt.opts.String(); got != tt.desc {
+			t.Errorf("%+v: String = %q, want %q", tt.opts, got, tt.desc)
+		}
+	}
+}
+
+func TestDiffOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts  DiffOptions
+		valid bool
+	}{
+		{DiffOptions{}, true},
+		{DiffOptions{Base: "main", Files: []string{"a.go"}}, true},
+		{DiffOptions{Base: "main", Range: "a..b"}, false},
+		{DiffOptions{Range: "a..b", Unstaged: true}, false},
+		{DiffOptions{Base: "main", Unstaged: true}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.opts, err, tt.valid)
+		}
+	}
+}
+
+const twoFileDiff = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,3 @@
+ package a
+-var x = 1
++var x = 2
+ 
+diff --git a/dir with space/b.go b/dir with space/b.go
+new file mode 100644
+--- /dev/null
++++ b/dir with space/b.go
+@@ -0,0 +1 @@
++package b
+`
+
+func TestSplitDiff(t *testing.T) {
+	files := SplitDiff(twoFileDiff)
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if files[0].Path != "a.go" || files[1].Path != "dir with space/b.go" {
+		t.Errorf("paths = %q, %q", files[0].Path, files[1].Path)
+	}
+	if files[0].Text+files[1].Text != twoFileDiff {
+		t.Error("the parts do not add up to the diff")
+	}
+	if len(SplitDiff("")) != 0 {
+		t.Error("an empty diff has no files")
+	}
+}
+
+// bigHunkDiff returns the diff of one file with hunks of n added lines each.
+func bigHunkDiff(hunks, n int) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/big.go b/big.go\n--- a/big.go\n+++ b/big.go\n")
+	for h := 0; h < hunks; h++ {
+		b.WriteString("@@ -1,0 +1,9 @@ func f()\n")
+		for i := 0; i < n; i++ {
+			b.WriteString("+line of code\n")
+		}
+	}
+	return b.String()
+}
+
+func TestChunkDiff(t *testing.T) {
+	header := "diff --git a/big.go b/big.go\n--- a/big.go\n+++ b/big.go\n"
+	tests := []struct {
+		name     string
+		diff     string
+		maxChars int
+		chunks   int
+	}{
+		{"fits", twoFileDiff, 10000, 1},
+		{"no limit", twoFileDiff, 0, 1},
+		{"split between files", twoFileDiff, 200, 2},
+		{"split between hunks", bigHunkDiff(3, 5), 150, 3},
+		{"hunk cut", bigHunkDiff(1, 50), 200, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkDiff(tt.diff, tt.maxChars)
+			if len(chunks) != tt.chunks {
+				t.Fatalf("got %d chunks, want %d:\n%s", len(chunks), tt.chunks, strings.Join(chunks, "\n---\n"))
+			}
+			for _, chunk := range chunks {
+				if tt.maxChars > 0 && len(chunk) > tt.maxChars+len("... (hunk truncated)\n") {
+					t.Errorf("chunk of %d chars exceeds %d", len(chunk), tt.maxChars)
+				}
+				if !strings.HasSuffix(chunk, "\n") {
+					t.Errorf("chunk does not end with a whole line: %q", chunk)
+				}
+				// Every piece of a file repeats its header, so hunks stay attributable
+				if strings.Contains(chunk, "@@") && strings.HasPrefix(tt.diff, header) && !strings.HasPrefix(chunk, header) {
+					t.Errorf("chunk misses the file header: %q", chunk)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkDiffCutsHunkAtLine(t *testing.T) {
+	chunks := ChunkDiff(bigHunkDiff(1, 50), 200)
+	chunk := chunks[0]
+	if !strings.HasSuffix(chunk, "+line of code\n... (hunk truncated)\n") {
+		t.Errorf("the cut hunk should end with whole lines and a marker: %q", chunk)
+	}
+	// A limit smaller than the header still keeps the "@@" line
+	chunks = ChunkDiff(bigHunkDiff(1, 50), 10)
+	if !strings.Contains(chunks[0], "@@ -1,0 +1,9 @@ func f()\n... (hunk truncated)\n") {
+		t.Errorf("the hunk header should be kept: %q", chunks[0])
+	}
+}
+
+// gitRepo creates a repository with user configuration that changes git's
+// diff output, and runs the test in its subdirectory sub.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	git("init", "-q")
+	for _, kv := range [][2]string{
+		{"user.email", "test@example.com"}, {"user.name", "test"},
+		{"diff.noprefix", "true"}, {"diff.mnemonicPrefix", "true"}, {"diff.relative", "true"},
+		{"color.diff", "always"}, {"diff.external", "false"},
+	} {
+		git("config", kv[0], kv[1])
+	}
+	writeFiles(t, root, map[string]string{"sub/a.go": "package a\n"})
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	writeFiles(t, root, map[string]string{"sub/a.go": "package a\n\nvar staged = 1\n"})
+	git("add", ".")
+	writeFiles(t, root, map[string]string{"sub/a.go": "package a\n\nvar staged = 1\nvar unstaged = 2\n"})
+
+	t.Chdir(filepath.Join(root, "sub"))
+	return root
+}
+
+func TestGetDiffIgnoresUserConfig(t *testing.T) {
+	gitRepo(t)
+	for _, opts := range []DiffOptions{{}, {Unstaged: true}} {
+		diff, err := GetDiff(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(diff, "\x1b[") {
+			t.Errorf("%s: the diff is colored", opts)
+		}
+		files := ParseDiff(diff)
+		if len(files) != 1 || files[0].Path() != "sub/a.go" || SplitDiff(diff)[0].Path != "sub/a.go" {
+			t.Errorf("%s: files = %+v, want sub/a.go relative to the repository", opts, files)
+		}
+	}
+}
+
+func TestReadNewFromSubdirectory(t *testing.T) {
+	gitRepo(t)
+	tests := []struct {
+		opts DiffOptions
+		want string
+	}{
+		{DiffOptions{}, "var staged = 1\n"},
+		{DiffOptions{Unstaged: true}, "var unstaged = 2\n"},
+		{DiffOptions{Base: "HEAD"}, "package a\n"},
+		{DiffOptions{Range: "HEAD"}, "var unstaged = 2\n"},
+		{DiffOptions{Range: "HEAD.."}, "package a\n"},
+	}
+	for _, tt := range tests {
+		got, err := tt.opts.ReadNew("sub/a.go")
+		if err != nil {
+			t.Errorf("%s: %v", tt.opts, err)
+			continue
+		}
+		if !strings.HasSuffix(string(got), tt.want) {
+			t.Errorf("%s: ReadNew = %q, want it to end with %q", tt.opts, got, tt.want)
+		}
+	}
+	if _, err := (DiffOptions{Unstaged: true}).ReadNew("sub/missing.go"); !os.IsNotExist(err) {
+		t.Errorf("ReadNew of a missing file = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LineRange is an inclusive range of 1-based lines.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Overlaps reports whether r and o share a line.
+func (r LineRange) Overlaps(o LineRange) bool {
+	return r.Start <= o.End && o.Start <= r.End
+}
+
+// parseHunkHeader reads "@@ -a,b +c,d @@". A missing count means 1.
+func parseHunkHeader(line string) (oldStart, oldCount, newStart, newCount int, ok bool) {
+	// A context line " @@ ..." is code, not a header
+	if !strings.HasPrefix(line, "@@ ") {
+		return 0, 0, 0, 0, false
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, 0, false
+	}
+	parse := func(s string) (int, int, bool) {
+		startText, countText, found := strings.Cut(s[1:], ",")
+		start, err := strconv.Atoi(startText)
+		if err != nil {
+			return 0, 0, false
+		}
+		count := 1
+		if found {
+			if count, err = strconv.Atoi(countText); err != nil {
+				return 0, 0, false
+			}
+		}
+		return start, count, true
+	}
+	oldStart, oldCount, ok1 := parse(fields[1])
+	newStart, newCount, ok2 := parse(fields[2])
+	return oldStart, oldCount, newStart, newCount, ok1 && ok2
+}
+
+// DiffLineKind is the first character of a line in a hunk: '+', '-' or ' '.
+type DiffLineKind byte
+
+const (
+	LineAdded   DiffLineKind = '+'
+	LineRemoved DiffLineKind = '-'
+	LineContext DiffLineKind = ' '
+)
+
+// DiffLine is one line of a hunk. OldLine is 0 for added lines and NewLine is 0
+// for removed ones.
+type DiffLine struct {
+	Kind    DiffLineKind
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+// Hunk is one "@@" section of a file diff. Section is the text git prints after
+// the header, usually the enclosing function.
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Section  string
+	Lines    []DiffLine
+}
+
+// NewRange returns the lines of the new version the hunk covers, context included.
+func (h Hunk) NewRange() LineRange {
+	return LineRange{Start: h.NewStart, End: h.NewStart + h.NewCount - 1}
+}
+
+// Changes returns the lines of the new version that were added or replaced. A
+// removal without added lines is reported as the line after it (or the last
+// line, at the end of the file), where the code was taken out.
+func (h Hunk) Changes() []LineRange {
+	var ranges []LineRange
+	add := func(line int) {
+		if n := len(ranges); n > 0 && ranges[n-1].End >= line-1 {
+			ranges[n-1].End = max(ranges[n-1].End, line)
+			return
+		}
+		ranges = append(ranges, LineRange{Start: line, End: line})
+	}
+
+	next, last := h.NewStart, h.NewStart+h.NewCount-1
+	if h.NewCount == 0 {
+		// "+c,0" names the line before an empty range
+		next, last = h.NewStart+1, h.NewStart
+	}
+	for _, line := range h.Lines {
+		switch line.Kind {
+		case LineAdded:
+			add(line.NewLine)
+			next = line.NewLine + 1
+		case LineContext:
+			next = line.NewLine + 1
+		case LineRemoved:
+			add(max(min(next, last), 1))
+		}
+	}
+	return ranges
+}
+
+// DiffFile is the diff of one file. For a new file OldPath is empty, for a
+// deleted one NewPath is.
+type DiffFile struct {
+	OldPath   string
+	NewPath   string
+	IsNew     bool
+	IsDeleted bool
+	IsBinary  bool
+	Hunks     []Hunk
+}
+
+// Path returns the path of the file, the old one if it was deleted.
+func (f DiffFile) Path() string {
+	if f.IsDeleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// ParseDiff reads a git unified diff into its files, hunks and lines.
+func ParseDiff(diff string) []DiffFile {
+	var files []DiffFile
+	for _, part := range SplitDiff(diff) {
+		file := DiffFile{OldPath: part.Path, NewPath: part.Path}
+		var hunk *Hunk
+		oldLine, newLine := 0, 0
+		for _, line := range strings.Split(strings.TrimSuffix(part.Text, "\n"), "\n") {
+			if oldStart, oldCount, newStart, newCount, ok := parseHunkHeader(line); ok {
+				file.Hunks = append(file.Hunks, Hunk{OldStart: oldStart, OldCount: oldCount, NewStart: newStart, NewCount: newCount})
+				hunk = &file.Hunks[len(file.Hunks)-1]
+				if _, section, found := strings.Cut(line[2:], "@@"); found {
+					hunk.Section = strings.TrimSpace(section)
+				}
+				oldLine, newLine = oldStart, newStart
+				continue
+			}
+
+			if hunk == nil {
+				switch {
+				case strings.HasPrefix(line, "new file mode"):
+					file.IsNew, file.OldPath = true, ""
+				case strings.HasPrefix(line, "deleted file mode"):
+					file.IsDeleted, file.NewPath = true, ""
+				case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+					file.IsBinary = true
+				case strings.HasPrefix(line, "rename from "):
+					file.OldPath = strings.TrimPrefix(line, "rename from ")
+				case strings.HasPrefix(line, "rename to "):
+					file.NewPath = strings.TrimPrefix(line, "rename to ")
+				case strings.HasPrefix(line, "--- a/"):
+					file.OldPath = strings.TrimPrefix(line, "--- a/")
+				case strings.HasPrefix(line, "+++ b/"):
+					file.NewPath = strings.TrimPrefix(line, "+++ b/")
+				}
+				continue
+			}
+
+			if line == "" {
+				continue
+			}
+			switch DiffLineKind(line[0]) {
+			case LineAdded:
+				hunk.Lines = append(hunk.Lines, DiffLine{Kind: LineAdded, NewLine: newLine, Text: line[1:]})
+				newLine++
+			case LineRemoved:
+				hunk.Lines = append(hunk.Lines, DiffLine{Kind: LineRemoved, OldLine: oldLine, Text: line[1:]})
+				oldLine++
+			case LineContext:
+				hunk.Lines = append(hunk.Lines, DiffLine{Kind: LineContext, OldLine: oldLine, NewLine: newLine, Text: line[1:]})
+				oldLine++
+				newLine++
+			}
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// ChangedRanges returns, for every file of a diff that still exists, the lines
+// of the new version covered by its hunks.
+func ChangedRanges(diff string) map[string][]LineRange {
+	ranges := make(map[string][]LineRange)
+	for _, file := range ParseDiff(diff) {
+		if file.IsDeleted {
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			if hunk.NewCount > 0 {
+				ranges[file.NewPath] = append(ranges[file.NewPath], hunk.NewRange())
+			}
+		}
+	}
+	return ranges
+}
+
+// NumberDiff prefixes the lines of a diff's hunks with their line number in the
+// new version of the file, so that a model can refer to real lines. Removed
+// lines get no number.
+func NumberDiff(diff string) string {
+	var b strings.Builder
+	next := 0
+	inHunk := false
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			inHunk = false
+		}
+		if _, _, start, _, ok := parseHunkHeader(line); ok {
+			next, inHunk = start, true
+			b.WriteString(line)
+			continue
+		}
+		if !inHunk {
+			b.WriteString(line)
+			continue
+		}
+		switch line[0] {
+		case '+', ' ':
+			fmt.Fprintf(&b, "%5d %s", next, line)
+			next++
+		default:
+			fmt.Fprintf(&b, "%5s %s", "", line)
+		}
+	}
+	return b.String()
+}
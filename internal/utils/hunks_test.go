@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hunk parses a single hunk given as its header and lines.
+func hunk(t *testing.T, lines ...string) Hunk {
+	t.Helper()
+	files := ParseDiff("diff --git a/f.go b/f.go\n--- a/f.go\n+++ b/f.go\n" + strings.Join(lines, "\n") + "\n")
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("want one hunk, got %+v", files)
+	}
+	return files[0].Hunks[0]
+}
+
+func TestHunkChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []LineRange
+	}{
+		{"replaced line", []string{"@@ -2,3 +2,3 @@", " a", "-b", "+B", " c"}, []LineRange{{3, 3}}},
+		{"added lines", []string{"@@ -2,2 +2,4 @@", " a", "+x", "+y", " b"}, []LineRange{{3, 4}}},
+		{"new file", []string{"@@ -0,0 +1,2 @@", "+a", "+b"}, []LineRange{{1, 2}}},
+		{"removal in the middle", []string{"@@ -2,3 +2,2 @@", " a", "-b", " c"}, []LineRange{{3, 3}}},
+		{"removal at the end", []string{"@@ -1,3 +1,2 @@", " a", " b", "-c"}, []LineRange{{2, 2}}},
+		// "+2,0": the lines after line 2 were removed, the hunk has no new lines
+		{"pure removal", []string{"@@ -3,2 +2,0 @@", "-x", "-y"}, []LineRange{{2, 2}}},
+		{"file emptied", []string{"@@ -1,2 +0,0 @@", "-x", "-y"}, []LineRange{{1, 1}}},
+		{"removal before additions", []string{"@@ -1,2 +1,3 @@", "-x", "+y", "+z", " a"}, []LineRange{{1, 2}}},
+		{"separate changes", []string{"@@ -1,5 +1,5 @@", "-a", "+A", " b", " c", " d", "-e", "+E"}, []LineRange{{1, 1}, {5, 5}}},
+		{"adjacent changes merge", []string{"@@ -1,3 +1,3 @@", "-a", "+A", "-b", "+B", " c"}, []LineRange{{1, 2}}},
+		{"context line like a header", []string{"@@ -1,2 +1,2 @@", " @@ -1 +1 @@", "-a", "+b"}, []LineRange{{2, 2}}},
+		{"context only", []string{"@@ -1,2 +1,2 @@", " a", " b"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hunk(t, tt.lines...).Changes(); !slices.Equal(got, tt.want) {
+				t.Errorf("Changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want [4]int
+		ok   bool
+	}{
+		{"@@ -1,3 +1,4 @@", [4]int{1, 3, 1, 4}, true},
+		{"@@ -5 +5 @@ func main() {", [4]int{5, 1, 5, 1}, true},
+		{"@@ -3,2 +2,0 @@", [4]int{3, 2, 2, 0}, true},
+		{"@@@ -1,2 -1,2 +1,3 @@@", [4]int{}, false}, // combined diffs are not supported
+		{"@@ -x,1 +1 @@", [4]int{}, false},
+		{" @@ -1 +1 @@", [4]int{}, false},
+	}
+	for _, tt := range tests {
+		a, b, c, d, ok := parseHunkHeader(tt.line)
+		if ok != tt.ok || (ok && [4]int{a, b, c, d} != tt.want) {
+			t.Errorf("parseHunkHeader(%q) = %v %v, want %v %v", tt.line, [4]int{a, b, c, d}, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+const mixedDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,3 +10,4 @@ func main() {
+ 	a()
+-	b()
++	b(1)
++	c()
+ }
+diff --git a/old.go b/new.go
+similarity index 90%
+rename from old.go
+rename to new.go
+--- a/old.go
++++ b/new.go
+@@ -1 +1 @@
+-package old
++package renamed
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package gone
+-
+diff --git a/added.go b/added.go
+new file mode 100644
+--- /dev/null
++++ b/added.go
+@@ -0,0 +1 @@
++package added
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff(mixedDiff)
+	if len(files) != 5 {
+		t.Fatalf("got %d files, want 5", len(files))
+	}
+
+	main := files[0]
+	if main.Path() != "main.go" || len(main.Hunks) != 1 {
+		t.Fatalf("main.go = %+v", main)
+	}
+	h := main.Hunks[0]
+	if h.Section != "func main() {" || h.NewRange() != (LineRange{10, 13}) {
+		t.Errorf("hunk section %q, range %v", h.Section, h.NewRange())
+	}
+	wantLines := []DiffLine{
+		{LineContext, 10, 10, "\ta()"},
+		{LineRemoved, 11, 0, "\tb()"},
+		{LineAdded, 0, 11, "\tb(1)"},
+		{LineAdded, 0, 12, "\tc()"},
+		{LineContext, 12, 13, "}"},
+	}
+	if !reflect.DeepEqual(h.Lines, wantLines) {
+		t.Errorf("lines = %+v, want %+v", h.Lines, wantLines)
+	}
+
+	tests := []struct {
+		file                     DiffFile
+		old, new, path           string
+		isNew, deleted, isBinary bool
+	}{
+		{files[1], "old.go", "new.go", "new.go", false, false, false},
+		{files[2], "gone.go", "", "gone.go", false, true, false},
+		{files[3], "", "added.go", "added.go", true, false, false},
+		{files[4], "logo.png", "logo.png", "logo.png", false, false, true},
+	}
+	for _, tt := range tests {
+		f := tt.file
+		if f.OldPath != tt.old || f.NewPath != tt.new || f.Path() != tt.path || f.IsNew != tt.isNew || f.IsDeleted != tt.deleted || f.IsBinary != tt.isBinary {
+			t.Errorf("%s: got %+v", tt.path, f)
+		}
+	}
+}
+
+func TestChangedRanges(t *testing.T) {
+	got := ChangedRanges(mixedDiff)
+	want := map[string][]LineRange{
+		"main.go":  {{10, 13}},
+		"new.go":   {{1, 1}},
+		"added.go": {{1, 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedRanges = %v, want %v", got, want)
+	}
+}
+
+func TestNumberDiff(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -8,3 +9,3 @@ func f() {
+ 	x := 1
+-	y := 2
++	y := 3
+ 	return
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1 +1 @@
+-package b
++package bb
+`
+	want := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -8,3 +9,3 @@ func f() {
+    9  	x := 1
+      -	y := 2
+   10 +	y := 3
+   11  	return
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1 +1 @@
+      -package b
+    1 +package bb
+`
+	if got := NumberDiff(diff); got != want {
+		t.Errorf("NumberDiff =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,296 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IgnoreFiles are read in every directory, in this order. Rules from deeper
+// directories and later files override earlier ones, like git does.
+var IgnoreFiles = []string{".gitignore", ".archonignore"}
+
+// defaultIgnorePatterns are always applied before any ignore file, so they can
+// still be re-included with a negated pattern.
+var defaultIgnorePatterns = []string{
+	".git/", "node_modules/", "vendor/", "chromem_db/", ".archon/", "bin/", "build/", "obj/", ".idea/", ".vscode/",
+	".archon.yaml", "archon.exe",
+	"*.exe", "*.dll", "*.so", "*.dylib", "*.bin", "*.log", "*.test",
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnoreRule compiles one line of gitignore syntax. ok is false for blank
+// lines, comments and invalid patterns.
+func parseIgnoreRule(line string) (rule ignoreRule, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the ignore file's
+	// directory, otherwise it matches at any depth.
+	var expr string
+	if strings.Contains(line, "/") {
+		expr = globToRegexp(strings.TrimPrefix(line, "/"))
+	} else {
+		expr = "(?:.*/)?" + globToRegexp(line)
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+func globToRegexp(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				if i+2 < len(pattern) && pattern[i+2] == '/' {
+					// "**/" matches zero or more directories
+					sb.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					sb.WriteString(".*")
+					i++
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+func parseIgnoreLines(lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		if rule, ok := parseIgnoreRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func readIgnoreFile(path string) []ignoreRule {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnoreLines(lines)
+}
+
+// IgnoreMatcher decides which paths under root are ignored, combining the
+// built-in defaults, .git/info/exclude, nested .gitignore and .archonignore
+// files and extra patterns (from .archon.yaml), in that order of precedence.
+type IgnoreMatcher struct {
+	root     string
+	defaults []ignoreRule
+	exclude  []ignoreRule
+	extra    []ignoreRule
+
+	mu      sync.Mutex
+	dirs    map[string][]ignoreRule
+	ignored map[string]bool
+}
+
+// NewIgnoreMatcher creates a matcher for the project at root. extra patterns use
+// gitignore syntax relative to root and override every ignore file.
+func NewIgnoreMatcher(root string, extra []string) *IgnoreMatcher {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &IgnoreMatcher{
+		root:     root,
+		defaults: parseIgnoreLines(defaultIgnorePatterns),
+		exclude:  readIgnoreFile(filepath.Join(root, ".git", "info", "exclude")),
+		extra:    parseIgnoreLines(extra),
+		dirs:     make(map[string][]ignoreRule),
+		ignored:  make(map[string]bool),
+	}
+}
+
+// Match reports whether path is ignored. Like git, everything below an ignored
+// directory is ignored too.
+func (m *IgnoreMatcher) Match(path string, isDir bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// Outside the project only the defaults apply
+		parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(abs), "/"), "/")
+		for i := 1; i < len(parts); i++ {
+			if matchRules(m.defaults, strings.Join(parts[:i], "/"), true, false) {
+				return true
+			}
+		}
+		return matchRules(m.defaults, strings.Join(parts, "/"), isDir, false)
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchDir(parts[:i]) {
+			return true
+		}
+	}
+	return m.match(parts, isDir)
+}
+
+// matchDir is match for a directory, memoized since every path below it asks again.
+func (m *IgnoreMatcher) matchDir(parts []string) bool {
+	key := strings.Join(parts, "/")
+	if ignored, ok := m.ignored[key]; ok {
+		return ignored
+	}
+	ignored := m.match(parts, true)
+	m.ignored[key] = ignored
+	return ignored
+}
+
+func (m *IgnoreMatcher) match(parts []string, isDir bool) bool {
+	rel := strings.Join(parts, "/")
+	ignored := matchRules(m.defaults, rel, isDir, false)
+	ignored = matchRules(m.exclude, rel, isDir, ignored)
+	for i := 0; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		sub := rel
+		if dir != "" {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		ignored = matchRules(m.dirRules(dir), sub, isDir, ignored)
+	}
+	return matchRules(m.extra, rel, isDir, ignored)
+}
+
+// dirRules returns the rules of the ignore files in dir (relative to root).
+func (m *IgnoreMatcher) dirRules(dir string) []ignoreRule {
+	if rules, ok := m.dirs[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	for _, name := range IgnoreFiles {
+		rules = append(rules, readIgnoreFile(filepath.Join(m.root, filepath.FromSlash(dir), name))...)
+	}
+	m.dirs[dir] = rules
+	return rules
+}
+
+// matchRules applies rules in order; the last matching rule wins.
+func matchRules(rules []ignoreRule, rel string, isDir bool, ignored bool) bool {
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+var (
+	ignoreMu       sync.Mutex
+	ignoreMatcher  *IgnoreMatcher
+	ignorePatterns []string
+)
+
+// SetIgnorePatterns sets the extra patterns (the `ignore` list in .archon.yaml)
+// used by IsIgnored.
+func SetIgnorePatterns(patterns []string) {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	if slices.Equal(patterns, ignorePatterns) {
+		return
+	}
+	ignorePatterns = slices.Clone(patterns)
+	ignoreMatcher = nil
+}
+
+// ResetIgnoreCache makes IsIgnored re-read the ignore files, e.g. after one changed.
+func ResetIgnoreCache() {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	ignoreMatcher = nil
+}
+
+// IsIgnoreFile reports whether path is one of the files ignore rules are read from.
+func IsIgnoreFile(path string) bool {
+	return slices.Contains(IgnoreFiles, filepath.Base(path))
+}
+
+func currentIgnoreMatcher() *IgnoreMatcher {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	if ignoreMatcher == nil {
+		// Anchored patterns and parent .gitignore files are relative to the
+		// repository, also when archon runs in a subdirectory of it
+		ignoreMatcher = NewIgnoreMatcher(RepoRoot(), ignorePatterns)
+	}
+	return ignoreMatcher
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "deep/dir/a.log", false, true},
+		{"*.log", "a.log.txt", false, false},
+		{"/root.txt", "root.txt", false, true},
+		{"/root.txt", "sub/root.txt", false, false},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"docs/**/*.md", "docs/sub/deep/a.md", false, true},
+		{"docs/**/*.md", "docs/a.md", false, true},
+		{"**/gen", "a/b/gen", true, true},
+		{"out/", "out", true, true},
+		{"out/", "out", false, false},
+		{"file?.go", "file1.go", false, true},
+		{"file?.go", "file10.go", false, false},
+		{"[ab].go", "b.go", false, true},
+		{"[!ab].go", "b.go", false, false},
+		{`\#hash`, "#hash", false, true},
+		{"trailing   ", "trailing", false, true},
+	}
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.pattern)
+		if !ok {
+			t.Fatalf("parseIgnoreRule(%q) failed", tt.pattern)
+		}
+		if got := matchRules([]ignoreRule{rule}, tt.path, tt.isDir, false); got != tt.want {
+			t.Errorf("%q on %q (dir %v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreRuleSkips(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("parseIgnoreRule(%q) should be skipped", line)
+		}
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":           "*.tmp\n/dist\nsecrets/\n!keep.tmp\n",
+		"sub/.gitignore":       "local.txt\n!important.log\n",
+		"sub/.archonignore":    "fixtures/\n",
+		"node_modules/x/a.js":  "",
+		"dist/app.js":          "",
+		"sub/dist/app.js":      "",
+		"secrets/key.txt":      "",
+		"sub/local.txt":        "",
+		"local.txt":            "",
+		"sub/fixtures/data.go": "",
+		"sub/important.log":    "",
+		"a.tmp":                "",
+		"keep.tmp":             "",
+		"secrets/keep.tmp":     "",
+		"main.go":              "",
+		".git/info/exclude":    "excluded.go\n",
+		"excluded.go":          "",
+	})
+	m := NewIgnoreMatcher(root, []string{"main.go"})
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules/x/a.js", false, true}, // default
+		{"dist", true, true},
+		{"dist/app.js", false, true},      // inside an ignored directory
+		{"sub/dist/app.js", false, false}, // "/dist" is anchored
+		{"secrets/key.txt", false, true},
+		{"secrets/keep.tmp", false, true}, // no re-inclusion inside an ignored directory
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},           // sub/.gitignore only applies below sub
+		{"sub/fixtures/data.go", false, true}, // .archonignore
+		{"sub/important.log", false, false},   // negation overrides the *.log default
+		{"a.tmp", false, true},
+		{"keep.tmp", false, false},
+		{"main.go", false, true},     // extra patterns
+		{"excluded.go", false, true}, // .git/info/exclude
+		{".git/HEAD", false, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsIgnoredFromSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// git reports the resolved path (e.g. /private/var on macOS)
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	writeFiles(t, root, map[string]string{
+		".gitignore":       "/sub/generated.go\n*.out\n",
+		"sub/generated.go": "",
+		"sub/main.go":      "",
+		"sub/run.out":      "",
+	})
+
+	t.Chdir(filepath.Join(root, "sub"))
+	ResetIgnoreCache()
+	t.Cleanup(ResetIgnoreCache)
+
+	for path, want := range map[string]bool{"generated.go": true, "run.out": true, "main.go": false} {
+		if got := IsIgnored(path); got != want {
+			t.Errorf("IsIgnored(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
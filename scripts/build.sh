@@ -9,22 +9,38 @@ mkdir -p $BUILD_DIR
 
 echo "Building ArchonCLI for multiple platforms..."
 
+# The Tree-sitter grammars are C code and need cgo. Cross-compiling turns cgo
+# off, so every target is built with zig as its C compiler. Without zig, the
+# binaries fall back to the regex parser (see `archon status`).
+if command -v zig >/dev/null 2>&1; then
+    HAVE_ZIG=1
+else
+    HAVE_ZIG=0
+    echo "Warning: zig not found, building without cgo: the binaries use the regex parser instead of Tree-sitter."
+    echo "         Install zig (https://ziglang.org) to build them with Tree-sitter."
+fi
+
+# build <goos> <goarch> <zig target> <output>
+build() {
+    echo "Building for $1 ($2)..."
+    if [ "$HAVE_ZIG" = 1 ]; then
+        CGO_ENABLED=1 CC="zig cc -target $3" CXX="zig c++ -target $3" GOOS=$1 GOARCH=$2 \
+            go build -o "$BUILD_DIR/$4" ./cmd/archon/main.go || exit 1
+    else
+        CGO_ENABLED=0 GOOS=$1 GOARCH=$2 go build -o "$BUILD_DIR/$4" ./cmd/archon/main.go || exit 1
+    fi
+}
+
 # Windows
-echo "Building for Windows (amd64)..."
-GOOS=windows GOARCH=amd64 go build -o $BUILD_DIR/$APP_NAME-windows-amd64.exe ./cmd/archon/main.go
-echo "Building for Windows (arm64)..."
-GOOS=windows GOARCH=arm64 go build -o $BUILD_DIR/$APP_NAME-windows-arm64.exe ./cmd/archon/main.go
+build windows amd64 x86_64-windows-gnu $APP_NAME-windows-amd64.exe
+build windows arm64 aarch64-windows-gnu $APP_NAME-windows-arm64.exe
 
 # Linux
-echo "Building for Linux (amd64)..."
-GOOS=linux GOARCH=amd64 go build -o $BUILD_DIR/$APP_NAME-linux-amd64 ./cmd/archon/main.go
-echo "Building for Linux (arm64)..."
-GOOS=linux GOARCH=arm64 go build -o $BUILD_DIR/$APP_NAME-linux-arm64 ./cmd/archon/main.go
+build linux amd64 x86_64-linux-musl $APP_NAME-linux-amd64
+build linux arm64 aarch64-linux-musl $APP_NAME-linux-arm64
 
 # macOS (Darwin)
-echo "Building for macOS (amd64)..."
-GOOS=darwin GOARCH=amd64 go build -o $BUILD_DIR/$APP_NAME-darwin-amd64 ./cmd/archon/main.go
-echo "Building for macOS (arm64)..."
-GOOS=darwin GOARCH=arm64 go build -o $BUILD_DIR/$APP_NAME-darwin-arm64 ./cmd/archon/main.go
+build darwin amd64 x86_64-macos $APP_NAME-darwin-amd64
+build darwin arm64 aarch64-macos $APP_NAME-darwin-arm64
 
 echo "Build complete! Results available in $BUILD_DIR/ folder"